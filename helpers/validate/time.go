@@ -51,6 +51,34 @@ func ISO8601DurationBetween(min string, max string) func(i interface{}, k string
 	}
 }
 
+// ISO8601DurationBetweenWithMessage validates that the value is an ISO8601 Duration within the specified (inclusive) range, and
+// unlike ISO8601DurationBetween includes the allowed range in the format it's specified in within the error message
+func ISO8601DurationBetweenWithMessage(min string, max string) func(i interface{}, k string) (warnings []string, errors []error) {
+	minDuration := period.MustParse(min).DurationApprox()
+	maxDuration := period.MustParse(max).DurationApprox()
+	if minDuration >= maxDuration {
+		panic(fmt.Sprintf("min duration (%v) >= max duration (%v)", minDuration, maxDuration))
+	}
+	return func(i interface{}, k string) (warnings []string, errors []error) {
+		v, ok := i.(string)
+		if !ok {
+			return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+		}
+
+		p, err := period.Parse(v)
+		if err != nil {
+			return nil, []error{fmt.Errorf("expected %s to be an ISO8601 Duration between %q and %q, got %q: %+v", k, min, max, v, err)}
+		}
+
+		duration := p.DurationApprox()
+		if duration < minDuration || duration > maxDuration {
+			return nil, []error{fmt.Errorf("expected %s to be an ISO8601 Duration between %q (%v) and %q (%v), got %q (%v)", k, min, minDuration, max, maxDuration, v, duration)}
+		}
+
+		return nil, nil
+	}
+}
+
 func ISO8601DateTime(i interface{}, k string) (warnings []string, errors []error) {
 	v, ok := i.(string)
 	if !ok {
@@ -4,6 +4,7 @@
 package validate
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -106,3 +107,54 @@ func TestISO8601Duration(t *testing.T) {
 		}
 	}
 }
+
+func TestISO8601DurationBetweenWithMessage(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Errors   int
+		Contains []string
+	}{
+		{
+			Value:  "PT15M",
+			Errors: 0,
+		},
+		{
+			Value:  "PT1H30M",
+			Errors: 0,
+		},
+		{
+			Value:  "PT2H",
+			Errors: 0,
+		},
+		{
+			Value:    "PT3H",
+			Errors:   1,
+			Contains: []string{`between "PT15M" (15m0s) and "PT2H" (2h0m0s)`, `got "PT3H" (3h0m0s)`},
+		},
+		{
+			Value:    "PT5M",
+			Errors:   1,
+			Contains: []string{`between "PT15M" (15m0s) and "PT2H" (2h0m0s)`, `got "PT5M" (5m0s)`},
+		},
+		{
+			Value:    "3 hours",
+			Errors:   1,
+			Contains: []string{`between "PT15M" and "PT2H"`, `got "3 hours"`},
+		},
+	}
+
+	validateFunc := ISO8601DurationBetweenWithMessage("PT15M", "PT2H")
+	for _, tc := range cases {
+		_, errors := validateFunc(tc.Value, "example")
+
+		if len(errors) != tc.Errors {
+			t.Fatalf("Expected ISO8601DurationBetweenWithMessage to trigger '%d' errors for '%s' - got '%d'", tc.Errors, tc.Value, len(errors))
+		}
+
+		for _, expected := range tc.Contains {
+			if !strings.Contains(errors[0].Error(), expected) {
+				t.Fatalf("Expected the error for '%s' to contain '%s' - got '%s'", tc.Value, expected, errors[0].Error())
+			}
+		}
+	}
+}
@@ -4,11 +4,16 @@
 package common
 
 import (
+	"context"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
 )
 
 func correlationRequestIDMiddleware(id string) client.RequestMiddleware {
@@ -57,3 +62,73 @@ func responseLoggerMiddleware(providerName string) client.ResponseMiddleware {
 		return response, nil
 	}
 }
+
+// throttledRequestRetryKey marks a request as being re-sent by ThrottledRequestRetryMiddleware, so that the middleware
+// doesn't retry the request again when it's invoked for the re-sent request's response
+type throttledRequestRetryKey struct{}
+
+// ThrottledRequestRetryMiddleware re-sends read requests which are still being throttled (HTTP 429) once the SDK
+// has exhausted its own retries, up to `maxAttempts` times using an exponential backoff (honouring any `Retry-After` header).
+// Requests are re-sent using the client which sent the original request, so that they're authorized, logged and sent in
+// the same way - as such each attempt also includes the client's own retries.
+func ThrottledRequestRetryMiddleware(c *client.Client, maxAttempts int) client.ResponseMiddleware {
+	return func(request *http.Request, response *http.Response) (*http.Response, error) {
+		if response == nil || response.StatusCode != http.StatusTooManyRequests || request.Method != http.MethodGet {
+			return response, nil
+		}
+
+		ctx := request.Context()
+		if ctx.Value(throttledRequestRetryKey{}) != nil {
+			return response, nil
+		}
+
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			select {
+			case <-ctx.Done():
+				return response, nil
+			case <-time.After(throttledRequestBackoff(attempt, response)):
+			}
+
+			log.Printf("[DEBUG] Retrying throttled request to %s (attempt %d of %d)", request.URL, attempt+1, maxAttempts)
+			retry := &client.Request{
+				Client:  c,
+				Request: request.Clone(context.WithValue(ctx, throttledRequestRetryKey{}, true)),
+				// the response is returned to the original caller, which validates the status code
+				ValidStatusFunc: func(*http.Response, *odata.OData) bool {
+					return true
+				},
+			}
+			retried, err := c.Execute(ctx, retry)
+			if err != nil || retried == nil || retried.Response == nil {
+				log.Printf("[DEBUG] Retrying throttled request to %s: %+v", request.URL, err)
+				return response, nil
+			}
+
+			if response.Body != nil {
+				response.Body.Close()
+			}
+			response = retried.Response
+
+			if response.StatusCode != http.StatusTooManyRequests {
+				break
+			}
+		}
+
+		return response, nil
+	}
+}
+
+func throttledRequestBackoff(attempt int, response *http.Response) time.Duration {
+	if v := response.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	maxBackoff := 60 * time.Second
+	backoff := time.Duration(math.Pow(2, float64(attempt))) * 2 * time.Second
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,100 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+)
+
+func TestThrottledRequestRetryMiddleware(t *testing.T) {
+	testData := []struct {
+		name              string
+		method            string
+		throttledAttempts int
+		maxAttempts       int
+		expectedStatus    int
+		expectedAttempts  int
+	}{
+		{
+			name:              "recovers within the attempts",
+			method:            http.MethodGet,
+			throttledAttempts: 3,
+			maxAttempts:       3,
+			expectedStatus:    http.StatusOK,
+			expectedAttempts:  3,
+		},
+		{
+			name:              "attempts exhausted",
+			method:            http.MethodGet,
+			throttledAttempts: 100,
+			maxAttempts:       2,
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedAttempts:  2,
+		},
+		{
+			name:              "writes aren't retried",
+			method:            http.MethodPut,
+			throttledAttempts: 1,
+			maxAttempts:       3,
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedAttempts:  0,
+		},
+	}
+
+	for _, v := range testData {
+		t.Logf("[DEBUG] Testing %q..", v.name)
+
+		// the SDK client also retries throttled requests itself (and this can't be disabled), so rather than counting the
+		// requests received by the server each attempt made by the middleware is numbered using a header - the original
+		// request is attempt 0 - and requests are throttled based on that
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempt, _ := strconv.Atoi(r.Header.Get(testAttemptHeader))
+			if attempt < v.throttledAttempts {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		// request middlewares are applied once for each attempt, rather than for each of the client's own retries
+		c := client.NewClient(server.URL, "test", "2020-01-01")
+		attempts := 0
+		c.AppendRequestMiddleware(func(request *http.Request) (*http.Request, error) {
+			attempts++
+			request.Header.Set(testAttemptHeader, strconv.Itoa(attempts))
+			return request, nil
+		})
+
+		request, err := http.NewRequest(v.method, server.URL, nil)
+		if err != nil {
+			t.Fatalf("building request: %+v", err)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("sending request: %+v", err)
+		}
+
+		response, err = ThrottledRequestRetryMiddleware(c, v.maxAttempts)(request, response)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		response.Body.Close()
+		server.Close()
+
+		if response.StatusCode != v.expectedStatus {
+			t.Fatalf("expected status %d but got %d", v.expectedStatus, response.StatusCode)
+		}
+		if attempts != v.expectedAttempts {
+			t.Fatalf("expected %d attempts but got %d", v.expectedAttempts, attempts)
+		}
+	}
+}
+
+const testAttemptHeader = "X-Test-Attempt"
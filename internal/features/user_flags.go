@@ -38,6 +38,10 @@ type VirtualMachineScaleSetFeatures struct {
 	ReimageOnManualUpgrade    bool
 	RollInstancesWhenRequired bool
 	ScaleToZeroOnDelete       bool
+
+	// ThrottledRequestRetryAttempts is the number of additional times a throttled (HTTP 429) read request
+	// to the Virtual Machine Scale Set and Skus APIs should be retried, a value of `0` disables this
+	ThrottledRequestRetryAttempts int
 }
 
 type KeyVaultFeatures struct {
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/features"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/pluginsdk"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/validation"
 )
 
 func schemaFeatures(supportLegacyTestSuite bool) *pluginsdk.Schema {
@@ -260,6 +261,12 @@ func schemaFeatures(supportLegacyTestSuite bool) *pluginsdk.Schema {
 						Optional: true,
 						Default:  false,
 					},
+					"throttled_request_retry_attempts": {
+						Type:         pluginsdk.TypeInt,
+						Optional:     true,
+						Default:      0,
+						ValidateFunc: validation.IntBetween(0, 10),
+					},
 				},
 			},
 		},
@@ -548,6 +555,9 @@ func expandFeatures(input []interface{}) features.UserFeatures {
 			if v, ok := scaleSetRaw["scale_to_zero_before_deletion"]; ok {
 				featuresMap.VirtualMachineScaleSet.ScaleToZeroOnDelete = v.(bool)
 			}
+			if v, ok := scaleSetRaw["throttled_request_retry_attempts"]; ok {
+				featuresMap.VirtualMachineScaleSet.ThrottledRequestRetryAttempts = v.(int)
+			}
 		}
 	}
 
@@ -1138,6 +1138,26 @@ func TestExpandFeaturesVirtualMachineScaleSet(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "Throttled Request Retry Attempts",
+			Input: []interface{}{
+				map[string]interface{}{
+					"virtual_machine_scale_set": []interface{}{
+						map[string]interface{}{
+							"throttled_request_retry_attempts": 5,
+						},
+					},
+				},
+			},
+			Expected: features.UserFeatures{
+				VirtualMachineScaleSet: features.VirtualMachineScaleSetFeatures{
+					ReimageOnManualUpgrade:        true,
+					RollInstancesWhenRequired:     true,
+					ScaleToZeroOnDelete:           true,
+					ThrottledRequestRetryAttempts: 5,
+				},
+			},
+		},
 		{
 			Name: "All Fields Disabled",
 			Input: []interface{}{
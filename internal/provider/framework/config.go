@@ -370,11 +370,17 @@ func (p *ProviderConfig) Load(ctx context.Context, data *ProviderModel, tfVersio
 			if !feature[0].ScaleToZeroBeforeDeletion.IsNull() && !feature[0].ScaleToZeroBeforeDeletion.IsUnknown() {
 				f.VirtualMachineScaleSet.ScaleToZeroOnDelete = feature[0].ScaleToZeroBeforeDeletion.ValueBool()
 			}
+
+			f.VirtualMachineScaleSet.ThrottledRequestRetryAttempts = 0
+			if !feature[0].ThrottledRequestRetryAttempts.IsNull() && !feature[0].ThrottledRequestRetryAttempts.IsUnknown() {
+				f.VirtualMachineScaleSet.ThrottledRequestRetryAttempts = int(feature[0].ThrottledRequestRetryAttempts.ValueInt64())
+			}
 		} else {
 			f.VirtualMachineScaleSet.ForceDelete = false
 			f.VirtualMachineScaleSet.ReimageOnManualUpgrade = true
 			f.VirtualMachineScaleSet.RollInstancesWhenRequired = true
 			f.VirtualMachineScaleSet.ScaleToZeroOnDelete = false
+			f.VirtualMachineScaleSet.ThrottledRequestRetryAttempts = 0
 		}
 
 		if !features.ResourceGroup.IsNull() && !features.ResourceGroup.IsUnknown() {
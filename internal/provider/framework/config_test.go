@@ -258,10 +258,11 @@ func defaultFeaturesList() types.List {
 	virtualMachineList, _ := basetypes.NewListValue(types.ObjectType{}.WithAttributeTypes(VirtualMachineAttributes), []attr.Value{virtualMachine})
 
 	virtualMachineScaleSet, _ := basetypes.NewObjectValueFrom(context.Background(), VirtualMachineScaleSetAttributes, map[string]attr.Value{
-		"force_delete":                  basetypes.NewBoolNull(),
-		"reimage_on_manual_upgrade":     basetypes.NewBoolNull(),
-		"roll_instances_when_required":  basetypes.NewBoolNull(),
-		"scale_to_zero_before_deletion": basetypes.NewBoolNull(),
+		"force_delete":                     basetypes.NewBoolNull(),
+		"reimage_on_manual_upgrade":        basetypes.NewBoolNull(),
+		"roll_instances_when_required":     basetypes.NewBoolNull(),
+		"scale_to_zero_before_deletion":    basetypes.NewBoolNull(),
+		"throttled_request_retry_attempts": basetypes.NewInt64Null(),
 	})
 	virtualMachineScaleSetList, _ := basetypes.NewListValue(types.ObjectType{}.WithAttributeTypes(VirtualMachineScaleSetAttributes), []attr.Value{virtualMachineScaleSet})
 
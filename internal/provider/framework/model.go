@@ -175,17 +175,19 @@ var VirtualMachineAttributes = map[string]attr.Type{
 }
 
 type VirtualMachineScaleSet struct {
-	ForceDelete               types.Bool `tfsdk:"force_delete"`
-	ReimageOnManualUpgrade    types.Bool `tfsdk:"reimage_on_manual_upgrade"`
-	RollInstancesWhenRequired types.Bool `tfsdk:"roll_instances_when_required"`
-	ScaleToZeroBeforeDeletion types.Bool `tfsdk:"scale_to_zero_before_deletion"`
+	ForceDelete                   types.Bool  `tfsdk:"force_delete"`
+	ReimageOnManualUpgrade        types.Bool  `tfsdk:"reimage_on_manual_upgrade"`
+	RollInstancesWhenRequired     types.Bool  `tfsdk:"roll_instances_when_required"`
+	ScaleToZeroBeforeDeletion     types.Bool  `tfsdk:"scale_to_zero_before_deletion"`
+	ThrottledRequestRetryAttempts types.Int64 `tfsdk:"throttled_request_retry_attempts"`
 }
 
 var VirtualMachineScaleSetAttributes = map[string]attr.Type{
-	"force_delete":                  types.BoolType,
-	"reimage_on_manual_upgrade":     types.BoolType,
-	"roll_instances_when_required":  types.BoolType,
-	"scale_to_zero_before_deletion": types.BoolType,
+	"force_delete":                     types.BoolType,
+	"reimage_on_manual_upgrade":        types.BoolType,
+	"roll_instances_when_required":     types.BoolType,
+	"scale_to_zero_before_deletion":    types.BoolType,
+	"throttled_request_retry_attempts": types.Int64Type,
 }
 
 type ResourceGroup struct {
@@ -18,6 +18,7 @@ import (
 	providerfunction "github.com/hashicorp/terraform-provider-azurerm/internal/provider/function"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/resourceproviders"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/sdk/frameworkhelpers"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/validation"
 )
 
 type azureRmFrameworkProvider struct {
@@ -386,6 +387,16 @@ func (p *azureRmFrameworkProvider) Schema(_ context.Context, _ provider.SchemaRe
 									"scale_to_zero_before_deletion": schema.BoolAttribute{
 										Optional: true,
 									},
+									"throttled_request_retry_attempts": schema.Int64Attribute{
+										Optional: true,
+										Validators: []validator.Int64{
+											frameworkhelpers.WrappedInt64Validator{
+												Func:         validation.IntBetween(0, 10),
+												Desc:         "The number of times to retry a throttled request must be between 0 and 10.",
+												MarkdownDesc: "The number of times to retry a throttled request must be between `0` and `10`.",
+											},
+										},
+									},
 								},
 							},
 						},
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azuresdkhacks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/cdn/mgmt/2021-06-01/cdn" // nolint: staticcheck
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// NOTE: this workaround client exists to provide the predicate based listing available in the newer `go-azure-sdk` clients,
+// so that callers can filter the Rule Sets within a Profile without manually paging through the results
+type CdnFrontDoorRuleSetsWorkaroundClient struct {
+	sdkClient *cdn.RuleSetsClient
+}
+
+func NewCdnFrontDoorRuleSetsWorkaroundClient(client *cdn.RuleSetsClient) CdnFrontDoorRuleSetsWorkaroundClient {
+	return CdnFrontDoorRuleSetsWorkaroundClient{
+		sdkClient: client,
+	}
+}
+
+type RuleSetOperationPredicate struct {
+	Id   *string
+	Name *string
+	Type *string
+
+	// NamePrefix matches Rule Sets whose name starts with this value, compared case-insensitively since Rule Set names are case-insensitive
+	NamePrefix *string
+}
+
+func (p RuleSetOperationPredicate) Matches(input cdn.RuleSet) bool {
+	if p.Id != nil && (input.ID == nil || *p.Id != *input.ID) {
+		return false
+	}
+
+	if p.Name != nil && (input.Name == nil || *p.Name != *input.Name) {
+		return false
+	}
+
+	if p.Type != nil && (input.Type == nil || *p.Type != *input.Type) {
+		return false
+	}
+
+	if p.NamePrefix != nil && (input.Name == nil || !strings.HasPrefix(strings.ToLower(*input.Name), strings.ToLower(*p.NamePrefix))) {
+		return false
+	}
+
+	return true
+}
+
+type RuleSetsListByProfileCompleteResult struct {
+	LatestHttpResponse autorest.Response
+	Items              []cdn.RuleSet
+}
+
+// ListByProfileComplete retrieves all the Rule Sets within the specified Profile
+func (c CdnFrontDoorRuleSetsWorkaroundClient) ListByProfileComplete(ctx context.Context, resourceGroupName string, profileName string) (RuleSetsListByProfileCompleteResult, error) {
+	return c.ListByProfileCompleteMatchingPredicate(ctx, resourceGroupName, profileName, RuleSetOperationPredicate{})
+}
+
+// ListByProfileCompleteMatchingPredicate retrieves all the results and then applies the predicate
+func (c CdnFrontDoorRuleSetsWorkaroundClient) ListByProfileCompleteMatchingPredicate(ctx context.Context, resourceGroupName string, profileName string, predicate RuleSetOperationPredicate) (result RuleSetsListByProfileCompleteResult, err error) {
+	items := make([]cdn.RuleSet, 0)
+
+	iterator, err := c.sdkClient.ListByProfileComplete(ctx, resourceGroupName, profileName)
+	if err != nil {
+		result.LatestHttpResponse = ruleSetsLatestHttpResponse(err, iterator.Response().Response)
+		err = fmt.Errorf("loading results: %+v", err)
+		return
+	}
+
+	// the iterator discards the response of the last page once it's exhausted, so this is tracked as each page is read
+	latestHttpResponse := iterator.Response().Response
+	for iterator.NotDone() {
+		latestHttpResponse = iterator.Response().Response
+		if v := iterator.Value(); predicate.Matches(v) {
+			items = append(items, v)
+		}
+
+		if err = iterator.NextWithContext(ctx); err != nil {
+			result.LatestHttpResponse = ruleSetsLatestHttpResponse(err, latestHttpResponse)
+			err = fmt.Errorf("loading results: %+v", err)
+			return
+		}
+	}
+
+	result = RuleSetsListByProfileCompleteResult{
+		LatestHttpResponse: latestHttpResponse,
+		Items:              items,
+	}
+	return
+}
+
+// ruleSetsLatestHttpResponse returns the response of the failed request when it's available from the error, since the iterator
+// only exposes the response of the last page which was read successfully
+func ruleSetsLatestHttpResponse(err error, fallback autorest.Response) autorest.Response {
+	if detailed, ok := err.(autorest.DetailedError); ok && detailed.Response != nil {
+		return autorest.Response{Response: detailed.Response}
+	}
+
+	return fallback
+}
@@ -0,0 +1,187 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package azuresdkhacks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/cdn/mgmt/2021-06-01/cdn" // nolint: staticcheck
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+)
+
+func TestRuleSetOperationPredicateMatches(t *testing.T) {
+	ruleSet := cdn.RuleSet{
+		ID:   pointer.To("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Cdn/profiles/profile1/ruleSets/RedirectRules"),
+		Name: pointer.To("RedirectRules"),
+		Type: pointer.To("Microsoft.Cdn/profiles/ruleSets"),
+	}
+
+	cases := []struct {
+		name      string
+		predicate RuleSetOperationPredicate
+		input     cdn.RuleSet
+		expected  bool
+	}{
+		{
+			name:      "empty predicate",
+			predicate: RuleSetOperationPredicate{},
+			input:     ruleSet,
+			expected:  true,
+		},
+		{
+			name:      "matching id",
+			predicate: RuleSetOperationPredicate{Id: ruleSet.ID},
+			input:     ruleSet,
+			expected:  true,
+		},
+		{
+			name:      "different id",
+			predicate: RuleSetOperationPredicate{Id: pointer.To("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Cdn/profiles/profile1/ruleSets/Other")},
+			input:     ruleSet,
+			expected:  false,
+		},
+		{
+			name:      "matching name",
+			predicate: RuleSetOperationPredicate{Name: pointer.To("RedirectRules")},
+			input:     ruleSet,
+			expected:  true,
+		},
+		{
+			name:      "name is compared case-sensitively",
+			predicate: RuleSetOperationPredicate{Name: pointer.To("redirectrules")},
+			input:     ruleSet,
+			expected:  false,
+		},
+		{
+			name:      "different type",
+			predicate: RuleSetOperationPredicate{Type: pointer.To("Microsoft.Cdn/profiles/routes")},
+			input:     ruleSet,
+			expected:  false,
+		},
+		{
+			name:      "matching name prefix",
+			predicate: RuleSetOperationPredicate{NamePrefix: pointer.To("Redirect")},
+			input:     ruleSet,
+			expected:  true,
+		},
+		{
+			name:      "name prefix is compared case-insensitively",
+			predicate: RuleSetOperationPredicate{NamePrefix: pointer.To("redirect")},
+			input:     ruleSet,
+			expected:  true,
+		},
+		{
+			name:      "different name prefix",
+			predicate: RuleSetOperationPredicate{NamePrefix: pointer.To("Rewrite")},
+			input:     ruleSet,
+			expected:  false,
+		},
+		{
+			name:      "name prefix without a name",
+			predicate: RuleSetOperationPredicate{NamePrefix: pointer.To("Redirect")},
+			input:     cdn.RuleSet{},
+			expected:  false,
+		},
+		{
+			name:      "all fields must match",
+			predicate: RuleSetOperationPredicate{Name: pointer.To("RedirectRules"), NamePrefix: pointer.To("Rewrite")},
+			input:     ruleSet,
+			expected:  false,
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := tc.predicate.Matches(tc.input); actual != tc.expected {
+			t.Fatalf("expected %q to match: %t but got %t", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestCdnFrontDoorRuleSetsWorkaroundClientListByProfileCompleteMatchingPredicate(t *testing.T) {
+	ruleSetsPath := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Cdn/profiles/profile1/ruleSets"
+	ruleSet := func(name string) string {
+		return fmt.Sprintf(`{"id": %q, "name": %q, "type": "Microsoft.Cdn/profiles/ruleSets"}`, ruleSetsPath+"/"+name, name)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"value": [%s, %s], "nextLink": %q}`, ruleSet("RedirectRules"), ruleSet("RewriteRules"), server.URL+ruleSetsPath+"?page=2")
+		case "2":
+			fmt.Fprintf(w, `{"value": [%s], "nextLink": %q}`, ruleSet("redirectOther"), server.URL+ruleSetsPath+"?page=3")
+		case "3":
+			fmt.Fprintf(w, `{"value": [%s]}`, ruleSet("CachingRules"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sdkClient := cdn.NewRuleSetsClientWithBaseURI(server.URL, "12345678-1234-9876-4563-123456789012")
+	client := NewCdnFrontDoorRuleSetsWorkaroundClient(&sdkClient)
+
+	all, err := client.ListByProfileComplete(context.Background(), "group1", "profile1")
+	if err != nil {
+		t.Fatalf("listing all Rule Sets: %+v", err)
+	}
+	if actual, expected := ruleSetNames(all.Items), []string{"RedirectRules", "RewriteRules", "redirectOther", "CachingRules"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected the Rule Sets from every page %q but got %q", expected, actual)
+	}
+
+	matching, err := client.ListByProfileCompleteMatchingPredicate(context.Background(), "group1", "profile1", RuleSetOperationPredicate{
+		NamePrefix: pointer.To("Redirect"),
+	})
+	if err != nil {
+		t.Fatalf("listing the Rule Sets matching the predicate: %+v", err)
+	}
+	if actual, expected := ruleSetNames(matching.Items), []string{"RedirectRules", "redirectOther"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected the Rule Sets matching the predicate across every page %q but got %q", expected, actual)
+	}
+	if matching.LatestHttpResponse.Response == nil || matching.LatestHttpResponse.StatusCode != http.StatusOK {
+		t.Fatalf("expected the latest HTTP response to be populated but got: %+v", matching.LatestHttpResponse)
+	}
+}
+
+func TestCdnFrontDoorRuleSetsWorkaroundClientListByProfileCompleteMatchingPredicate_pageError(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") != "" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": "NotFound", "message": "page not found"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"value": [{"name": "RedirectRules"}], "nextLink": %q}`, server.URL+r.URL.Path+"?page=2")
+	}))
+	defer server.Close()
+
+	sdkClient := cdn.NewRuleSetsClientWithBaseURI(server.URL, "12345678-1234-9876-4563-123456789012")
+	client := NewCdnFrontDoorRuleSetsWorkaroundClient(&sdkClient)
+
+	result, err := client.ListByProfileCompleteMatchingPredicate(context.Background(), "group1", "profile1", RuleSetOperationPredicate{})
+	if err == nil {
+		t.Fatalf("expected an error when a subsequent page can't be retrieved")
+	}
+	if len(result.Items) != 0 {
+		t.Fatalf("expected no Rule Sets to be returned alongside the error but got %d", len(result.Items))
+	}
+	if result.LatestHttpResponse.Response == nil || result.LatestHttpResponse.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the latest HTTP response to be the failed page but got: %+v", result.LatestHttpResponse)
+	}
+}
+
+func ruleSetNames(input []cdn.RuleSet) []string {
+	names := make([]string, 0, len(input))
+	for _, v := range input {
+		names = append(names, pointer.From(v.Name))
+	}
+	return names
+}
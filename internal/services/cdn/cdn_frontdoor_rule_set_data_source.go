@@ -43,6 +43,34 @@ func dataSourceCdnFrontDoorRuleSet() *pluginsdk.Resource {
 				Type:     pluginsdk.TypeString,
 				Computed: true,
 			},
+
+			"usage": {
+				Type:     pluginsdk.TypeList,
+				Computed: true,
+				Elem: &pluginsdk.Resource{
+					Schema: map[string]*pluginsdk.Schema{
+						"name": {
+							Type:     pluginsdk.TypeString,
+							Computed: true,
+						},
+
+						"current_value": {
+							Type:     pluginsdk.TypeInt,
+							Computed: true,
+						},
+
+						"limit": {
+							Type:     pluginsdk.TypeInt,
+							Computed: true,
+						},
+
+						"unit": {
+							Type:     pluginsdk.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -68,5 +96,13 @@ func dataSourceCdnFrontDoorRuleSetRead(d *pluginsdk.ResourceData, meta interface
 	d.Set("resource_group_name", id.ResourceGroup)
 	d.Set("cdn_frontdoor_profile_id", parse.NewFrontDoorProfileID(id.SubscriptionId, id.ResourceGroup, id.ProfileName).ID())
 
+	usages, err := frontDoorRuleSetResourceUsage(ctx, client, id)
+	if err != nil {
+		return err
+	}
+	if err := d.Set("usage", flattenFrontDoorRuleSetUsages(usages)); err != nil {
+		return fmt.Errorf("setting `usage`: %+v", err)
+	}
+
 	return nil
 }
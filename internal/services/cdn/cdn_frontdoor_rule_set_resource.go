@@ -4,9 +4,11 @@
 package cdn
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/cdn/mgmt/2021-06-01/cdn" // nolint: staticcheck
 	"github.com/hashicorp/terraform-provider-azurerm/helpers/tf"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/clients"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/services/cdn/parse"
@@ -63,22 +65,26 @@ func resourceCdnFrontDoorRuleSetCreate(d *pluginsdk.ResourceData, meta interface
 
 	id := parse.NewFrontDoorRuleSetID(profile.SubscriptionId, profile.ResourceGroup, profile.ProfileName, d.Get("name").(string))
 	if d.IsNewResource() {
-		existing, err := client.Get(ctx, id.ResourceGroup, id.ProfileName, id.RuleSetName)
+		exists, err := cdnFrontDoorRuleSetExists(ctx, client, id)
 		if err != nil {
-			if !utils.ResponseWasNotFound(existing.Response) {
-				return fmt.Errorf("checking for existing %s: %+v", id, err)
-			}
+			return err
 		}
 
-		if !utils.ResponseWasNotFound(existing.Response) {
+		if exists {
 			return tf.ImportAsExistsError("azurerm_cdn_frontdoor_rule_set", id.ID())
 		}
 	}
 
-	if _, err = client.Create(ctx, id.ResourceGroup, id.ProfileName, id.RuleSetName); err != nil {
+	resp, err := client.Create(ctx, id.ResourceGroup, id.ProfileName, id.RuleSetName)
+	if err != nil {
 		return fmt.Errorf("creating %s: %+v", id, err)
 	}
 
+	// the Create operation completes synchronously, so a failed provisioning is only surfaced in the returned Rule Set
+	if props := resp.RuleSetProperties; props != nil && props.ProvisioningState == cdn.AfdProvisioningStateFailed {
+		return fmt.Errorf("creating %s: provisioning failed with Deployment Status %q", id, string(props.DeploymentStatus))
+	}
+
 	d.SetId(id.ID())
 	return resourceCdnFrontDoorRuleSetRead(d, meta)
 }
@@ -129,3 +135,17 @@ func resourceCdnFrontDoorRuleSetDelete(d *pluginsdk.ResourceData, meta interface
 
 	return nil
 }
+
+// cdnFrontDoorRuleSetExists returns whether the specified Rule Set exists, since the Create operation is a PUT which would
+// otherwise silently take over an existing Rule Set - any error other than a 404 is returned rather than treated as not found
+func cdnFrontDoorRuleSetExists(ctx context.Context, client *cdn.RuleSetsClient, id parse.FrontDoorRuleSetId) (bool, error) {
+	resp, err := client.Get(ctx, id.ResourceGroup, id.ProfileName, id.RuleSetName)
+	if err != nil {
+		if utils.ResponseWasNotFound(resp.Response) {
+			return false, nil
+		}
+		return false, fmt.Errorf("checking for existing %s: %+v", id, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/services/cdn/mgmt/2021-06-01/cdn" // nolint: staticcheck
+	"github.com/hashicorp/terraform-provider-azurerm/internal/services/cdn/parse"
+)
+
+type frontDoorRuleSetUsage struct {
+	Current int64
+	Limit   int64
+	Unit    string
+}
+
+// frontDoorRuleSetResourceUsage retrieves the quota usage of a Front Door Rule Set, keyed by the name of the resource the quota applies to
+func frontDoorRuleSetResourceUsage(ctx context.Context, client *cdn.RuleSetsClient, id parse.FrontDoorRuleSetId) (map[string]frontDoorRuleSetUsage, error) {
+	iterator, err := client.ListResourceUsageComplete(ctx, id.ResourceGroup, id.ProfileName, id.RuleSetName)
+	if err != nil {
+		return nil, fmt.Errorf("listing the resource usage for %s: %+v", id, err)
+	}
+
+	usages, err := flattenFrontDoorRuleSetResourceUsage(ctx, iterator)
+	if err != nil {
+		return nil, fmt.Errorf("listing the resource usage for %s: %+v", id, err)
+	}
+
+	return usages, nil
+}
+
+func flattenFrontDoorRuleSetResourceUsage(ctx context.Context, iterator cdn.UsagesListResultIterator) (map[string]frontDoorRuleSetUsage, error) {
+	usages := make(map[string]frontDoorRuleSetUsage)
+
+	for iterator.NotDone() {
+		item := iterator.Value()
+		if item.Name != nil && item.Name.Value != nil {
+			usage := frontDoorRuleSetUsage{}
+			if item.CurrentValue != nil {
+				usage.Current = *item.CurrentValue
+			}
+			if item.Limit != nil {
+				usage.Limit = *item.Limit
+			}
+			if item.Unit != nil {
+				usage.Unit = *item.Unit
+			}
+
+			usages[*item.Name.Value] = usage
+		}
+
+		if err := iterator.NextWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return usages, nil
+}
+
+// flattenFrontDoorRuleSetUsages returns the usage of each resource within the Rule Set, ordered by the name of the resource
+func flattenFrontDoorRuleSetUsages(usages map[string]frontDoorRuleSetUsage) []interface{} {
+	names := make([]string, 0, len(usages))
+	for name := range usages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	output := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		usage := usages[name]
+		output = append(output, map[string]interface{}{
+			"name":          name,
+			"current_value": int(usage.Current),
+			"limit":         int(usage.Limit),
+			"unit":          usage.Unit,
+		})
+	}
+
+	return output
+}
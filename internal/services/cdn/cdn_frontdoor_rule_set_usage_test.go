@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/cdn/mgmt/2021-06-01/cdn" // nolint: staticcheck
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+)
+
+func TestFlattenFrontDoorRuleSetResourceUsage(t *testing.T) {
+	usage := func(name string, current int64, limit int64) cdn.Usage {
+		return cdn.Usage{
+			Name:         &cdn.UsageName{Value: pointer.To(name)},
+			CurrentValue: pointer.To(current),
+			Limit:        pointer.To(limit),
+			Unit:         pointer.To("Count"),
+		}
+	}
+
+	firstPage := cdn.UsagesListResult{
+		Value:    &[]cdn.Usage{usage("rule", 20, 25)},
+		NextLink: pointer.To("https://management.azure.com/next"),
+	}
+	secondPage := cdn.UsagesListResult{
+		Value: &[]cdn.Usage{usage("condition", 3, 10), {CurrentValue: pointer.To(int64(1))}},
+	}
+	nextPage := func(ctx context.Context, current cdn.UsagesListResult) (cdn.UsagesListResult, error) {
+		if current.NextLink == nil {
+			return cdn.UsagesListResult{}, nil
+		}
+		return secondPage, nil
+	}
+
+	iterator := cdn.NewUsagesListResultIterator(cdn.NewUsagesListResultPage(firstPage, nextPage))
+	actual, err := flattenFrontDoorRuleSetResourceUsage(context.TODO(), iterator)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := map[string]frontDoorRuleSetUsage{
+		"rule":      {Current: 20, Limit: 25, Unit: "Count"},
+		"condition": {Current: 3, Limit: 10, Unit: "Count"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %+v but got %+v", expected, actual)
+	}
+
+	flattened := flattenFrontDoorRuleSetUsages(actual)
+	expectedFlattened := []interface{}{
+		map[string]interface{}{"name": "condition", "current_value": 3, "limit": 10, "unit": "Count"},
+		map[string]interface{}{"name": "rule", "current_value": 20, "limit": 25, "unit": "Count"},
+	}
+	if !reflect.DeepEqual(flattened, expectedFlattened) {
+		t.Fatalf("expected %+v but got %+v", expectedFlattened, flattened)
+	}
+
+	failingPage := func(ctx context.Context, current cdn.UsagesListResult) (cdn.UsagesListResult, error) {
+		return cdn.UsagesListResult{}, fmt.Errorf("throttled")
+	}
+	iterator = cdn.NewUsagesListResultIterator(cdn.NewUsagesListResultPage(firstPage, failingPage))
+	if _, err := flattenFrontDoorRuleSetResourceUsage(context.TODO(), iterator); err == nil {
+		t.Fatalf("expected an error when retrieving the next page fails but didn't get one")
+	}
+}
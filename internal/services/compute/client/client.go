@@ -76,6 +76,10 @@ type Client struct {
 	VirtualMachineScaleSetRollingUpgradesClient *virtualmachinescalesetrollingupgrades.VirtualMachineScaleSetRollingUpgradesClient
 	VirtualMachineScaleSetVMsClient             *virtualmachinescalesetvms.VirtualMachineScaleSetVMsClient
 	VirtualMachineImagesClient                  *virtualmachineimages.VirtualMachineImagesClient
+
+	listSkus                     func(ctx context.Context, location string) ([]skus.ResourceSku, error)
+	marketplaceAgreementAccepted func(ctx context.Context, publisher string, offer string, plan string) (*bool, error)
+	skuCapabilities              *skuCapabilitiesCache
 }
 
 func NewClient(o *common.ClientOptions) (*Client, error) {
@@ -198,6 +202,10 @@ func NewClient(o *common.ClientOptions) (*Client, error) {
 		return nil, fmt.Errorf("building Skus client: %+v", err)
 	}
 	o.Configure(skusClient.Client, o.Authorizers.ResourceManager)
+	if attempts := o.Features.VirtualMachineScaleSet.ThrottledRequestRetryAttempts; attempts > 0 {
+		skusClient.Client.AppendResponseMiddleware(common.ThrottledRequestRetryMiddleware(skusClient.Client.Client, attempts))
+	}
+	listSkus := listVirtualMachineSkusForLocation(skusClient, o.SubscriptionId)
 
 	snapshotsClient, err := snapshots.NewSnapshotsClientWithBaseURI(o.Environment.ResourceManager)
 	if err != nil {
@@ -240,6 +248,9 @@ func NewClient(o *common.ClientOptions) (*Client, error) {
 		return nil, fmt.Errorf("building VirtualMachineScaleSets client: %+v", err)
 	}
 	o.Configure(virtualMachineScaleSetsClient.Client, o.Authorizers.ResourceManager)
+	if attempts := o.Features.VirtualMachineScaleSet.ThrottledRequestRetryAttempts; attempts > 0 {
+		virtualMachineScaleSetsClient.Client.AppendResponseMiddleware(common.ThrottledRequestRetryMiddleware(virtualMachineScaleSetsClient.Client.Client, attempts))
+	}
 
 	virtualMachineScaleSetExtensionsClient, err := virtualmachinescalesetextensions.NewVirtualMachineScaleSetExtensionsClientWithBaseURI(o.Environment.ResourceManager)
 	if err != nil {
@@ -290,6 +301,10 @@ func NewClient(o *common.ClientOptions) (*Client, error) {
 		VirtualMachineScaleSetRollingUpgradesClient: virtualMachineScaleSetRollingUpgradesClient,
 		VirtualMachineScaleSetVMsClient:             virtualMachineScaleSetVMsClient,
 		VirtualMachineImagesClient:                  vmImageClient,
+
+		listSkus:                     listSkus,
+		marketplaceAgreementAccepted: getMarketplaceAgreementAccepted(marketplaceAgreementsClient, o.SubscriptionId),
+		skuCapabilities:              newSkuCapabilitiesCache(listSkus),
 	}, nil
 }
 
@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/lang/response"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/marketplaceordering/2015-06-01/agreements"
+)
+
+// MarketplaceAgreementAccepted returns whether the Marketplace Agreement for the specified Plan has been accepted within
+// this Subscription, or nil if the Plan doesn't have a Marketplace Agreement
+func (c *Client) MarketplaceAgreementAccepted(ctx context.Context, publisher string, offer string, plan string) (*bool, error) {
+	return c.marketplaceAgreementAccepted(ctx, publisher, offer, plan)
+}
+
+func getMarketplaceAgreementAccepted(client *agreements.AgreementsClient, subscriptionId string) func(ctx context.Context, publisher string, offer string, plan string) (*bool, error) {
+	return func(ctx context.Context, publisher string, offer string, plan string) (*bool, error) {
+		id := agreements.NewOfferPlanID(subscriptionId, publisher, offer, plan)
+		resp, err := client.MarketplaceAgreementsGet(ctx, id)
+		if err != nil {
+			if response.WasNotFound(resp.HttpResponse) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("retrieving %s: %+v", id, err)
+		}
+
+		accepted := false
+		if model := resp.Model; model != nil && model.Properties != nil {
+			accepted = pointer.From(model.Properties.Accepted)
+		}
+		return pointer.To(accepted), nil
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/lang/response"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetrollingupgrades"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesets"
+)
+
+type RollingUpgradeState string
+
+const (
+	RollingUpgradeStateRunning   RollingUpgradeState = "Running"
+	RollingUpgradeStateCompleted RollingUpgradeState = "Completed"
+	RollingUpgradeStateFailed    RollingUpgradeState = "Failed"
+	RollingUpgradeStateCancelled RollingUpgradeState = "Cancelled"
+)
+
+// LatestRollingUpgrade describes the progress of the latest Rolling Upgrade of a Virtual Machine Scale Set
+type LatestRollingUpgrade struct {
+	State RollingUpgradeState
+
+	// StatusCode is the status returned from the API, which State is derived from
+	StatusCode string
+
+	StartTime      string
+	LastActionTime string
+	ErrorMessage   string
+
+	FailedInstanceCount     int64
+	InProgressInstanceCount int64
+	PendingInstanceCount    int64
+	SuccessfulInstanceCount int64
+}
+
+// GetLatestRollingUpgrade returns the status of the latest Rolling Upgrade for the Virtual Machine Scale Set - nil is
+// returned when no Rolling Upgrade has taken place
+func (c *Client) GetLatestRollingUpgrade(ctx context.Context, id virtualmachinescalesets.VirtualMachineScaleSetId) (*LatestRollingUpgrade, error) {
+	// TODO replace with commonid once https://github.com/hashicorp/pandora/issues/4017 has been merged
+	virtualMachineScaleSetId := virtualmachinescalesetrollingupgrades.NewVirtualMachineScaleSetID(id.SubscriptionId, id.ResourceGroupName, id.VirtualMachineScaleSetName)
+
+	resp, err := c.VirtualMachineScaleSetRollingUpgradesClient.GetLatest(ctx, virtualMachineScaleSetId)
+	if err != nil {
+		if response.WasNotFound(resp.HttpResponse) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("retrieving the latest rolling upgrade for %s: %+v", id, err)
+	}
+
+	if resp.Model == nil || resp.Model.Properties == nil {
+		return nil, fmt.Errorf("retrieving the latest rolling upgrade for %s: `properties` was nil", id)
+	}
+
+	return pointer.To(latestRollingUpgradeFromProperties(*resp.Model.Properties)), nil
+}
+
+func latestRollingUpgradeFromProperties(input virtualmachinescalesetrollingupgrades.RollingUpgradeStatusInfoProperties) LatestRollingUpgrade {
+	output := LatestRollingUpgrade{}
+
+	if status := input.RunningStatus; status != nil {
+		code := pointer.From(status.Code)
+		output.StatusCode = string(code)
+		output.StartTime = pointer.From(status.StartTime)
+		output.LastActionTime = pointer.From(status.LastActionTime)
+
+		switch code {
+		case virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeRollingForward:
+			output.State = RollingUpgradeStateRunning
+		case virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeCompleted:
+			output.State = RollingUpgradeStateCompleted
+		case virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeFaulted:
+			output.State = RollingUpgradeStateFailed
+		case virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeCancelled:
+			output.State = RollingUpgradeStateCancelled
+		}
+	}
+
+	if progress := input.Progress; progress != nil {
+		output.FailedInstanceCount = pointer.From(progress.FailedInstanceCount)
+		output.InProgressInstanceCount = pointer.From(progress.InProgressInstanceCount)
+		output.PendingInstanceCount = pointer.From(progress.PendingInstanceCount)
+		output.SuccessfulInstanceCount = pointer.From(progress.SuccessfulInstanceCount)
+	}
+
+	if apiErr := input.Error; apiErr != nil {
+		output.ErrorMessage = pointer.From(apiErr.Message)
+	}
+
+	return output
+}
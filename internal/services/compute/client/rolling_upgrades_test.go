@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetrollingupgrades"
+)
+
+func TestLatestRollingUpgradeFromProperties(t *testing.T) {
+	properties := func(code virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCode) virtualmachinescalesetrollingupgrades.RollingUpgradeStatusInfoProperties {
+		return virtualmachinescalesetrollingupgrades.RollingUpgradeStatusInfoProperties{
+			RunningStatus: &virtualmachinescalesetrollingupgrades.RollingUpgradeRunningStatus{
+				Code:      pointer.To(code),
+				StartTime: pointer.To("2024-01-01T00:00:00Z"),
+			},
+			Progress: &virtualmachinescalesetrollingupgrades.RollingUpgradeProgressInfo{
+				FailedInstanceCount:     pointer.To(int64(1)),
+				InProgressInstanceCount: pointer.To(int64(2)),
+				PendingInstanceCount:    pointer.To(int64(3)),
+				SuccessfulInstanceCount: pointer.To(int64(4)),
+			},
+		}
+	}
+
+	cases := []struct {
+		code     virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCode
+		expected RollingUpgradeState
+	}{
+		{
+			code:     virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeRollingForward,
+			expected: RollingUpgradeStateRunning,
+		},
+		{
+			code:     virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeCompleted,
+			expected: RollingUpgradeStateCompleted,
+		},
+		{
+			code:     virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeFaulted,
+			expected: RollingUpgradeStateFailed,
+		},
+		{
+			code:     virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeCancelled,
+			expected: RollingUpgradeStateCancelled,
+		},
+	}
+
+	for _, tc := range cases {
+		actual := latestRollingUpgradeFromProperties(properties(tc.code))
+		if actual.State != tc.expected {
+			t.Fatalf("expected the state for %q to be %q but got %q", tc.code, tc.expected, actual.State)
+		}
+		if actual.StatusCode != string(tc.code) || actual.StartTime != "2024-01-01T00:00:00Z" {
+			t.Fatalf("expected the running status for %q to be populated but got %+v", tc.code, actual)
+		}
+		if actual.FailedInstanceCount != 1 || actual.InProgressInstanceCount != 2 || actual.PendingInstanceCount != 3 || actual.SuccessfulInstanceCount != 4 {
+			t.Fatalf("expected the instance counts for %q to be populated but got %+v", tc.code, actual)
+		}
+	}
+
+	faulted := properties(virtualmachinescalesetrollingupgrades.RollingUpgradeStatusCodeFaulted)
+	faulted.Error = &virtualmachinescalesetrollingupgrades.ApiError{
+		Message: pointer.To("the upgrade exceeded the unhealthy instance threshold"),
+	}
+	if actual := latestRollingUpgradeFromProperties(faulted); actual.ErrorMessage != "the upgrade exceeded the unhealthy instance threshold" {
+		t.Fatalf("expected the error message to be populated but got %q", actual.ErrorMessage)
+	}
+
+	if actual := latestRollingUpgradeFromProperties(virtualmachinescalesetrollingupgrades.RollingUpgradeStatusInfoProperties{}); actual.State != "" || actual.SuccessfulInstanceCount != 0 {
+		t.Fatalf("expected an empty status when no properties are returned but got %+v", actual)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/location"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2021-07-01/skus"
+)
+
+// skuCapabilitiesCacheTTL is how long the SKUs retrieved for a Location are cached for - these change rarely
+// so this mainly exists to ensure that long-running applies eventually pick up any changes
+const skuCapabilitiesCacheTTL = 30 * time.Minute
+
+// SkuCapabilities describes the capabilities of a Virtual Machine SKU within a Location
+type SkuCapabilities struct {
+	Name         string
+	Location     string
+	Zones        []string
+	Capabilities map[string]string
+}
+
+// CapabilityValue returns the value of the specified capability, matched case-insensitively
+func (s SkuCapabilities) CapabilityValue(name string) (string, bool) {
+	for k, v := range s.Capabilities {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// HasCapability returns whether the specified capability exists and is set to `True`
+func (s SkuCapabilities) HasCapability(name string) bool {
+	v, ok := s.CapabilityValue(name)
+	return ok && strings.EqualFold(v, "True")
+}
+
+// CapabilityInt returns the value of the specified capability as an integer, if it exists and is numeric
+func (s SkuCapabilities) CapabilityInt(name string) (int64, bool) {
+	v, ok := s.CapabilityValue(name)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+type skuCapabilitiesCacheEntry struct {
+	expiresAt time.Time
+	skus      map[string]SkuCapabilities
+}
+
+// skuCapabilitiesCache caches the Virtual Machine SKUs available within a Location, keyed by Location and then SKU Name
+type skuCapabilitiesCache struct {
+	sync.Mutex
+
+	entries  map[string]skuCapabilitiesCacheEntry
+	listSkus func(ctx context.Context, location string) ([]skus.ResourceSku, error)
+	now      func() time.Time
+	ttl      time.Duration
+}
+
+func newSkuCapabilitiesCache(listSkus func(ctx context.Context, location string) ([]skus.ResourceSku, error)) *skuCapabilitiesCache {
+	return &skuCapabilitiesCache{
+		entries:  make(map[string]skuCapabilitiesCacheEntry),
+		listSkus: listSkus,
+		now:      time.Now,
+		ttl:      skuCapabilitiesCacheTTL,
+	}
+}
+
+func (c *skuCapabilitiesCache) get(ctx context.Context, skuLocation string, skuName string) (*SkuCapabilities, error) {
+	entry, err := c.entry(ctx, skuLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	sku, ok := entry.skus[strings.ToLower(skuName)]
+	if !ok {
+		return nil, nil
+	}
+	return &sku, nil
+}
+
+func (c *skuCapabilitiesCache) list(ctx context.Context, skuLocation string) ([]SkuCapabilities, error) {
+	entry, err := c.entry(ctx, skuLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entry.skus))
+	for name := range entry.skus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	output := make([]SkuCapabilities, 0, len(names))
+	for _, name := range names {
+		output = append(output, entry.skus[name])
+	}
+	return output, nil
+}
+
+func (c *skuCapabilitiesCache) entry(ctx context.Context, skuLocation string) (*skuCapabilitiesCacheEntry, error) {
+	skuLocation = location.Normalize(skuLocation)
+
+	// the lock is held whilst retrieving the SKUs so that concurrent lookups for the same Location only make a single request
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[skuLocation]
+	if !ok || c.now().After(entry.expiresAt) {
+		items, err := c.listSkus(ctx, skuLocation)
+		if err != nil {
+			return nil, err
+		}
+
+		entry = skuCapabilitiesCacheEntry{
+			expiresAt: c.now().Add(c.ttl),
+			skus:      make(map[string]SkuCapabilities),
+		}
+		for _, item := range items {
+			if item.Name == nil || !strings.EqualFold(pointer.From(item.ResourceType), "virtualMachines") {
+				continue
+			}
+
+			sku := SkuCapabilities{
+				Name:         *item.Name,
+				Location:     skuLocation,
+				Zones:        make([]string, 0),
+				Capabilities: make(map[string]string),
+			}
+			if item.Capabilities != nil {
+				for _, capability := range *item.Capabilities {
+					if capability.Name != nil {
+						sku.Capabilities[*capability.Name] = pointer.From(capability.Value)
+					}
+				}
+			}
+			if item.LocationInfo != nil {
+				for _, info := range *item.LocationInfo {
+					if !strings.EqualFold(location.Normalize(pointer.From(info.Location)), skuLocation) || info.Zones == nil {
+						continue
+					}
+					sku.Zones = append(sku.Zones, *info.Zones...)
+				}
+			}
+
+			entry.skus[strings.ToLower(sku.Name)] = sku
+		}
+		c.entries[skuLocation] = entry
+	}
+
+	return &entry, nil
+}
+
+// GetSkuCapabilities returns the capabilities of the Virtual Machine SKU within the specified Location, or nil if the SKU
+// isn't available in that Location. The SKUs for each Location are cached for the lifetime of this provider instance (subject
+// to a TTL) since this is called frequently and the API is prone to throttling.
+func (c *Client) GetSkuCapabilities(ctx context.Context, location string, skuName string) (*SkuCapabilities, error) {
+	return c.skuCapabilities.get(ctx, location, skuName)
+}
+
+// ListSkuCapabilities returns the capabilities of each of the Virtual Machine SKUs available within the specified Location,
+// which are cached in the same way as GetSkuCapabilities
+func (c *Client) ListSkuCapabilities(ctx context.Context, location string) ([]SkuCapabilities, error) {
+	return c.skuCapabilities.list(ctx, location)
+}
+
+func listVirtualMachineSkusForLocation(client *skus.SkusClient, subscriptionId string) func(ctx context.Context, location string) ([]skus.ResourceSku, error) {
+	return func(ctx context.Context, location string) ([]skus.ResourceSku, error) {
+		opts := skus.DefaultResourceSkusListOperationOptions()
+		// by default this API returns every SKU in every Location, so we filter to the current Location only
+		opts.Filter = pointer.To(fmt.Sprintf("location eq '%s'", location))
+		resp, err := client.ResourceSkusListComplete(ctx, commonids.NewSubscriptionID(subscriptionId), opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing Resource SKUs in %q: %+v", location, err)
+		}
+		return resp.Items, nil
+	}
+}
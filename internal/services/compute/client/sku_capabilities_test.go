@@ -0,0 +1,90 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/zones"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2021-07-01/skus"
+)
+
+func TestSkuCapabilitiesCache(t *testing.T) {
+	calls := 0
+	cache := newSkuCapabilitiesCache(func(ctx context.Context, location string) ([]skus.ResourceSku, error) {
+		calls++
+		return []skus.ResourceSku{
+			{
+				Name:         pointer.To("Standard_F2"),
+				ResourceType: pointer.To("virtualMachines"),
+				Capabilities: &[]skus.ResourceSkuCapabilities{
+					{Name: pointer.To("EncryptionAtHostSupported"), Value: pointer.To("True")},
+					{Name: pointer.To("MaxDataDiskCount"), Value: pointer.To("4")},
+				},
+				LocationInfo: &[]skus.ResourceSkuLocationInfo{
+					{Location: pointer.To("westeurope"), Zones: &zones.Schema{"1", "2", "3"}},
+				},
+			},
+			{
+				Name:         pointer.To("Standard_F2"),
+				ResourceType: pointer.To("disks"),
+			},
+		}, nil
+	})
+	now := time.Now()
+	cache.now = func() time.Time {
+		return now
+	}
+
+	sku, err := cache.get(context.TODO(), "West Europe", "standard_f2")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if sku == nil {
+		t.Fatalf("expected the SKU to be found")
+	}
+	if !sku.HasCapability("encryptionAtHostSupported") {
+		t.Fatalf("expected the SKU to support Encryption at Host")
+	}
+	if v, ok := sku.CapabilityInt("MaxDataDiskCount"); !ok || v != 4 {
+		t.Fatalf("expected `MaxDataDiskCount` to be 4 but got %d", v)
+	}
+	if len(sku.Zones) != 3 {
+		t.Fatalf("expected 3 zones but got %d", len(sku.Zones))
+	}
+
+	// a second lookup, including for a SKU which doesn't exist, should be served from the cache
+	if _, err := cache.get(context.TODO(), "westeurope", "Standard_F2"); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	missing, err := cache.get(context.TODO(), "westeurope", "Standard_Missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if missing != nil {
+		t.Fatalf("expected no SKU to be returned for `Standard_Missing`")
+	}
+	all, err := cache.list(context.TODO(), "westeurope")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(all) != 1 || all[0].Name != "Standard_F2" {
+		t.Fatalf("expected only the `virtualMachines` SKU `Standard_F2` to be listed but got %+v", all)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the SKUs to be listed once but got %d", calls)
+	}
+
+	// once the TTL has elapsed the SKUs should be retrieved again
+	now = now.Add(skuCapabilitiesCacheTTL + time.Second)
+	if _, err := cache.get(context.TODO(), "westeurope", "Standard_F2"); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the SKUs to be listed twice but got %d", calls)
+	}
+}
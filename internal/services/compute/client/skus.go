@@ -0,0 +1,33 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2021-07-01/skus"
+)
+
+// ListSkusMatching retrieves all the Resource SKUs available within the specified Location and then applies the predicate,
+// for example to find the SKUs which support a given capability within a Zone
+func (c *Client) ListSkusMatching(ctx context.Context, location string, predicate func(skus.ResourceSku) bool) ([]skus.ResourceSku, error) {
+	return listSkusMatching(ctx, c.listSkus, location, predicate)
+}
+
+func listSkusMatching(ctx context.Context, listSkus func(ctx context.Context, location string) ([]skus.ResourceSku, error), location string, predicate func(skus.ResourceSku) bool) ([]skus.ResourceSku, error) {
+	items := make([]skus.ResourceSku, 0)
+
+	results, err := listSkus(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results {
+		if predicate == nil || predicate(item) {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
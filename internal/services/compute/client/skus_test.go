@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2021-07-01/skus"
+)
+
+func TestListSkusMatching(t *testing.T) {
+	listSkus := func(ctx context.Context, location string) ([]skus.ResourceSku, error) {
+		if location != "westeurope" {
+			return nil, fmt.Errorf("unexpected location %q", location)
+		}
+		return []skus.ResourceSku{
+			{
+				Name: pointer.To("Standard_D2s_v3"),
+				Capabilities: &[]skus.ResourceSkuCapabilities{
+					{Name: pointer.To("UltraSSDAvailable"), Value: pointer.To("True")},
+				},
+			},
+			{
+				Name: pointer.To("Standard_F2"),
+			},
+		}, nil
+	}
+
+	ultraSSDAvailable := func(input skus.ResourceSku) bool {
+		if input.Capabilities == nil {
+			return false
+		}
+		for _, capability := range *input.Capabilities {
+			if pointer.From(capability.Name) == "UltraSSDAvailable" && pointer.From(capability.Value) == "True" {
+				return true
+			}
+		}
+		return false
+	}
+
+	items, err := listSkusMatching(context.TODO(), listSkus, "westeurope", ultraSSDAvailable)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(items) != 1 || pointer.From(items[0].Name) != "Standard_D2s_v3" {
+		t.Fatalf("expected only `Standard_D2s_v3` to match but got %+v", items)
+	}
+
+	items, err = listSkusMatching(context.TODO(), listSkus, "westeurope", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected every SKU to be returned without a predicate but got %d", len(items))
+	}
+
+	if _, err := listSkusMatching(context.TODO(), listSkus, "eastus", ultraSSDAvailable); err == nil {
+		t.Fatalf("expected an error when listing the SKUs fails but didn't get one")
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/location"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-02/diskencryptionsets"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2023-04-02/disks"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/features"
 )
 
 // retrieveDiskEncryptionSetEncryptionType returns encryption type of the disk encryption set
@@ -39,3 +41,63 @@ func retrieveDiskEncryptionSetEncryptionType(ctx context.Context, client *disken
 
 	return encryptionType, nil
 }
+
+// validateDiskEncryptionSetLocation ensures the disk encryption set exists within the same location as the resource using it,
+// when Enhanced Validation is disabled only the ID is validated since retrieving the disk encryption set requires additional permissions
+func validateDiskEncryptionSetLocation(ctx context.Context, client *diskencryptionsets.DiskEncryptionSetsClient, diskEncryptionSetId string, expectedLocation string) error {
+	id, err := commonids.ParseDiskEncryptionSetID(diskEncryptionSetId)
+	if err != nil {
+		return err
+	}
+
+	if !features.EnhancedValidationEnabled() {
+		return nil
+	}
+
+	resp, err := client.Get(ctx, *id)
+	if err != nil {
+		return fmt.Errorf("retrieving %s: %+v", *id, err)
+	}
+
+	if model := resp.Model; model != nil {
+		if actual := location.Normalize(model.Location); actual != location.Normalize(expectedLocation) {
+			return fmt.Errorf("%s must be in the same location as the resource using it (%q) but was in %q", *id, location.Normalize(expectedLocation), actual)
+		}
+	}
+
+	return nil
+}
+
+// validateDiskEncryptionSetEncryptionType ensures the disk encryption set is of the encryption type expected by the field
+// referencing it, since a Confidential VM disk encryption set can't be used for Customer Managed Key encryption (and vice versa)
+func validateDiskEncryptionSetEncryptionType(ctx context.Context, client *diskencryptionsets.DiskEncryptionSetsClient, diskEncryptionSetId string, confidential bool) error {
+	id, err := commonids.ParseDiskEncryptionSetID(diskEncryptionSetId)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Get(ctx, *id)
+	if err != nil {
+		return fmt.Errorf("retrieving %s: %+v", *id, err)
+	}
+
+	if model := resp.Model; model != nil {
+		if props := model.Properties; props != nil && props.EncryptionType != nil {
+			return checkDiskEncryptionSetEncryptionType(*id, *props.EncryptionType, confidential)
+		}
+	}
+
+	return nil
+}
+
+func checkDiskEncryptionSetEncryptionType(id commonids.DiskEncryptionSetId, encryptionType diskencryptionsets.DiskEncryptionSetType, confidential bool) error {
+	isConfidential := encryptionType == diskencryptionsets.DiskEncryptionSetTypeConfidentialVMEncryptedWithCustomerKey
+	if confidential && !isConfidential {
+		return fmt.Errorf("%s has the encryption type %q but must be %q to be used for Confidential VM encryption", id, string(encryptionType), string(diskencryptionsets.DiskEncryptionSetTypeConfidentialVMEncryptedWithCustomerKey))
+	}
+	if !confidential && isConfidential {
+		return fmt.Errorf("%s has the encryption type %q which can only be used for Confidential VM encryption, use `secure_vm_disk_encryption_set_id` instead", id, string(encryptionType))
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package compute
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-02/diskencryptionsets"
+)
+
+func TestCheckDiskEncryptionSetEncryptionType(t *testing.T) {
+	id := commonids.NewDiskEncryptionSetID("12345678-1234-9876-4563-123456789012", "group1", "des1")
+
+	cases := []struct {
+		name           string
+		encryptionType diskencryptionsets.DiskEncryptionSetType
+		confidential   bool
+		shouldError    bool
+	}{
+		{
+			name:           "customer managed key used for customer managed key encryption",
+			encryptionType: diskencryptionsets.DiskEncryptionSetTypeEncryptionAtRestWithCustomerKey,
+			confidential:   false,
+			shouldError:    false,
+		},
+		{
+			name:           "platform and customer managed keys used for customer managed key encryption",
+			encryptionType: diskencryptionsets.DiskEncryptionSetTypeEncryptionAtRestWithPlatformAndCustomerKeys,
+			confidential:   false,
+			shouldError:    false,
+		},
+		{
+			name:           "confidential used for confidential encryption",
+			encryptionType: diskencryptionsets.DiskEncryptionSetTypeConfidentialVMEncryptedWithCustomerKey,
+			confidential:   true,
+			shouldError:    false,
+		},
+		{
+			name:           "confidential used for customer managed key encryption",
+			encryptionType: diskencryptionsets.DiskEncryptionSetTypeConfidentialVMEncryptedWithCustomerKey,
+			confidential:   false,
+			shouldError:    true,
+		},
+		{
+			name:           "customer managed key used for confidential encryption",
+			encryptionType: diskencryptionsets.DiskEncryptionSetTypeEncryptionAtRestWithCustomerKey,
+			confidential:   true,
+			shouldError:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := checkDiskEncryptionSetEncryptionType(id, tc.encryptionType, tc.confidential)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
@@ -5,10 +5,12 @@ package compute_test
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-provider-azurerm/internal/acceptance"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/acceptance/check"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/features"
 )
 
 func TestAccLinuxVirtualMachineScaleSet_authPassword(t *testing.T) {
@@ -26,6 +28,22 @@ func TestAccLinuxVirtualMachineScaleSet_authPassword(t *testing.T) {
 	})
 }
 
+func TestAccLinuxVirtualMachineScaleSet_authPasswordInvalid(t *testing.T) {
+	if !features.FourPointOhBeta() {
+		t.Skip("Skipping since `admin_password` is only validated in 4.0")
+	}
+
+	data := acceptance.BuildTestData(t, "azurerm_linux_virtual_machine_scale_set", "test")
+	r := LinuxVirtualMachineScaleSetResource{}
+
+	data.ResourceTest(t, r, []acceptance.TestStep{
+		{
+			Config:      r.authPasswordValue(data, "Password1"),
+			ExpectError: regexp.MustCompile("specified is not allowed"),
+		},
+	})
+}
+
 func TestAccLinuxVirtualMachineScaleSet_authSSHKey(t *testing.T) {
 	data := acceptance.BuildTestData(t, "azurerm_linux_virtual_machine_scale_set", "test")
 	r := LinuxVirtualMachineScaleSetResource{}
@@ -126,6 +144,10 @@ func TestAccLinuxVirtualMachineScaleSet_authDisablePasswordAuthUpdate(t *testing
 }
 
 func (r LinuxVirtualMachineScaleSetResource) authPassword(data acceptance.TestData) string {
+	return r.authPasswordValue(data, "P@ssword1234!")
+}
+
+func (r LinuxVirtualMachineScaleSetResource) authPasswordValue(data acceptance.TestData, password string) string {
 	return fmt.Sprintf(`
 %s
 
@@ -136,7 +158,7 @@ resource "azurerm_linux_virtual_machine_scale_set" "test" {
   sku                 = "Standard_F2"
   instances           = 1
   admin_username      = "adminuser"
-  admin_password      = "P@ssword1234!"
+  admin_password      = "%s"
 
   disable_password_authentication = false
 
@@ -163,7 +185,7 @@ resource "azurerm_linux_virtual_machine_scale_set" "test" {
     }
   }
 }
-`, r.template(data), data.RandomInteger)
+`, r.template(data), data.RandomInteger, password)
 }
 
 func (r LinuxVirtualMachineScaleSetResource) authSSHKey(data acceptance.TestData) string {
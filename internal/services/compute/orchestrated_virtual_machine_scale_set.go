@@ -260,7 +260,7 @@ func OrchestratedVirtualMachineScaleSetExtensionsSchema() *pluginsdk.Schema {
 				"settings": {
 					Type:             pluginsdk.TypeString,
 					Optional:         true,
-					ValidateFunc:     validation.StringIsJSON,
+					ValidateFunc:     validate.ExtensionSettings,
 					DiffSuppressFunc: pluginsdk.SuppressJsonDiff,
 				},
 			},
@@ -590,7 +590,7 @@ func OrchestratedVirtualMachineScaleSetAdditionalCapabilitiesSchema() *pluginsdk
 					Type:     pluginsdk.TypeBool,
 					Optional: true,
 					Default:  false,
-					ForceNew: true,
+					// this can be toggled in-place when the Scale Set has no instances, see virtualMachineScaleSetUltraSSDCustomizeDiff
 				},
 			},
 		},
@@ -699,7 +699,7 @@ func OrchestratedVirtualMachineScaleSetTerminationNotificationSchema() *pluginsd
 				"timeout": {
 					Type:         pluginsdk.TypeString,
 					Optional:     true,
-					ValidateFunc: azValidate.ISO8601DurationBetween("PT5M", "PT15M"),
+					ValidateFunc: azValidate.ISO8601DurationBetweenWithMessage("PT5M", "PT15M"),
 					Default:      "PT5M",
 				},
 			},
@@ -1104,15 +1104,41 @@ func expandOrchestratedVirtualMachineScaleSetIPConfiguration(raw map[string]inte
 	publicIPConfigsRaw := raw["public_ip_address"].([]interface{})
 	if len(publicIPConfigsRaw) > 0 {
 		publicIPConfigRaw := publicIPConfigsRaw[0].(map[string]interface{})
-		publicIPAddressConfig := expandOrchestratedVirtualMachineScaleSetPublicIPAddress(publicIPConfigRaw)
+		publicIPAddressConfig, err := expandOrchestratedVirtualMachineScaleSetPublicIPAddress(publicIPConfigRaw)
+		if err != nil {
+			return nil, err
+		}
 		ipConfiguration.Properties.PublicIPAddressConfiguration = publicIPAddressConfig
 	}
 
 	return &ipConfiguration, nil
 }
 
-func expandOrchestratedVirtualMachineScaleSetPublicIPAddress(raw map[string]interface{}) *virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration {
+// validateOrchestratedVirtualMachineScaleSetPublicIPAddressIPTags ensures that IP Tags are only used with a Standard SKU
+// Public IP Address, since these aren't supported for Basic SKU Public IP Addresses
+func validateOrchestratedVirtualMachineScaleSetPublicIPAddressIPTags(ipTagsRaw []interface{}, sku *virtualmachinescalesets.PublicIPAddressSku) error {
+	if len(ipTagsRaw) == 0 || sku == nil {
+		return nil
+	}
+
+	if pointer.From(sku.Name) == virtualmachinescalesets.PublicIPAddressSkuNameBasic {
+		return fmt.Errorf("`ip_tag` can only be specified when the `sku_name` of the `public_ip_address` is a `Standard` SKU, got %q", flattenOrchestratedVirtualMachineScaleSetPublicIPSku(sku))
+	}
+
+	return nil
+}
+
+func expandOrchestratedVirtualMachineScaleSetPublicIPAddress(raw map[string]interface{}) (*virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration, error) {
+	var sku *virtualmachinescalesets.PublicIPAddressSku
+	if skuName := raw["sku_name"].(string); skuName != "" {
+		sku = expandOrchestratedVirtualMachineScaleSetPublicIPSku(skuName)
+	}
+
 	ipTagsRaw := raw["ip_tag"].([]interface{})
+	if err := validateOrchestratedVirtualMachineScaleSetPublicIPAddressIPTags(ipTagsRaw, sku); err != nil {
+		return nil, err
+	}
+
 	ipTags := make([]virtualmachinescalesets.VirtualMachineScaleSetIPTag, 0)
 	for _, ipTagV := range ipTagsRaw {
 		ipTagRaw := ipTagV.(map[string]interface{})
@@ -1146,16 +1172,15 @@ func expandOrchestratedVirtualMachineScaleSetPublicIPAddress(raw map[string]inte
 		}
 	}
 
-	if sku := raw["sku_name"].(string); sku != "" {
-		v := expandOrchestratedVirtualMachineScaleSetPublicIPSku(sku)
-		publicIPAddressConfig.Sku = v
+	if sku != nil {
+		publicIPAddressConfig.Sku = sku
 	}
 
 	if version := raw["version"].(string); version != "" {
 		publicIPAddressConfig.Properties.PublicIPAddressVersion = pointer.To(virtualmachinescalesets.IPVersion(version))
 	}
 
-	return &publicIPAddressConfig
+	return &publicIPAddressConfig, nil
 }
 
 func ExpandOrchestratedVirtualMachineScaleSetNetworkInterfaceUpdate(input []interface{}) (*[]virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration, error) {
@@ -1241,14 +1266,26 @@ func expandOrchestratedVirtualMachineScaleSetIPConfigurationUpdate(raw map[strin
 	publicIPConfigsRaw := raw["public_ip_address"].([]interface{})
 	if len(publicIPConfigsRaw) > 0 {
 		publicIPConfigRaw := publicIPConfigsRaw[0].(map[string]interface{})
-		publicIPAddressConfig := expandOrchestratedVirtualMachineScaleSetPublicIPAddressUpdate(publicIPConfigRaw)
+		publicIPAddressConfig, err := expandOrchestratedVirtualMachineScaleSetPublicIPAddressUpdate(publicIPConfigRaw)
+		if err != nil {
+			return nil, err
+		}
 		ipConfiguration.Properties.PublicIPAddressConfiguration = publicIPAddressConfig
 	}
 
 	return &ipConfiguration, nil
 }
 
-func expandOrchestratedVirtualMachineScaleSetPublicIPAddressUpdate(raw map[string]interface{}) *virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration {
+func expandOrchestratedVirtualMachineScaleSetPublicIPAddressUpdate(raw map[string]interface{}) (*virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration, error) {
+	// whilst the IP Tags can't be updated, we validate them against the SKU for consistency with the create
+	var sku *virtualmachinescalesets.PublicIPAddressSku
+	if skuName := raw["sku_name"].(string); skuName != "" {
+		sku = expandOrchestratedVirtualMachineScaleSetPublicIPSku(skuName)
+	}
+	if err := validateOrchestratedVirtualMachineScaleSetPublicIPAddressIPTags(raw["ip_tag"].([]interface{}), sku); err != nil {
+		return nil, err
+	}
+
 	publicIPAddressConfig := virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration{
 		Name:       pointer.To(raw["name"].(string)),
 		Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfigurationProperties{},
@@ -1265,7 +1302,7 @@ func expandOrchestratedVirtualMachineScaleSetPublicIPAddressUpdate(raw map[strin
 		publicIPAddressConfig.Properties.IdleTimeoutInMinutes = pointer.To(int64(raw["idle_timeout_in_minutes"].(int)))
 	}
 
-	return &publicIPAddressConfig
+	return &publicIPAddressConfig, nil
 }
 
 func ExpandOrchestratedVirtualMachineScaleSetDataDisk(input []interface{}, ultraSSDEnabled bool) (*[]virtualmachinescalesets.VirtualMachineScaleSetDataDisk, error) {
@@ -1429,7 +1466,7 @@ func expandOrchestratedVirtualMachineScaleSetExtensions(input []interface{}) (ex
 			ProvisionAfterExtensions: utils.ExpandStringSlice(extensionRaw["extensions_to_provision_after_vm_creation"].([]interface{})),
 		}
 
-		if extensionType == "ApplicationHealthLinux" || extensionType == "ApplicationHealthWindows" {
+		if isVirtualMachineScaleSetHealthExtension(extensionType) {
 			hasHealthExtension = true
 		}
 
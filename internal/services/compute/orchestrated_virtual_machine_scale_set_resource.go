@@ -4,6 +4,7 @@
 package compute
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
@@ -99,7 +100,7 @@ func resourceOrchestratedVirtualMachineScaleSet() *pluginsdk.Resource {
 			// identical for both uniform and flex mode VMSS's
 			"automatic_instance_repair": VirtualMachineScaleSetAutomaticRepairsPolicySchema(),
 
-			"boot_diagnostics": bootDiagnosticsSchema(),
+			"boot_diagnostics": VirtualMachineScaleSetBootDiagnosticsSchema(),
 
 			"capacity_reservation_group_id": {
 				Type:         pluginsdk.TypeString,
@@ -116,6 +117,8 @@ func resourceOrchestratedVirtualMachineScaleSet() *pluginsdk.Resource {
 			// Optional
 			"additional_capabilities": OrchestratedVirtualMachineScaleSetAdditionalCapabilitiesSchema(),
 
+			"edge_zone": commonschema.EdgeZoneOptionalForceNew(),
+
 			"encryption_at_host_enabled": {
 				Type:     pluginsdk.TypeBool,
 				Optional: true,
@@ -152,7 +155,7 @@ func resourceOrchestratedVirtualMachineScaleSet() *pluginsdk.Resource {
 				Type:         pluginsdk.TypeString,
 				Optional:     true,
 				Default:      "PT1H30M",
-				ValidateFunc: validate.ISO8601DurationBetween("PT15M", "PT2H"),
+				ValidateFunc: validate.ISO8601DurationBetweenWithMessage("PT15M", "PT2H"),
 			},
 
 			// whilst the Swagger defines multiple at this time only UAI is supported
@@ -269,6 +272,11 @@ func resourceOrchestratedVirtualMachineScaleSet() *pluginsdk.Resource {
 
 			"priority_mix": OrchestratedVirtualMachineScaleSetPriorityMixPolicySchema(),
 		},
+
+		// `ultra_ssd_enabled` can only be changed in-place when there are no instances
+		CustomizeDiff: pluginsdk.CustomDiffWithAll(
+			virtualMachineScaleSetUltraSSDCustomizeDiff,
+		),
 	}
 }
 
@@ -298,8 +306,9 @@ func resourceOrchestratedVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData,
 	t := d.Get("tags").(map[string]interface{})
 
 	props := virtualmachinescalesets.VirtualMachineScaleSet{
-		Location: location.Normalize(d.Get("location").(string)),
-		Tags:     tags.Expand(t),
+		Location:         location.Normalize(d.Get("location").(string)),
+		ExtendedLocation: expandEdgeZone(d.Get("edge_zone").(string)),
+		Tags:             tags.Expand(t),
 		Properties: &virtualmachinescalesets.VirtualMachineScaleSetProperties{
 			PlatformFaultDomainCount: pointer.To(int64(d.Get("platform_fault_domain_count").(int))),
 			// OrchestrationMode needs to be hardcoded to Uniform, for the
@@ -537,7 +546,7 @@ func resourceOrchestratedVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData,
 	}
 
 	if v, ok := d.GetOk("boot_diagnostics"); ok {
-		virtualMachineProfile.DiagnosticsProfile = expandBootDiagnosticsVMSS(v.([]interface{}))
+		virtualMachineProfile.DiagnosticsProfile = ExpandVirtualMachineScaleSetBootDiagnostics(v.([]interface{}))
 	}
 
 	if v, ok := d.GetOk("priority"); ok {
@@ -641,7 +650,10 @@ func resourceOrchestratedVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData,
 	}
 
 	log.Printf("[DEBUG] Creating Orchestrated %s.", id)
-	if err := client.CreateOrUpdateThenPoll(ctx, id, props, virtualmachinescalesets.DefaultCreateOrUpdateOperationOptions()); err != nil {
+	err := createVirtualMachineScaleSetRetryingOnProximityPlacementGroupNotFound(ctx, d.Get("proximity_placement_group_id").(string), virtualMachineScaleSetProximityPlacementGroupNotFoundRetries, virtualMachineScaleSetProximityPlacementGroupNotFoundRetryInterval, func(ctx context.Context) error {
+		return client.CreateOrUpdateThenPoll(ctx, id, props, virtualmachinescalesets.DefaultCreateOrUpdateOperationOptions())
+	})
+	if err != nil {
 		return fmt.Errorf("creating Orchestrated %s: %+v", id, err)
 	}
 
@@ -957,7 +969,7 @@ func resourceOrchestratedVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData,
 			updateInstances = true
 
 			bootDiagnosticsRaw := d.Get("boot_diagnostics").([]interface{})
-			updateProps.VirtualMachineProfile.DiagnosticsProfile = expandBootDiagnosticsVMSS(bootDiagnosticsRaw)
+			updateProps.VirtualMachineProfile.DiagnosticsProfile = ExpandVirtualMachineScaleSetBootDiagnostics(bootDiagnosticsRaw)
 		}
 
 		if d.HasChange("termination_notification") {
@@ -1002,6 +1014,14 @@ func resourceOrchestratedVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData,
 			update.Plan = expandPlanVMSS(planRaw)
 		}
 
+		if d.HasChange("additional_capabilities.0.ultra_ssd_enabled") {
+			additionalCapabilities, err := ExpandVirtualMachineScaleSetAdditionalCapabilitiesUpdate(d.Get("additional_capabilities").([]interface{}), existing.Model.Sku, virtualmachinescalesets.OrchestrationModeFlexible)
+			if err != nil {
+				return err
+			}
+			updateProps.AdditionalCapabilities = additionalCapabilities
+		}
+
 		if d.HasChange("sku_name") || d.HasChange("instances") {
 			// in-case ignore_changes is being used, since both fields are required
 			// look up the current values and override them as needed
@@ -1123,6 +1143,7 @@ func resourceOrchestratedVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, m
 
 	if model := resp.Model; model != nil {
 		d.Set("location", location.Normalize(model.Location))
+		d.Set("edge_zone", flattenEdgeZone(model.ExtendedLocation))
 		d.Set("zones", zones.FlattenUntyped(model.Zones))
 
 		var skuName *string
@@ -1178,7 +1199,7 @@ func resourceOrchestratedVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, m
 
 			extensionOperationsEnabled := true
 			if profile := props.VirtualMachineProfile; profile != nil {
-				if err := d.Set("boot_diagnostics", flattenBootDiagnosticsVMSS(profile.DiagnosticsProfile)); err != nil {
+				if err := d.Set("boot_diagnostics", FlattenVirtualMachineScaleSetBootDiagnostics(profile.DiagnosticsProfile, d.Get("boot_diagnostics").([]interface{}))); err != nil {
 					return fmt.Errorf("setting `boot_diagnostics`: %+v", err)
 				}
 
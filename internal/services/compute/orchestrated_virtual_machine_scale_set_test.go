@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package compute
+
+import "testing"
+
+func TestExpandOrchestratedVirtualMachineScaleSetPublicIPAddress_ipTagSku(t *testing.T) {
+	publicIPAddress := func(skuName string, ipTags ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                    "public",
+			"domain_name_label":       "",
+			"idle_timeout_in_minutes": 4,
+			"ip_tag":                  ipTags,
+			"public_ip_prefix_id":     "",
+			"sku_name":                skuName,
+			"version":                 "IPv4",
+		}
+	}
+	ipTag := map[string]interface{}{
+		"tag":  "/Sql",
+		"type": "FirstPartyUsage",
+	}
+
+	cases := []struct {
+		name        string
+		input       map[string]interface{}
+		shouldError bool
+	}{
+		{
+			name:        "ip tag with the default sku",
+			input:       publicIPAddress("", ipTag),
+			shouldError: false,
+		},
+		{
+			name:        "ip tag with a standard sku",
+			input:       publicIPAddress("Standard_Regional", ipTag),
+			shouldError: false,
+		},
+		{
+			name:        "ip tag with a basic sku",
+			input:       publicIPAddress("Basic_Regional", ipTag),
+			shouldError: true,
+		},
+		{
+			name:        "no ip tag with a basic sku",
+			input:       publicIPAddress("Basic_Regional"),
+			shouldError: false,
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := expandOrchestratedVirtualMachineScaleSetPublicIPAddress(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+
+		_, err = expandOrchestratedVirtualMachineScaleSetPublicIPAddressUpdate(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error when updating for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
@@ -4,6 +4,9 @@
 package compute
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonschema"
@@ -203,38 +206,6 @@ func expandBootDiagnostics(input []interface{}) *virtualmachines.DiagnosticsProf
 	}
 }
 
-func expandBootDiagnosticsVMSS(input []interface{}) *virtualmachinescalesets.DiagnosticsProfile {
-	if len(input) == 0 {
-		return &virtualmachinescalesets.DiagnosticsProfile{
-			BootDiagnostics: &virtualmachinescalesets.BootDiagnostics{
-				Enabled:    pointer.To(false),
-				StorageUri: pointer.To(""),
-			},
-		}
-	}
-
-	// this serves the managed boot diagnostics, in this case we only have this empty block without `storage_account_uri` set
-	if input[0] == nil {
-		return &virtualmachinescalesets.DiagnosticsProfile{
-			BootDiagnostics: &virtualmachinescalesets.BootDiagnostics{
-				Enabled:    pointer.To(true),
-				StorageUri: pointer.To(""),
-			},
-		}
-	}
-
-	raw := input[0].(map[string]interface{})
-
-	storageAccountUri := raw["storage_account_uri"].(string)
-
-	return &virtualmachinescalesets.DiagnosticsProfile{
-		BootDiagnostics: &virtualmachinescalesets.BootDiagnostics{
-			Enabled:    pointer.To(true),
-			StorageUri: pointer.To(storageAccountUri),
-		},
-	}
-}
-
 func flattenBootDiagnostics(input *virtualmachines.DiagnosticsProfile) []interface{} {
 	if input == nil || input.BootDiagnostics == nil || input.BootDiagnostics.Enabled == nil || !*input.BootDiagnostics.Enabled {
 		return []interface{}{}
@@ -252,23 +223,6 @@ func flattenBootDiagnostics(input *virtualmachines.DiagnosticsProfile) []interfa
 	}
 }
 
-func flattenBootDiagnosticsVMSS(input *virtualmachinescalesets.DiagnosticsProfile) []interface{} {
-	if input == nil || input.BootDiagnostics == nil || input.BootDiagnostics.Enabled == nil || !*input.BootDiagnostics.Enabled {
-		return []interface{}{}
-	}
-
-	storageAccountUri := ""
-	if input.BootDiagnostics.StorageUri != nil {
-		storageAccountUri = *input.BootDiagnostics.StorageUri
-	}
-
-	return []interface{}{
-		map[string]interface{}{
-			"storage_account_uri": storageAccountUri,
-		},
-	}
-}
-
 func linuxSecretSchema() *pluginsdk.Schema {
 	return &pluginsdk.Schema{
 		Type:     pluginsdk.TypeList,
@@ -487,6 +441,35 @@ func expandPlanVMSS(input []interface{}) *virtualmachinescalesets.Plan {
 	}
 }
 
+// validatePlanMatchesSourceImageReference ensures that when both a `plan` and a `source_image_reference` are specified
+// the plan refers to the same Marketplace Image, since Azure otherwise rejects the request with a less obvious error
+func validatePlanMatchesSourceImageReference(planInput []interface{}, referenceInput []interface{}) error {
+	if len(planInput) == 0 || planInput[0] == nil || len(referenceInput) == 0 || referenceInput[0] == nil {
+		return nil
+	}
+
+	plan := planInput[0].(map[string]interface{})
+	reference := referenceInput[0].(map[string]interface{})
+
+	fields := []struct {
+		planField      string
+		referenceField string
+	}{
+		{planField: "publisher", referenceField: "publisher"},
+		{planField: "product", referenceField: "offer"},
+		{planField: "name", referenceField: "sku"},
+	}
+	for _, field := range fields {
+		planValue := plan[field.planField].(string)
+		referenceValue := reference[field.referenceField].(string)
+		if !strings.EqualFold(planValue, referenceValue) {
+			return fmt.Errorf("the `plan` block's `%s` (%q) must match the `source_image_reference` block's `%s` (%q)", field.planField, planValue, field.referenceField, referenceValue)
+		}
+	}
+
+	return nil
+}
+
 func flattenPlan(input *virtualmachines.Plan) []interface{} {
 	if input == nil {
 		return []interface{}{}
@@ -911,9 +894,12 @@ func windowsSecretSchema() *pluginsdk.Schema {
 					MinItems: 1,
 					Elem: &pluginsdk.Resource{
 						Schema: map[string]*pluginsdk.Schema{
+							// the Certificate Store is required on Windows, whereas on Linux certificates are placed in `/var/lib/waagent`
+							// and as such the Linux schema doesn't expose this field
 							"store": {
-								Type:     pluginsdk.TypeString,
-								Required: true,
+								Type:         pluginsdk.TypeString,
+								Required:     true,
+								ValidateFunc: validation.StringIsNotEmpty,
 							},
 							"url": {
 								Type:         pluginsdk.TypeString,
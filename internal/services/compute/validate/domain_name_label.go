@@ -23,3 +23,18 @@ func OrchestratedDomainNameLabel(i interface{}, k string) (warnings []string, er
 	}
 	return
 }
+
+// VirtualMachineScaleSetDomainNameLabel validates the `domain_name_label` of a Public IP Address within a Virtual Machine
+// Scale Set, which must be a valid DNS label
+func VirtualMachineScaleSetDomainNameLabel(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	if matched := regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`).Match([]byte(v)); !matched {
+		errors = append(errors, fmt.Errorf("%s must be between 1 - 63 characters long, start and end with a lower case letter or number and contain only a-z, 0-9 and hyphens, got %q", k, v))
+	}
+	return
+}
@@ -3,7 +3,10 @@
 
 package validate
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestOrchestratedDomainNameLabel(t *testing.T) {
 	testData := []struct {
@@ -92,3 +95,76 @@ func TestOrchestratedDomainNameLabel(t *testing.T) {
 		}
 	}
 }
+
+func TestVirtualMachineScaleSetDomainNameLabel(t *testing.T) {
+	testData := []struct {
+		input    string
+		expected bool
+	}{
+		{
+			// empty
+			input:    "",
+			expected: false,
+		},
+		{
+			// single character
+			input:    "a",
+			expected: true,
+		},
+		{
+			// basic example
+			input:    "acctest1-2",
+			expected: true,
+		},
+		{
+			// can start with a number
+			input:    "1acctest",
+			expected: true,
+		},
+		{
+			// can't contain upper case
+			input:    "AccTest",
+			expected: false,
+		},
+		{
+			// can't contain underscores
+			input:    "acc_test",
+			expected: false,
+		},
+		{
+			// can't contain dots
+			input:    "acc.test",
+			expected: false,
+		},
+		{
+			// can't start with hyphen
+			input:    "-acctest",
+			expected: false,
+		},
+		{
+			// can't end with hyphen
+			input:    "acctest-",
+			expected: false,
+		},
+		{
+			// 63 characters
+			input:    strings.Repeat("a", 63),
+			expected: true,
+		},
+		{
+			// 64 characters
+			input:    strings.Repeat("a", 64),
+			expected: false,
+		},
+	}
+
+	for _, v := range testData {
+		t.Logf("[DEBUG] Testing %q...", v.input)
+
+		_, errors := VirtualMachineScaleSetDomainNameLabel(v.input, "domain_name_label")
+		actual := len(errors) == 0
+		if v.expected != actual {
+			t.Fatalf("Expected %t but got %t", v.expected, actual)
+		}
+	}
+}
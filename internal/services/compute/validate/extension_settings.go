@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/validation"
+)
+
+// ExtensionSettings validates that the `settings` of an Extension are valid JSON, and warns when they appear to
+// contain sensitive values - since `settings` are stored in the state in plain text whereas `protected_settings` are not
+func ExtensionSettings(i interface{}, k string) (warnings []string, errors []error) {
+	warnings, errors = validation.StringIsJSON(i, k)
+	if len(errors) > 0 {
+		return
+	}
+
+	v := i.(string)
+	if v == "" {
+		return
+	}
+
+	var settings interface{}
+	if err := json.Unmarshal([]byte(v), &settings); err != nil {
+		return
+	}
+
+	sensitiveKeys := findSensitiveExtensionSettingsKeys(settings)
+	if len(sensitiveKeys) > 0 {
+		sort.Strings(sensitiveKeys)
+		warnings = append(warnings, fmt.Sprintf("%s contains keys which look like they may contain sensitive values (%s) - `settings` are stored in plain text, consider moving these into `protected_settings` instead", k, strings.Join(sensitiveKeys, ", ")))
+	}
+
+	return
+}
+
+func findSensitiveExtensionSettingsKeys(input interface{}) []string {
+	keys := make([]string, 0)
+
+	switch v := input.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSensitiveExtensionSettingsKey(key) {
+				keys = append(keys, key)
+				continue
+			}
+			keys = append(keys, findSensitiveExtensionSettingsKeys(value)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			keys = append(keys, findSensitiveExtensionSettingsKeys(value)...)
+		}
+	}
+
+	return keys
+}
+
+// sensitiveExtensionSettingsKeyNames are the (lower-cased) names of keys which hold a key rather than referencing one - matching
+// these explicitly avoids flagging keys such as `publicKey`, `sshPublicKey` and `keyVaultUrl`
+var sensitiveExtensionSettingsKeyNames = map[string]struct{}{
+	"key":               {},
+	"accesskey":         {},
+	"accountkey":        {},
+	"apikey":            {},
+	"encryptionkey":     {},
+	"primarykey":        {},
+	"privatekey":        {},
+	"secondarykey":      {},
+	"sharedaccesskey":   {},
+	"sharedkey":         {},
+	"storageaccountkey": {},
+	"subscriptionkey":   {},
+	"workspacekey":      {},
+}
+
+func isSensitiveExtensionSettingsKey(input string) bool {
+	key := strings.ToLower(input)
+	for _, sensitive := range []string{"password", "secret", "token"} {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	_, ok := sensitiveExtensionSettingsKeyNames[key]
+	return ok
+}
@@ -0,0 +1,92 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package validate
+
+import "testing"
+
+func TestExtensionSettings(t *testing.T) {
+	testData := []struct {
+		input         string
+		expectError   bool
+		expectWarning bool
+	}{
+		{
+			// empty
+			input: "",
+		},
+		{
+			// invalid json
+			input:       "{",
+			expectError: true,
+		},
+		{
+			// no sensitive keys
+			input: `{"commandToExecute": "echo hello", "keyVaultUrl": "https://example.vault.azure.net"}`,
+		},
+		{
+			// password
+			input:         `{"commandToExecute": "echo hello", "password": "P@ssword1234!"}`,
+			expectWarning: true,
+		},
+		{
+			// case insensitive
+			input:         `{"AdminPassword": "P@ssword1234!"}`,
+			expectWarning: true,
+		},
+		{
+			// secret
+			input:         `{"clientSecret": "abc123"}`,
+			expectWarning: true,
+		},
+		{
+			// token
+			input:         `{"sasToken": "abc123"}`,
+			expectWarning: true,
+		},
+		{
+			// key
+			input:         `{"storageAccountKey": "abc123"}`,
+			expectWarning: true,
+		},
+		{
+			// api key
+			input:         `{"apiKey": "abc123"}`,
+			expectWarning: true,
+		},
+		{
+			// private key
+			input:         `{"privateKey": "abc123"}`,
+			expectWarning: true,
+		},
+		{
+			// public key
+			input: `{"publicKey": "ssh-rsa AAAA"}`,
+		},
+		{
+			// ssh public key
+			input: `{"sshPublicKey": "ssh-rsa AAAA"}`,
+		},
+		{
+			// key referencing another resource
+			input: `{"keyName": "example", "encryptionKeyUrl": "https://example.vault.azure.net/keys/example"}`,
+		},
+		{
+			// nested
+			input:         `{"storage": [{"name": "example", "password": "P@ssword1234!"}]}`,
+			expectWarning: true,
+		},
+	}
+
+	for _, v := range testData {
+		t.Logf("[DEBUG] Testing %q...", v.input)
+
+		warnings, errors := ExtensionSettings(v.input, "settings")
+		if actual := len(errors) > 0; actual != v.expectError {
+			t.Fatalf("Expected an error to be %t but got %t", v.expectError, actual)
+		}
+		if actual := len(warnings) > 0; actual != v.expectWarning {
+			t.Fatalf("Expected a warning to be %t but got %t", v.expectWarning, actual)
+		}
+	}
+}
@@ -30,6 +30,31 @@ func TestLinuxAdminPassword(t *testing.T) {
 			input:    "P@$$w0rd",
 			expected: false,
 		},
+		{
+			// bad: reserved words are matched case-insensitively
+			input:    "p@$$W0RD",
+			expected: false,
+		},
+		{
+			// bad: reserved words are matched case-insensitively
+			input:    "PASS@WORD1",
+			expected: false,
+		},
+		{
+			// bad: reserved words are matched case-insensitively
+			input:    "ILoveYou!",
+			expected: false,
+		},
+		{
+			// bad: reserved words are matched ignoring surrounding whitespace
+			input:    " P@ssw0rd ",
+			expected: false,
+		},
+		{
+			// a reserved word with additional characters is fine
+			input:    "P@ssw0rd9",
+			expected: true,
+		},
 		{
 			// bad: can't be longer than 72 characters
 			input:    "abcdefghijklmnopqrstuvwxyzabcdefghiJklmno9q.stuvwxyzabcdefghijkjlmnopqrst",
@@ -67,3 +92,112 @@ func TestLinuxAdminPassword(t *testing.T) {
 		}
 	}
 }
+
+func TestLinuxAdminPasswordWithOptions(t *testing.T) {
+	testData := []struct {
+		input             string
+		minLen            int
+		maxLen            int
+		requireComplexity bool
+		expected          bool
+	}{
+		{
+			// bad: empty
+			input:             "",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: false,
+			expected:          false,
+		},
+		{
+			// bad: shorter than the minimum length
+			input:             "A9.defg",
+			minLen:            8,
+			maxLen:            123,
+			requireComplexity: true,
+			expected:          false,
+		},
+		{
+			// exactly the minimum length is fine
+			input:             "A9.defgh",
+			minLen:            8,
+			maxLen:            123,
+			requireComplexity: true,
+			expected:          true,
+		},
+		{
+			// longer than 72 characters is fine when the maximum length is raised
+			input:             "abcdefghijklmnopqrstuvwxyzabcdefghiJklmno9q.stuvwxyzabcdefghijkjlmnopqrst",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: true,
+			expected:          true,
+		},
+		{
+			// exactly the maximum length is fine
+			input:             "abcdefghiJ",
+			minLen:            6,
+			maxLen:            10,
+			requireComplexity: false,
+			expected:          true,
+		},
+		{
+			// bad: longer than the maximum length
+			input:             "abcdefghiJk",
+			minLen:            6,
+			maxLen:            10,
+			requireComplexity: false,
+			expected:          false,
+		},
+		{
+			// all lower characters are fine when complexity isn't required
+			input:             "juanjojuanjo",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: false,
+			expected:          true,
+		},
+		{
+			// bad: all lower characters when complexity is required
+			input:             "juanjojuanjo",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: true,
+			expected:          false,
+		},
+		{
+			// bad: can't use reserved words, even when complexity isn't required
+			input:             "Password1",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: false,
+			expected:          false,
+		},
+		{
+			// bad: can't use mixed-case variants of reserved words, even when complexity isn't required
+			input:             "password1",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: false,
+			expected:          false,
+		},
+		{
+			// bad: can't use reserved words
+			input:             "iloveyou!",
+			minLen:            6,
+			maxLen:            123,
+			requireComplexity: true,
+			expected:          false,
+		},
+	}
+
+	for _, v := range testData {
+		t.Logf("[DEBUG] Testing %q..", v.input)
+
+		_, errors := LinuxAdminPasswordWithOptions(v.minLen, v.maxLen, v.requireComplexity)(v.input, "admin_password")
+		actual := len(errors) == 0
+		if v.expected != actual {
+			t.Fatalf("Expected %t but got %t", v.expected, actual)
+		}
+	}
+}
@@ -141,6 +141,26 @@ func TestLinuxComputerNamePrefix(t *testing.T) {
 			input:    "abc-",
 			expected: true,
 		},
+		{
+			// empty
+			input:    "",
+			expected: false,
+		},
+		{
+			// underscore prefix
+			input:    "_abc",
+			expected: false,
+		},
+		{
+			// period suffix
+			input:    "abc.",
+			expected: false,
+		},
+		{
+			// special characters
+			input:    "abc@def",
+			expected: false,
+		},
 	}
 
 	for _, v := range testData {
@@ -12,6 +12,12 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// sshKeyMinimumRSABits is the minimum size of an RSA SSH Key supported by Azure
+const sshKeyMinimumRSABits = 2048
+
+// sshKeyGuidance is included in the errors for unsupported SSH Keys, to explain how a supported SSH Key can be generated
+const sshKeyGuidance = "a supported SSH key can be generated using `ssh-keygen -t rsa -b 4096`"
+
 // SSHKey performs some basic validation on supplied SSH Keys - Encoded Signature and Key Size are evaluated
 // Will require rework if/when other Key Types are supported
 func SSHKey(i interface{}, k string) (warnings []string, errors []error) {
@@ -36,15 +42,15 @@ func SSHKey(i interface{}, k string) (warnings []string, errors []error) {
 		}
 
 		if pubKey.Type() != ssh.KeyAlgoRSA {
-			return nil, []error{fmt.Errorf("- the provided %s SSH key is not supported. Only RSA SSH keys are supported by Azure", pubKey.Type())}
+			return nil, []error{fmt.Errorf("- the provided %s SSH key is not supported. Only RSA SSH keys are supported by Azure - %s", pubKey.Type(), sshKeyGuidance)}
 		} else {
 			rsaPubKey, ok := pubKey.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey)
 			if !ok {
 				return nil, []error{fmt.Errorf("- could not retrieve the RSA public key from the SSH public key")}
 			}
 			rsaPubKeyBits := rsaPubKey.Size() * 8
-			if rsaPubKeyBits < 2048 {
-				return nil, []error{fmt.Errorf("- the provided RSA SSH key has %d bits. Only ssh-rsa keys with 2048 bits or higher are supported by Azure", rsaPubKeyBits)}
+			if rsaPubKeyBits < sshKeyMinimumRSABits {
+				return nil, []error{fmt.Errorf("- the provided RSA SSH key has %d bits. Only ssh-rsa keys with %d bits or higher are supported by Azure - %s", rsaPubKeyBits, sshKeyMinimumRSABits, sshKeyGuidance)}
 			}
 		}
 	} else {
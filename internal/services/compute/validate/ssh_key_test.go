@@ -3,7 +3,10 @@
 
 package validate
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestSSHKey(t *testing.T) {
 	testData := []struct {
@@ -61,3 +64,34 @@ func TestSSHKey(t *testing.T) {
 		}
 	}
 }
+
+func TestSSHKeyUnsupportedKeyGuidance(t *testing.T) {
+	testData := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "RSA key smaller than 2048 bits",
+			input:    "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDbzSM5KBFKmNilWjlw2YenzARxww1H+BMDMBVyzKYsNwEQc6Tj3ZB1Jun0l6Xkaw5BxKdwKFdhPlQh3nqpbm7xmSY7MuRZLPU+LRM3wI9RwcreDb3BXWacy41YIRGhUzpAzXmWdVyub/k70AJAngpVLLBmLcjuavjplR/fkTjslw==",
+			expected: "has 1024 bits",
+		},
+		{
+			name:     "unsupported ed25519 key",
+			input:    "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOwlR9xtbM69hWLJbB5nHi0a65TuRvtaldgTJQ4ClL1W",
+			expected: "the provided ssh-ed25519 SSH key is not supported",
+		},
+	}
+
+	for _, v := range testData {
+		t.Logf("[DEBUG] Testing %q...", v.name)
+
+		_, errors := SSHKey(v.input, "public_key")
+		if len(errors) != 1 {
+			t.Fatalf("Expected 1 error but got %d", len(errors))
+		}
+		if msg := errors[0].Error(); !strings.Contains(msg, v.expected) || !strings.Contains(msg, "ssh-keygen -t rsa -b 4096") {
+			t.Fatalf("Expected the error to contain %q and guidance on generating a supported key but got %q", v.expected, msg)
+		}
+	}
+}
@@ -136,6 +136,26 @@ func TestWindowsComputerNamePrefix(t *testing.T) {
 			input:    "abc-",
 			expected: true,
 		},
+		{
+			// empty
+			input:    "",
+			expected: false,
+		},
+		{
+			// only numbers
+			input:    "12345",
+			expected: false,
+		},
+		{
+			// underscore
+			input:    "abc_def",
+			expected: false,
+		},
+		{
+			// period
+			input:    "abc.def",
+			expected: false,
+		},
 	}
 
 	for _, v := range testData {
@@ -5,25 +5,79 @@ package compute
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/identity"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/location"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-01/proximityplacementgroups"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-02/diskencryptionsets"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-03/galleryapplicationversions"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachines"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesets"
-	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-09-01/applicationsecuritygroups"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetvms"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/applicationsecuritygroups"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/loadbalancers"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/networksecuritygroups"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/publicipprefixes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	azValidate "github.com/hashicorp/terraform-provider-azurerm/helpers/validate"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/clients"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/features"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/services/compute/client"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/services/compute/validate"
+	networkParse "github.com/hashicorp/terraform-provider-azurerm/internal/services/network/parse"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/pluginsdk"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/validation"
 	"github.com/hashicorp/terraform-provider-azurerm/utils"
+	"github.com/rickb777/date/period"
 )
 
+const (
+	virtualMachineScaleSetMaxTags           = 50
+	virtualMachineScaleSetMaxTagKeyLength   = 512
+	virtualMachineScaleSetMaxTagValueLength = 256
+)
+
+// validateVirtualMachineScaleSetTags ensures the `tags` are within the limits enforced by the API, since the schema validation
+// is skipped when any of the values are unknown during the plan and the API only returns a generic error at apply time
+func validateVirtualMachineScaleSetTags(input map[string]interface{}) error {
+	if len(input) > virtualMachineScaleSetMaxTags {
+		return fmt.Errorf("a maximum of %d tags can be applied to a Virtual Machine Scale Set but %d were specified", virtualMachineScaleSetMaxTags, len(input))
+	}
+
+	keysTooLong := make([]string, 0)
+	valuesTooLong := make([]string, 0)
+	for k, v := range input {
+		if len(k) > virtualMachineScaleSetMaxTagKeyLength {
+			keysTooLong = append(keysTooLong, k)
+		}
+		if value, ok := v.(string); ok && len(value) > virtualMachineScaleSetMaxTagValueLength {
+			valuesTooLong = append(valuesTooLong, k)
+		}
+	}
+
+	if len(keysTooLong) > 0 {
+		sort.Strings(keysTooLong)
+		return fmt.Errorf("the maximum length for a tag key is %d characters but the following keys are longer: %q", virtualMachineScaleSetMaxTagKeyLength, keysTooLong)
+	}
+	if len(valuesTooLong) > 0 {
+		sort.Strings(valuesTooLong)
+		return fmt.Errorf("the maximum length for a tag value is %d characters but the values for the following keys are longer: %q", virtualMachineScaleSetMaxTagValueLength, valuesTooLong)
+	}
+
+	return nil
+}
+
 func VirtualMachineScaleSetAdditionalCapabilitiesSchema() *pluginsdk.Schema {
 	return &pluginsdk.Schema{
 		Type:     pluginsdk.TypeList,
@@ -38,7 +92,7 @@ func VirtualMachineScaleSetAdditionalCapabilitiesSchema() *pluginsdk.Schema {
 					Type:     pluginsdk.TypeBool,
 					Optional: true,
 					Default:  false,
-					ForceNew: true,
+					// this can be toggled in-place when the Scale Set has no instances, see virtualMachineScaleSetUltraSSDCustomizeDiff
 				},
 			},
 		},
@@ -57,6 +111,38 @@ func ExpandVirtualMachineScaleSetAdditionalCapabilities(input []interface{}) *vi
 	return &capabilities
 }
 
+// virtualMachineScaleSetUltraSSDCustomizeDiff forces a new Scale Set when `ultra_ssd_enabled` is changed whilst the Scale Set
+// has instances - the API only allows the UltraSSD capability to be toggled when there are no instances, so the change is only
+// applied in-place once the Scale Set has been scaled to 0
+func virtualMachineScaleSetUltraSSDCustomizeDiff(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+	if diff.Id() == "" || !diff.HasChange("additional_capabilities.0.ultra_ssd_enabled") {
+		return nil
+	}
+
+	// `instances` is refreshed from the capacity of the Scale Set, so the prior value is the number of instances it currently has
+	if instances, _ := diff.GetChange("instances"); instances.(int) > 0 {
+		return diff.ForceNew("additional_capabilities.0.ultra_ssd_enabled")
+	}
+
+	return nil
+}
+
+// ExpandVirtualMachineScaleSetAdditionalCapabilitiesUpdate expands the `additional_capabilities` block when it's changed
+// during an update - the API only allows the UltraSSD capability to be toggled when the Scale Set has no instances, so an
+// error is returned when the Scale Set has been scaled out since the plan was made
+func ExpandVirtualMachineScaleSetAdditionalCapabilitiesUpdate(input []interface{}, existingSku *virtualmachinescalesets.Sku, orchestrationMode virtualmachinescalesets.OrchestrationMode) (*virtualmachinescalesets.AdditionalCapabilities, error) {
+	if existingSku != nil && pointer.From(existingSku.Capacity) > 0 {
+		instances := *existingSku.Capacity
+		if orchestrationMode == virtualmachinescalesets.OrchestrationModeFlexible {
+			return nil, fmt.Errorf("`additional_capabilities.0.ultra_ssd_enabled` can only be changed when the Scale Set has no instances but it currently has %d - since the capability is applied to each Virtual Machine when it's created in a Flexible Scale Set, the Scale Set must be scaled to `0` (and any Virtual Machines added to the Scale Set outside of Terraform removed) before this can be changed", instances)
+		}
+
+		return nil, fmt.Errorf("`additional_capabilities.0.ultra_ssd_enabled` can only be changed when the Scale Set has no instances but it currently has %d - since the capability is part of the model shared by every instance in a Uniform Scale Set, the Scale Set must be scaled to `0` before this can be changed and then scaled back out afterwards", instances)
+	}
+
+	return ExpandVirtualMachineScaleSetAdditionalCapabilities(input), nil
+}
+
 func FlattenVirtualMachineScaleSetAdditionalCapabilities(input *virtualmachinescalesets.AdditionalCapabilities) []interface{} {
 	if input == nil {
 		return []interface{}{}
@@ -108,6 +194,11 @@ func VirtualMachineScaleSetNetworkInterfaceSchema() *pluginsdk.Schema {
 					Optional: true,
 					Default:  false,
 				},
+				"fpga_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
 				"network_security_group_id": {
 					Type:         pluginsdk.TypeString,
 					Optional:     true,
@@ -118,6 +209,11 @@ func VirtualMachineScaleSetNetworkInterfaceSchema() *pluginsdk.Schema {
 					Optional: true,
 					Default:  false,
 				},
+				"tcp_state_tracking_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
 			},
 		},
 	}
@@ -144,6 +240,13 @@ func VirtualMachineScaleSetGalleryApplicationSchema() *pluginsdk.Schema {
 					ValidateFunc: galleryapplicationversions.ValidateApplicationVersionID,
 				},
 
+				"automatic_upgrade_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Optional: true,
+					Default:  false,
+					ForceNew: true,
+				},
+
 				// Example: https://mystorageaccount.blob.core.windows.net/configurations/settings.config
 				"configuration_blob_uri": {
 					Type:         pluginsdk.TypeString,
@@ -167,6 +270,13 @@ func VirtualMachineScaleSetGalleryApplicationSchema() *pluginsdk.Schema {
 					ForceNew:     true,
 					ValidateFunc: validation.StringIsNotEmpty,
 				},
+
+				"treat_failure_as_deployment_failure_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Optional: true,
+					Default:  false,
+					ForceNew: true,
+				},
 			},
 		},
 	}
@@ -231,12 +341,16 @@ func expandVirtualMachineScaleSetGalleryApplication(input []interface{}) *[]virt
 		configurationReference := v.(map[string]interface{})["configuration_blob_uri"].(string)
 		order := v.(map[string]interface{})["order"].(int)
 		tag := v.(map[string]interface{})["tag"].(string)
+		automaticUpgradeEnabled := v.(map[string]interface{})["automatic_upgrade_enabled"].(bool)
+		treatFailureAsDeploymentFailureEnabled := v.(map[string]interface{})["treat_failure_as_deployment_failure_enabled"].(bool)
 
 		app := &virtualmachinescalesets.VMGalleryApplication{
-			PackageReferenceId:     packageReferenceId,
-			ConfigurationReference: pointer.To(configurationReference),
-			Order:                  pointer.To(int64(order)),
-			Tags:                   pointer.To(tag),
+			PackageReferenceId:              packageReferenceId,
+			ConfigurationReference:          pointer.To(configurationReference),
+			Order:                           pointer.To(int64(order)),
+			Tags:                            pointer.To(tag),
+			EnableAutomaticUpgrade:          pointer.To(automaticUpgradeEnabled),
+			TreatFailureAsDeploymentFailure: pointer.To(treatFailureAsDeploymentFailureEnabled),
 		}
 
 		out = append(out, *app)
@@ -269,10 +383,12 @@ func flattenVirtualMachineScaleSetGalleryApplication(input *[]virtualmachinescal
 		}
 
 		app := map[string]interface{}{
-			"version_id":             v.PackageReferenceId,
-			"configuration_blob_uri": configurationReference,
-			"order":                  order,
-			"tag":                    tag,
+			"version_id":                v.PackageReferenceId,
+			"automatic_upgrade_enabled": pointer.From(v.EnableAutomaticUpgrade),
+			"configuration_blob_uri":    configurationReference,
+			"order":                     order,
+			"tag":                       tag,
+			"treat_failure_as_deployment_failure_enabled": pointer.From(v.TreatFailureAsDeploymentFailure),
 		}
 
 		out = append(out, app)
@@ -286,6 +402,10 @@ func expandVirtualMachineScaleSetGalleryApplications(input []interface{}) *[]vir
 		return nil
 	}
 
+	// NOTE: the deprecated `gallery_applications` block intentionally doesn't expose `automatic_upgrade_enabled` or
+	// `treat_failure_as_deployment_failure_enabled` - these are left unset so that the API defaults apply and existing
+	// configurations continue to work until the block is removed in 4.0, users wanting these should use `gallery_application`
+
 	out := make([]virtualmachinescalesets.VMGalleryApplication, 0)
 
 	for _, v := range input {
@@ -344,33 +464,52 @@ func flattenVirtualMachineScaleSetGalleryApplications(input *[]virtualmachinesca
 }
 
 func VirtualMachineScaleSetScaleInPolicySchema() *pluginsdk.Schema {
+	scaleInRules := []string{
+		string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault),
+		string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesNewestVM),
+		string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesOldestVM),
+	}
+
+	elem := &pluginsdk.Resource{
+		Schema: map[string]*pluginsdk.Schema{
+			// the API evaluates the rules in order, when `rules` isn't specified this defaults to `Default` - this intentionally
+			// isn't Computed, so that we can tell whether `rules` or the deprecated `rule` is used
+			"rules": {
+				Type:     pluginsdk.TypeList,
+				Optional: true,
+				MinItems: 1,
+				Elem: &pluginsdk.Schema{
+					Type:         pluginsdk.TypeString,
+					ValidateFunc: validation.StringInSlice(scaleInRules, false),
+				},
+			},
+
+			"force_deletion_enabled": {
+				Type:     pluginsdk.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+		},
+	}
+
 	if !features.FourPointOhBeta() {
+		elem.Schema["rule"] = &pluginsdk.Schema{
+			Type:          pluginsdk.TypeString,
+			Optional:      true,
+			Computed:      true,
+			ValidateFunc:  validation.StringInSlice(scaleInRules, false),
+			ConflictsWith: []string{"scale_in.0.rules"},
+			Deprecated:    "`rule` has been deprecated in favour of the `rules` property and will be removed in version 4.0 of the AzureRM Provider.",
+		}
+		elem.Schema["rules"].ConflictsWith = []string{"scale_in.0.rule"}
+
 		return &pluginsdk.Schema{
 			Type:          pluginsdk.TypeList,
 			Optional:      true,
-			Computed:      !features.FourPointOhBeta(),
+			Computed:      true,
 			MaxItems:      1,
 			ConflictsWith: []string{"scale_in_policy"},
-			Elem: &pluginsdk.Resource{
-				Schema: map[string]*pluginsdk.Schema{
-					"rule": {
-						Type:     pluginsdk.TypeString,
-						Optional: true,
-						Default:  string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault),
-						ValidateFunc: validation.StringInSlice([]string{
-							string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault),
-							string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesNewestVM),
-							string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesOldestVM),
-						}, false),
-					},
-
-					"force_deletion_enabled": {
-						Type:     pluginsdk.TypeBool,
-						Optional: true,
-						Default:  false,
-					},
-				},
-			},
+			Elem:          elem,
 		}
 	}
 
@@ -378,26 +517,7 @@ func VirtualMachineScaleSetScaleInPolicySchema() *pluginsdk.Schema {
 		Type:     pluginsdk.TypeList,
 		Optional: true,
 		MaxItems: 1,
-		Elem: &pluginsdk.Resource{
-			Schema: map[string]*pluginsdk.Schema{
-				"rule": {
-					Type:     pluginsdk.TypeString,
-					Optional: true,
-					Default:  string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault),
-					ValidateFunc: validation.StringInSlice([]string{
-						string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault),
-						string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesNewestVM),
-						string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesOldestVM),
-					}, false),
-				},
-
-				"force_deletion_enabled": {
-					Type:     pluginsdk.TypeBool,
-					Optional: true,
-					Default:  false,
-				},
-			},
-		},
+		Elem:     elem,
 	}
 }
 
@@ -406,36 +526,70 @@ func ExpandVirtualMachineScaleSetScaleInPolicy(input []interface{}) *virtualmach
 		return nil
 	}
 
-	rule := input[0].(map[string]interface{})["rule"].(string)
-	forceDeletion := input[0].(map[string]interface{})["force_deletion_enabled"].(bool)
+	raw := input[0].(map[string]interface{})
+
+	rules := make([]virtualmachinescalesets.VirtualMachineScaleSetScaleInRules, 0)
+	if v, ok := raw["rules"].([]interface{}); ok {
+		for _, rule := range v {
+			if rule, ok := rule.(string); ok && rule != "" {
+				rules = append(rules, virtualmachinescalesets.VirtualMachineScaleSetScaleInRules(rule))
+			}
+		}
+	}
+	if len(rules) == 0 {
+		if rule, ok := raw["rule"].(string); ok && rule != "" {
+			rules = append(rules, virtualmachinescalesets.VirtualMachineScaleSetScaleInRules(rule))
+		}
+	}
+	if len(rules) == 0 {
+		rules = append(rules, virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault)
+	}
 
 	return &virtualmachinescalesets.ScaleInPolicy{
-		Rules:         &[]virtualmachinescalesets.VirtualMachineScaleSetScaleInRules{virtualmachinescalesets.VirtualMachineScaleSetScaleInRules(rule)},
-		ForceDeletion: pointer.To(forceDeletion),
+		Rules:         &rules,
+		ForceDeletion: pointer.To(raw["force_deletion_enabled"].(bool)),
 	}
 }
 
-func FlattenVirtualMachineScaleSetScaleInPolicy(input *virtualmachinescalesets.ScaleInPolicy) []interface{} {
+// FlattenVirtualMachineScaleSetScaleInPolicy flattens the Scale In Policy, using the existing `scale_in` block from the state
+// to determine whether `rules` is in use, since this is only set when it's configured
+func FlattenVirtualMachineScaleSetScaleInPolicy(input *virtualmachinescalesets.ScaleInPolicy, existing []interface{}) []interface{} {
 	if input == nil {
 		return []interface{}{}
 	}
 
-	rule := string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault)
-	var forceDeletion bool
-	if rules := input.Rules; rules != nil && len(*rules) > 0 {
-		rule = string((*rules)[0])
+	rules := make([]interface{}, 0)
+	if input.Rules != nil {
+		for _, rule := range *input.Rules {
+			rules = append(rules, string(rule))
+		}
+	}
+	if len(rules) == 0 {
+		rules = append(rules, string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault))
 	}
 
+	var forceDeletion bool
 	if input.ForceDeletion != nil {
 		forceDeletion = *input.ForceDeletion
 	}
 
-	return []interface{}{
-		map[string]interface{}{
-			"rule":                   rule,
-			"force_deletion_enabled": forceDeletion,
-		},
+	configuredRules := make([]interface{}, 0)
+	if len(existing) > 0 && existing[0] != nil {
+		if v, ok := existing[0].(map[string]interface{})["rules"].([]interface{}); ok && len(v) > 0 {
+			configuredRules = rules
+		}
+	}
+
+	result := map[string]interface{}{
+		"rules":                  configuredRules,
+		"force_deletion_enabled": forceDeletion,
+	}
+
+	if !features.FourPointOhBeta() {
+		result["rule"] = rules[0]
 	}
+
+	return []interface{}{result}
 }
 
 func VirtualMachineScaleSetSpotRestorePolicySchema() *pluginsdk.Schema {
@@ -458,7 +612,7 @@ func VirtualMachineScaleSetSpotRestorePolicySchema() *pluginsdk.Schema {
 					Optional:     true,
 					Default:      "PT1H",
 					ForceNew:     true,
-					ValidateFunc: azValidate.ISO8601DurationBetween("PT15M", "PT2H"),
+					ValidateFunc: azValidate.ISO8601DurationBetweenWithMessage("PT15M", "PT2H"),
 				},
 			},
 		},
@@ -532,6 +686,10 @@ func VirtualMachineScaleSetNetworkInterfaceSchemaForDataSource() *pluginsdk.Sche
 					Type:     pluginsdk.TypeBool,
 					Computed: true,
 				},
+				"fpga_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Computed: true,
+				},
 				"network_security_group_id": {
 					Type:     pluginsdk.TypeString,
 					Computed: true,
@@ -540,6 +698,10 @@ func VirtualMachineScaleSetNetworkInterfaceSchemaForDataSource() *pluginsdk.Sche
 					Type:     pluginsdk.TypeBool,
 					Computed: true,
 				},
+				"tcp_state_tracking_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Computed: true,
+				},
 			},
 		},
 	}
@@ -694,10 +856,35 @@ func virtualMachineScaleSetPublicIPAddressSchema() *pluginsdk.Schema {
 					ValidateFunc: validation.StringIsNotEmpty,
 				},
 
+				"delete_option": {
+					Type:     pluginsdk.TypeString,
+					Optional: true,
+					Default: func() interface{} {
+						if !features.FourPointOhBeta() {
+							return nil
+						}
+						return string(virtualmachinescalesets.DeleteOptionsDelete)
+					}(),
+					Computed: !features.FourPointOhBeta(),
+					ValidateFunc: validation.StringInSlice([]string{
+						string(virtualmachinescalesets.DeleteOptionsDelete),
+						string(virtualmachinescalesets.DeleteOptionsDetach),
+					}, false),
+				},
+
 				"domain_name_label": {
 					Type:         pluginsdk.TypeString,
 					Optional:     true,
-					ValidateFunc: validation.StringIsNotEmpty,
+					ValidateFunc: validate.VirtualMachineScaleSetDomainNameLabel,
+				},
+				"domain_name_label_scope": {
+					Type:     pluginsdk.TypeString,
+					Optional: true,
+					ForceNew: true,
+					ValidateFunc: validation.StringInSlice(
+						virtualmachinescalesets.PossibleValuesForDomainNameLabelScopeTypes(),
+						false,
+					),
 				},
 				"idle_timeout_in_minutes": {
 					Type:         pluginsdk.TypeInt,
@@ -762,11 +949,21 @@ func virtualMachineScaleSetPublicIPAddressSchemaForDataSource() *pluginsdk.Schem
 					Computed: true,
 				},
 
+				"delete_option": {
+					Type:     pluginsdk.TypeString,
+					Computed: true,
+				},
+
 				"domain_name_label": {
 					Type:     pluginsdk.TypeString,
 					Computed: true,
 				},
 
+				"domain_name_label_scope": {
+					Type:     pluginsdk.TypeString,
+					Computed: true,
+				},
+
 				"idle_timeout_in_minutes": {
 					Type:     pluginsdk.TypeInt,
 					Computed: true,
@@ -803,150 +1000,416 @@ func virtualMachineScaleSetPublicIPAddressSchemaForDataSource() *pluginsdk.Schem
 	}
 }
 
-func ExpandVirtualMachineScaleSetNetworkInterface(input []interface{}) (*[]virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration, error) {
-	output := make([]virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration, 0)
+// virtualMachineScaleSetFpgaSkuRegex matches the SKUs which include an FPGA (currently the NP-series), which are the only
+// SKUs supporting FPGA networking
+var virtualMachineScaleSetFpgaSkuRegex = regexp.MustCompile(`(?i)^Standard_NP[0-9]+s$`)
+
+// validateVirtualMachineScaleSetNetworkInterfaceFpga ensures FPGA networking is only enabled when the SKU includes an FPGA,
+// since otherwise the API only returns an error once the instances are being provisioned
+func validateVirtualMachineScaleSetNetworkInterfaceFpga(input []interface{}, skuName string) error {
+	if virtualMachineScaleSetFpgaSkuRegex.MatchString(skuName) {
+		return nil
+	}
 
 	for _, v := range input {
+		if v == nil {
+			continue
+		}
 		raw := v.(map[string]interface{})
 
-		dnsServers := utils.ExpandStringSlice(raw["dns_servers"].([]interface{}))
+		if raw["fpga_enabled"].(bool) {
+			return fmt.Errorf("`fpga_enabled` cannot be set to `true` on the `network_interface` %q since the SKU %q doesn't include an FPGA - FPGA networking is only supported on the NP-series SKUs", raw["name"].(string), skuName)
+		}
+	}
 
-		ipConfigurations := make([]virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration, 0)
-		ipConfigurationsRaw := raw["ip_configuration"].([]interface{})
-		for _, configV := range ipConfigurationsRaw {
-			configRaw := configV.(map[string]interface{})
-			ipConfiguration, err := expandVirtualMachineScaleSetIPConfiguration(configRaw)
-			if err != nil {
-				return nil, err
-			}
+	return nil
+}
 
-			ipConfigurations = append(ipConfigurations, *ipConfiguration)
-		}
+// ValidateZonesForLocation checks that each of the specified Availability Zones is supported within the Location, using the
+// Zones the Virtual Machine SKUs are available in - since otherwise an unsupported Zone only fails once the instances are provisioned.
+// This is only done when Enhanced Validation is enabled, and if the SKUs can't be retrieved (for example due to permissions) this is
+// left to the API.
+func ValidateZonesForLocation(ctx context.Context, client *client.Client, location string, zones []string) error {
+	if !features.EnhancedValidationEnabled() || len(zones) == 0 {
+		return nil
+	}
 
-		config := virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration{
-			Name: raw["name"].(string),
-			Properties: &virtualmachinescalesets.VirtualMachineScaleSetNetworkConfigurationProperties{
-				DnsSettings: &virtualmachinescalesets.VirtualMachineScaleSetNetworkConfigurationDnsSettings{
-					DnsServers: dnsServers,
-				},
-				EnableAcceleratedNetworking: pointer.To(raw["enable_accelerated_networking"].(bool)),
-				EnableIPForwarding:          pointer.To(raw["enable_ip_forwarding"].(bool)),
-				IPConfigurations:            ipConfigurations,
-				Primary:                     pointer.To(raw["primary"].(bool)),
-			},
+	virtualMachineSkus, err := client.ListSkuCapabilities(ctx, location)
+	if err != nil {
+		log.Printf("[DEBUG] unable to retrieve the Availability Zones supported in %q, leaving the validation of `zones` to the API: %+v", location, err)
+		return nil
+	}
+
+	return validateZonesForLocation(virtualMachineSkus, location, zones)
+}
+
+func validateZonesForLocation(input []client.SkuCapabilities, skuLocation string, zones []string) error {
+	// if the SKUs can't be found we leave it to the API to return an error
+	if len(input) == 0 {
+		return nil
+	}
+
+	// the Zones of each SKU are already limited to the Location
+	supported := make(map[string]struct{})
+	for _, sku := range input {
+		for _, zone := range sku.Zones {
+			supported[zone] = struct{}{}
 		}
+	}
 
-		if nsgId := raw["network_security_group_id"].(string); nsgId != "" {
-			config.Properties.NetworkSecurityGroup = &virtualmachinescalesets.SubResource{
-				Id: pointer.To(nsgId),
-			}
+	unsupported := make([]string, 0)
+	for _, zone := range zones {
+		if _, ok := supported[zone]; !ok {
+			unsupported = append(unsupported, zone)
 		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
 
-		output = append(output, config)
+	if len(supported) == 0 {
+		return fmt.Errorf("the Zones %q aren't supported since %q doesn't support Availability Zones", strings.Join(unsupported, ", "), skuLocation)
 	}
 
-	return &output, nil
-}
+	supportedZones := make([]string, 0, len(supported))
+	for zone := range supported {
+		supportedZones = append(supportedZones, zone)
+	}
+	sort.Strings(supportedZones)
 
-func expandVirtualMachineScaleSetIPConfiguration(raw map[string]interface{}) (*virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration, error) {
-	applicationGatewayBackendAddressPoolIdsRaw := raw["application_gateway_backend_address_pool_ids"].(*pluginsdk.Set).List()
-	applicationGatewayBackendAddressPoolIds := expandIDsToSubResources(applicationGatewayBackendAddressPoolIdsRaw)
+	return fmt.Errorf("the Zones %q aren't supported in %q - supported Zones are %q", strings.Join(unsupported, ", "), skuLocation, strings.Join(supportedZones, ", "))
+}
 
-	applicationSecurityGroupIdsRaw := raw["application_security_group_ids"].(*pluginsdk.Set).List()
-	applicationSecurityGroupIds := expandIDsToSubResources(applicationSecurityGroupIdsRaw)
+// EnsureMarketplaceAgreementAccepted checks that the Marketplace Agreement for the Plan used by a Marketplace Image has been
+// accepted within the Subscription, since otherwise provisioning fails once the instances are created. Since this requires an
+// additional request to the Marketplace Ordering API this is only done when Enhanced Validation is enabled.
+func EnsureMarketplaceAgreementAccepted(ctx context.Context, client *client.Client, publisher string, offer string, plan string) error {
+	if !features.EnhancedValidationEnabled() {
+		return nil
+	}
 
-	loadBalancerBackendAddressPoolIdsRaw := raw["load_balancer_backend_address_pool_ids"].(*pluginsdk.Set).List()
-	loadBalancerBackendAddressPoolIds := expandIDsToSubResources(loadBalancerBackendAddressPoolIdsRaw)
+	accepted, err := client.MarketplaceAgreementAccepted(ctx, publisher, offer, plan)
+	if err != nil {
+		// the agreement can't be retrieved without permissions to the Marketplace Ordering API, so leave this to the API
+		log.Printf("[DEBUG] unable to determine whether the Marketplace Agreement for the Plan %q has been accepted: %+v", plan, err)
+		return nil
+	}
 
-	loadBalancerInboundNatPoolIdsRaw := raw["load_balancer_inbound_nat_rules_ids"].(*pluginsdk.Set).List()
-	loadBalancerInboundNatPoolIds := expandIDsToSubResources(loadBalancerInboundNatPoolIdsRaw)
+	return validateMarketplaceAgreementAccepted(accepted, publisher, offer, plan)
+}
 
-	primary := raw["primary"].(bool)
-	version := virtualmachinescalesets.IPVersion(raw["version"].(string))
-	if primary && version == virtualmachinescalesets.IPVersionIPvSix {
-		return nil, fmt.Errorf("an IPv6 Primary IP Configuration is unsupported - instead add a IPv4 IP Configuration as the Primary and make the IPv6 IP Configuration the secondary")
+func validateMarketplaceAgreementAccepted(accepted *bool, publisher string, offer string, plan string) error {
+	// a nil value means there's no Marketplace Agreement for this Plan
+	if accepted == nil || *accepted {
+		return nil
 	}
 
-	ipConfiguration := virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration{
-		Name: raw["name"].(string),
-		Properties: &virtualmachinescalesets.VirtualMachineScaleSetIPConfigurationProperties{
-			Primary:                               pointer.To(primary),
-			PrivateIPAddressVersion:               pointer.To(version),
-			ApplicationGatewayBackendAddressPools: applicationGatewayBackendAddressPoolIds,
-			ApplicationSecurityGroups:             applicationSecurityGroupIds,
-			LoadBalancerBackendAddressPools:       loadBalancerBackendAddressPoolIds,
-			LoadBalancerInboundNatPools:           loadBalancerInboundNatPoolIds,
-		},
-	}
+	return fmt.Errorf(`the Marketplace Agreement for the Plan %q (Publisher %q / Offer %q) hasn't been accepted in this Subscription - this can be accepted by adding the following configuration:
 
-	if subnetId := raw["subnet_id"].(string); subnetId != "" {
-		ipConfiguration.Properties.Subnet = &virtualmachinescalesets.ApiEntityReference{
-			Id: pointer.To(subnetId),
-		}
+resource "azurerm_marketplace_agreement" "example" {
+  publisher = %q
+  offer     = %q
+  plan      = %q
+}
+
+and then referencing it in the "depends_on" of this Virtual Machine Scale Set`, plan, publisher, offer, publisher, offer, plan)
+}
+
+// checkVirtualMachineScaleSetAcceleratedNetworkingSupported checks that the SKU supports the number of Network Interfaces with
+// Accelerated Networking enabled using the cached SKU capabilities, since otherwise this fails at provisioning time
+func checkVirtualMachineScaleSetAcceleratedNetworkingSupported(ctx context.Context, client *client.Client, location string, skuName string, networkInterfacesRaw []interface{}) error {
+	if virtualMachineScaleSetAcceleratedNetworkInterfaceCount(networkInterfacesRaw) == 0 {
+		return nil
 	}
 
-	publicIPConfigsRaw := raw["public_ip_address"].([]interface{})
-	if len(publicIPConfigsRaw) > 0 {
-		publicIPConfigRaw := publicIPConfigsRaw[0].(map[string]interface{})
-		publicIPAddressConfig := expandVirtualMachineScaleSetPublicIPAddress(publicIPConfigRaw)
-		ipConfiguration.Properties.PublicIPAddressConfiguration = publicIPAddressConfig
+	sku, err := client.GetSkuCapabilities(ctx, location, skuName)
+	if err != nil {
+		return fmt.Errorf("retrieving the capabilities of the SKU %q: %+v", skuName, err)
 	}
 
-	return &ipConfiguration, nil
+	return validateVirtualMachineScaleSetAcceleratedNetworkingSupported(sku, location, skuName, networkInterfacesRaw)
 }
 
-func expandVirtualMachineScaleSetPublicIPAddress(raw map[string]interface{}) *virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration {
-	ipTagsRaw := raw["ip_tag"].([]interface{})
-	ipTags := make([]virtualmachinescalesets.VirtualMachineScaleSetIPTag, 0)
-	for _, ipTagV := range ipTagsRaw {
-		ipTagRaw := ipTagV.(map[string]interface{})
-		ipTags = append(ipTags, virtualmachinescalesets.VirtualMachineScaleSetIPTag{
-			Tag:       pointer.To(ipTagRaw["tag"].(string)),
-			IPTagType: pointer.To(ipTagRaw["type"].(string)),
-		})
+func validateVirtualMachineScaleSetAcceleratedNetworkingSupported(sku *client.SkuCapabilities, location string, skuName string, networkInterfacesRaw []interface{}) error {
+	// if the SKU can't be found we leave it to the API to return an error
+	if sku == nil {
+		return nil
 	}
 
-	publicIPAddressConfig := virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration{
-		Name: raw["name"].(string),
-		Properties: &virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationProperties{
-			IPTags:                 &ipTags,
-			PublicIPAddressVersion: pointer.To(virtualmachinescalesets.IPVersion(raw["version"].(string))),
-		},
+	count := virtualMachineScaleSetAcceleratedNetworkInterfaceCount(networkInterfacesRaw)
+	if count == 0 {
+		return nil
 	}
 
-	if domainNameLabel := raw["domain_name_label"].(string); domainNameLabel != "" {
-		dns := &virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationDnsSettings{
-			DomainNameLabel: domainNameLabel,
-		}
-		publicIPAddressConfig.Properties.DnsSettings = dns
+	if !sku.HasCapability("AcceleratedNetworkingEnabled") {
+		return fmt.Errorf("`enable_accelerated_networking` cannot be set to `true` on %d `network_interface` blocks since the SKU %q doesn't support Accelerated Networking in %q", count, skuName, location)
 	}
 
-	if idleTimeout := raw["idle_timeout_in_minutes"].(int); idleTimeout > 0 {
-		publicIPAddressConfig.Properties.IdleTimeoutInMinutes = pointer.To(int64(raw["idle_timeout_in_minutes"].(int)))
+	// Accelerated Networking can be enabled on every Network Interface the SKU supports, so the limit is `MaxNetworkInterfaces`
+	if limit, ok := sku.CapabilityInt("MaxNetworkInterfaces"); ok && count > limit {
+		return fmt.Errorf("`enable_accelerated_networking` cannot be set to `true` on %d `network_interface` blocks since the SKU %q supports a maximum of %d Network Interfaces with Accelerated Networking in %q", count, skuName, limit, location)
 	}
 
-	if publicIPPrefixID := raw["public_ip_prefix_id"].(string); publicIPPrefixID != "" {
-		publicIPAddressConfig.Properties.PublicIPPrefix = &virtualmachinescalesets.SubResource{
-			Id: pointer.To(publicIPPrefixID),
+	return nil
+}
+
+func virtualMachineScaleSetAcceleratedNetworkInterfaceCount(networkInterfacesRaw []interface{}) int64 {
+	count := int64(0)
+	for _, v := range networkInterfacesRaw {
+		if v == nil {
+			continue
+		}
+		if v.(map[string]interface{})["enable_accelerated_networking"].(bool) {
+			count++
 		}
 	}
 
-	return &publicIPAddressConfig
+	return count
 }
 
-func ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(input []interface{}) (*[]virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration, error) {
-	output := make([]virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration, 0)
+// checkVirtualMachineScaleSetApplicationSecurityGroupLocations checks that the Application Security Groups referenced by the IP
+// Configurations are in the same location as the Scale Set, rather than surfacing a less actionable error at provisioning time
+func checkVirtualMachineScaleSetApplicationSecurityGroupLocations(ctx context.Context, client *applicationsecuritygroups.ApplicationSecurityGroupsClient, networkInterfacesRaw []interface{}, expectedLocation string) error {
+	return validateVirtualMachineScaleSetApplicationSecurityGroupLocations(networkInterfacesRaw, expectedLocation, func(id applicationsecuritygroups.ApplicationSecurityGroupId) (string, error) {
+		resp, err := client.Get(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("retrieving %s: %+v", id, err)
+		}
 
-	for _, v := range input {
-		raw := v.(map[string]interface{})
+		if model := resp.Model; model != nil {
+			return pointer.From(model.Location), nil
+		}
 
-		dnsServers := utils.ExpandStringSlice(raw["dns_servers"].([]interface{}))
+		return "", nil
+	})
+}
 
-		ipConfigurations := make([]virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfiguration, 0)
-		ipConfigurationsRaw := raw["ip_configuration"].([]interface{})
+func validateVirtualMachineScaleSetApplicationSecurityGroupLocations(networkInterfacesRaw []interface{}, expectedLocation string, applicationSecurityGroupLocation func(id applicationsecuritygroups.ApplicationSecurityGroupId) (string, error)) error {
+	// the same Application Security Group is commonly referenced by multiple IP Configurations, so is only retrieved once
+	checked := make(map[string]struct{})
+	for _, v := range networkInterfacesRaw {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		ipConfigurationsRaw, _ := raw["ip_configuration"].([]interface{})
+		for _, configV := range ipConfigurationsRaw {
+			configRaw, ok := configV.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			applicationSecurityGroupIdsRaw, ok := configRaw["application_security_group_ids"].(*pluginsdk.Set)
+			if !ok {
+				continue
+			}
+
+			for _, idRaw := range applicationSecurityGroupIdsRaw.List() {
+				id, err := applicationsecuritygroups.ParseApplicationSecurityGroupIDInsensitively(idRaw.(string))
+				if err != nil {
+					return err
+				}
+
+				key := strings.ToLower(id.ID())
+				if _, ok := checked[key]; ok {
+					continue
+				}
+				checked[key] = struct{}{}
+
+				actual, err := applicationSecurityGroupLocation(*id)
+				if err != nil {
+					return err
+				}
+				if actual == "" {
+					continue
+				}
+
+				if location.Normalize(actual) != location.Normalize(expectedLocation) {
+					return fmt.Errorf("the Application Security Group %q referenced by the `ip_configuration` %q must be in the same location as the Scale Set (%q) but was in %q", id.ApplicationSecurityGroupName, configRaw["name"].(string), location.Normalize(expectedLocation), location.Normalize(actual))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetCapacityReservationPriorityCustomizeDiff ensures a Capacity Reservation Group isn't used with Spot
+// instances at plan time, since otherwise this is only rejected by the API once the Scale Set is being provisioned
+func virtualMachineScaleSetCapacityReservationPriorityCustomizeDiff(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+	return validateVirtualMachineScaleSetCapacityReservationPriority(diff.Get("capacity_reservation_group_id").(string), diff.Get("priority").(string))
+}
+
+func validateVirtualMachineScaleSetCapacityReservationPriority(capacityReservationGroupId string, priority string) error {
+	if capacityReservationGroupId != "" && strings.EqualFold(priority, string(virtualmachinescalesets.VirtualMachinePriorityTypesSpot)) {
+		return fmt.Errorf("`capacity_reservation_group_id` cannot be specified when `priority` is set to `Spot`, since Capacity Reservations can't be used with Spot instances")
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetCredentialsCustomizeDiff returns a CustomizeDiff function which ensures the credentials required by the
+// API for the specified OS Type are present, reporting all of the missing credentials at once rather than one per apply
+func virtualMachineScaleSetCredentialsCustomizeDiff(osType virtualmachinescalesets.OperatingSystemTypes) pluginsdk.CustomizeDiffFunc {
+	return func(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+		fields := []string{"admin_username", "admin_password"}
+		if osType == virtualmachinescalesets.OperatingSystemTypesLinux {
+			fields = append(fields, "admin_ssh_key", "disable_password_authentication")
+		}
+
+		// the credentials can't be checked until they're known, for example when the password comes from another resource
+		for _, field := range fields {
+			if !diff.NewValueKnown(field) {
+				return nil
+			}
+		}
+
+		credentials := virtualMachineScaleSetCredentials{
+			AdminUsername: diff.Get("admin_username").(string),
+			AdminPassword: diff.Get("admin_password").(string),
+		}
+		if osType == virtualmachinescalesets.OperatingSystemTypesLinux {
+			credentials.DisablePasswordAuthentication = diff.Get("disable_password_authentication").(bool)
+			credentials.SSHKeyCount = diff.Get("admin_ssh_key").(*pluginsdk.Set).Len()
+		}
+
+		return validateVirtualMachineScaleSetCredentials(osType, credentials)
+	}
+}
+
+type virtualMachineScaleSetCredentials struct {
+	AdminUsername                 string
+	AdminPassword                 string
+	DisablePasswordAuthentication bool
+	SSHKeyCount                   int
+}
+
+func validateVirtualMachineScaleSetCredentials(osType virtualmachinescalesets.OperatingSystemTypes, input virtualMachineScaleSetCredentials) error {
+	missing := make([]string, 0)
+	if input.AdminUsername == "" {
+		missing = append(missing, "`admin_username` must be specified")
+	}
+
+	switch osType {
+	case virtualmachinescalesets.OperatingSystemTypesWindows:
+		if input.AdminPassword == "" {
+			missing = append(missing, "`admin_password` must be specified")
+		}
+
+	case virtualmachinescalesets.OperatingSystemTypesLinux:
+		// Azure API: "Authentication using either SSH or by user name and password must be enabled in Linux profile."
+		if input.DisablePasswordAuthentication && input.AdminPassword == "" && input.SSHKeyCount == 0 {
+			missing = append(missing, "at least one `admin_ssh_key` must be specified when `disable_password_authentication` is set to `true`")
+		}
+		if !input.DisablePasswordAuthentication && input.AdminPassword == "" {
+			missing = append(missing, "`admin_password` must be specified when `disable_password_authentication` is set to `false`")
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("the credentials for the %s Virtual Machine Scale Set are incomplete: %s", string(osType), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetSinglePlacementGroupMaxCapacity is the maximum number of instances a Scale Set can contain
+// when it's limited to a Single Placement Group
+const virtualMachineScaleSetSinglePlacementGroupMaxCapacity = 100
+
+// validateVirtualMachineScaleSetSinglePlacementGroupCapacity ensures a Scale Set limited to a Single Placement Group isn't
+// scaled beyond the number of instances a Single Placement Group supports, rather than surfacing an API error
+func validateVirtualMachineScaleSetSinglePlacementGroupCapacity(capacity int, singlePlacementGroup bool) error {
+	if singlePlacementGroup && capacity > virtualMachineScaleSetSinglePlacementGroupMaxCapacity {
+		return fmt.Errorf("`single_placement_group` must be set to `false` when `instances` is greater than %d, but `instances` was set to %d", virtualMachineScaleSetSinglePlacementGroupMaxCapacity, capacity)
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetMaxLoadBalancers is the number of distinct Load Balancers which the Backend Address Pools of a Scale Set
+// can reference, since a Scale Set can be connected to at most one Public and one Internal Load Balancer
+const virtualMachineScaleSetMaxLoadBalancers = 2
+
+// virtualMachineScaleSetLoadBalancersCustomizeDiff checks the Load Balancers referenced by the Backend Address Pools at plan time
+// when the Scale Set is created or its Network Interfaces change, rather than failing once the Scale Set is being provisioned
+func virtualMachineScaleSetLoadBalancersCustomizeDiff(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+	if diff.Id() != "" && !diff.HasChange("network_interface") {
+		return nil
+	}
+
+	return validateVirtualMachineScaleSetLoadBalancerBackendAddressPools(diff.Get("network_interface").([]interface{}))
+}
+
+// validateVirtualMachineScaleSetLoadBalancerBackendAddressPools ensures the Backend Address Pools referenced across all of the
+// IP Configurations don't span more Load Balancers than a Scale Set can be connected to. IDs which can't be parsed, such as those
+// which aren't known until apply, are left to the API.
+func validateVirtualMachineScaleSetLoadBalancerBackendAddressPools(input []interface{}) error {
+	loadBalancerIds := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, v := range input {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		ipConfigurationsRaw, _ := raw["ip_configuration"].([]interface{})
 		for _, configV := range ipConfigurationsRaw {
+			configRaw, ok := configV.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			poolIdsRaw, ok := configRaw["load_balancer_backend_address_pool_ids"].(*pluginsdk.Set)
+			if !ok {
+				continue
+			}
+
+			for _, poolIdRaw := range poolIdsRaw.List() {
+				poolId, err := loadbalancers.ParseLoadBalancerBackendAddressPoolIDInsensitively(poolIdRaw.(string))
+				if err != nil {
+					continue
+				}
+
+				loadBalancerId := loadbalancers.NewProviderLoadBalancerID(poolId.SubscriptionId, poolId.ResourceGroupName, poolId.LoadBalancerName)
+				key := strings.ToLower(loadBalancerId.ID())
+				if _, exists := seen[key]; exists {
+					continue
+				}
+
+				seen[key] = struct{}{}
+				loadBalancerIds = append(loadBalancerIds, loadBalancerId.ID())
+			}
+		}
+	}
+
+	if len(loadBalancerIds) > virtualMachineScaleSetMaxLoadBalancers {
+		sort.Strings(loadBalancerIds)
+		return fmt.Errorf("the `load_balancer_backend_address_pool_ids` reference Backend Address Pools from %d different Load Balancers but a Virtual Machine Scale Set can only be connected to at most one Public and one Internal Load Balancer - the conflicting Load Balancers are: %s", len(loadBalancerIds), strings.Join(loadBalancerIds, ", "))
+	}
+
+	return nil
+}
+
+// ExpandVirtualMachineScaleSetNetworkInterface expands the Network Interfaces, the Public IP Address SKU is inherited from the Load
+// Balancer the Scale Set is connected to and is used to validate the Public IP Addresses - this is nil when it's not known
+func ExpandVirtualMachineScaleSetNetworkInterface(input []interface{}, publicIPAddressSku *virtualmachinescalesets.PublicIPAddressSku) (*[]virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration, error) {
+	output := make([]virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration, 0)
+
+	primaryCount := 0
+	for _, v := range input {
+		raw := v.(map[string]interface{})
+		if raw["primary"].(bool) {
+			primaryCount++
+		}
+
+		dnsServers := utils.ExpandStringSlice(raw["dns_servers"].([]interface{}))
+
+		ipConfigurations := make([]virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration, 0)
+		for _, configV := range raw["ip_configuration"].([]interface{}) {
 			configRaw := configV.(map[string]interface{})
-			ipConfiguration, err := expandVirtualMachineScaleSetIPConfigurationUpdate(configRaw)
+			ipConfiguration, err := expandVirtualMachineScaleSetIPConfiguration(configRaw, publicIPAddressSku)
 			if err != nil {
 				return nil, err
 			}
@@ -954,15 +1417,17 @@ func ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(input []interface{}) (*[
 			ipConfigurations = append(ipConfigurations, *ipConfiguration)
 		}
 
-		config := virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration{
-			Name: pointer.To(raw["name"].(string)),
-			Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfigurationProperties{
+		config := virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration{
+			Name: raw["name"].(string),
+			Properties: &virtualmachinescalesets.VirtualMachineScaleSetNetworkConfigurationProperties{
 				DnsSettings: &virtualmachinescalesets.VirtualMachineScaleSetNetworkConfigurationDnsSettings{
 					DnsServers: dnsServers,
 				},
+				DisableTcpStateTracking:     pointer.To(!raw["tcp_state_tracking_enabled"].(bool)),
 				EnableAcceleratedNetworking: pointer.To(raw["enable_accelerated_networking"].(bool)),
+				EnableFpga:                  pointer.To(raw["fpga_enabled"].(bool)),
 				EnableIPForwarding:          pointer.To(raw["enable_ip_forwarding"].(bool)),
-				IPConfigurations:            &ipConfigurations,
+				IPConfigurations:            ipConfigurations,
 				Primary:                     pointer.To(raw["primary"].(bool)),
 			},
 		}
@@ -976,10 +1441,80 @@ func ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(input []interface{}) (*[
 		output = append(output, config)
 	}
 
+	if len(output) > 1 && primaryCount != 1 {
+		return nil, fmt.Errorf("exactly one `network_interface` must be marked as `primary` when multiple are specified but got %d", primaryCount)
+	}
+
 	return &output, nil
 }
 
-func expandVirtualMachineScaleSetIPConfigurationUpdate(raw map[string]interface{}) (*virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfiguration, error) {
+// virtualMachineScaleSetIPConfigurationPrimaryCustomizeDiff ensures that from 4.0 a Network Interface with a single IP Configuration
+// has it marked as `primary` when planning, rather than this being rejected by the API during the apply.
+func virtualMachineScaleSetIPConfigurationPrimaryCustomizeDiff(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+	if !features.FourPointOhBeta() {
+		return nil
+	}
+
+	if diff.Id() != "" && !diff.HasChange("network_interface") {
+		return nil
+	}
+
+	for _, v := range diff.Get("network_interface").([]interface{}) {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		networkInterfaceName, _ := raw["name"].(string)
+		ipConfigurationsRaw, _ := raw["ip_configuration"].([]interface{})
+		if err := validateVirtualMachineScaleSetSingleIPConfigurationPrimary(networkInterfaceName, ipConfigurationsRaw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateVirtualMachineScaleSetSingleIPConfigurationPrimary ensures that when a Network Interface has a single IP Configuration
+// it's marked as `primary`, since the API otherwise rejects the Network Interface. This isn't set implicitly since `primary`
+// defaults to `false`, which would then cause a diff once the API returns it as `true`.
+func validateVirtualMachineScaleSetSingleIPConfigurationPrimary(networkInterfaceName string, ipConfigurationsRaw []interface{}) error {
+	if len(ipConfigurationsRaw) != 1 {
+		return nil
+	}
+
+	raw, ok := ipConfigurationsRaw[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if primary, _ := raw["primary"].(bool); !primary {
+		name, _ := raw["name"].(string)
+		return fmt.Errorf("the `ip_configuration` %q within the `network_interface` %q must have `primary` set to `true` since it's the only IP Configuration", name, networkInterfaceName)
+	}
+
+	return nil
+}
+
+// validateVirtualMachineScaleSetIPConfigurationAllocation ensures the IP Configuration can be allocated a private IP Address. The
+// Compute API version used here doesn't expose a private IP allocation method or a static private IP Address for Scale Set IP
+// Configurations - IPv4 addresses are always dynamically allocated from the Subnet - as such from 4.0 an IPv4 IP Configuration
+// must specify the `subnet_id` which the address is allocated from. Prior to 4.0 this is left to the API, since existing
+// configurations may omit it.
+func validateVirtualMachineScaleSetIPConfigurationAllocation(raw map[string]interface{}) error {
+	if !features.FourPointOhBeta() {
+		return nil
+	}
+
+	version := virtualmachinescalesets.IPVersion(raw["version"].(string))
+	if version == virtualmachinescalesets.IPVersionIPvFour && raw["subnet_id"].(string) == "" {
+		return fmt.Errorf("`subnet_id` must be specified for the `ip_configuration` %q since it uses `IPv4`", raw["name"].(string))
+	}
+
+	return nil
+}
+
+func expandVirtualMachineScaleSetIPConfiguration(raw map[string]interface{}, publicIPAddressSku *virtualmachinescalesets.PublicIPAddressSku) (*virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration, error) {
 	applicationGatewayBackendAddressPoolIdsRaw := raw["application_gateway_backend_address_pool_ids"].(*pluginsdk.Set).List()
 	applicationGatewayBackendAddressPoolIds := expandIDsToSubResources(applicationGatewayBackendAddressPoolIdsRaw)
 
@@ -994,14 +1529,16 @@ func expandVirtualMachineScaleSetIPConfigurationUpdate(raw map[string]interface{
 
 	primary := raw["primary"].(bool)
 	version := virtualmachinescalesets.IPVersion(raw["version"].(string))
-
 	if primary && version == virtualmachinescalesets.IPVersionIPvSix {
 		return nil, fmt.Errorf("an IPv6 Primary IP Configuration is unsupported - instead add a IPv4 IP Configuration as the Primary and make the IPv6 IP Configuration the secondary")
 	}
+	if err := validateVirtualMachineScaleSetIPConfigurationAllocation(raw); err != nil {
+		return nil, err
+	}
 
-	ipConfiguration := virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfiguration{
-		Name: pointer.To(raw["name"].(string)),
-		Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfigurationProperties{
+	ipConfiguration := virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration{
+		Name: raw["name"].(string),
+		Properties: &virtualmachinescalesets.VirtualMachineScaleSetIPConfigurationProperties{
 			Primary:                               pointer.To(primary),
 			PrivateIPAddressVersion:               pointer.To(version),
 			ApplicationGatewayBackendAddressPools: applicationGatewayBackendAddressPoolIds,
@@ -1020,26 +1557,72 @@ func expandVirtualMachineScaleSetIPConfigurationUpdate(raw map[string]interface{
 	publicIPConfigsRaw := raw["public_ip_address"].([]interface{})
 	if len(publicIPConfigsRaw) > 0 {
 		publicIPConfigRaw := publicIPConfigsRaw[0].(map[string]interface{})
-		publicIPAddressConfig := expandVirtualMachineScaleSetPublicIPAddressUpdate(publicIPConfigRaw)
+		publicIPAddressConfig, err := expandVirtualMachineScaleSetPublicIPAddress(publicIPConfigRaw, publicIPAddressSku)
+		if err != nil {
+			return nil, err
+		}
 		ipConfiguration.Properties.PublicIPAddressConfiguration = publicIPAddressConfig
 	}
 
 	return &ipConfiguration, nil
 }
 
-func expandVirtualMachineScaleSetPublicIPAddressUpdate(raw map[string]interface{}) *virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration {
-	publicIPAddressConfig := virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration{
-		Name:       pointer.To(raw["name"].(string)),
-		Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfigurationProperties{},
-	}
+// virtualMachineScaleSetIPv6LoadBalancerWarnings returns a warning for each IPv6 IP Configuration which references a Load Balancer
+// Backend Address Pool, since this requires a Standard SKU Load Balancer with an IPv6 Frontend and otherwise only fails once the
+// instances are provisioned
+func virtualMachineScaleSetIPv6LoadBalancerWarnings(networkInterfacesRaw []interface{}) diag.Diagnostics {
+	var warnings diag.Diagnostics
+	for _, networkInterfaceRaw := range networkInterfacesRaw {
+		for _, v := range networkInterfaceRaw.(map[string]interface{})["ip_configuration"].([]interface{}) {
+			raw := v.(map[string]interface{})
+			if virtualmachinescalesets.IPVersion(raw["version"].(string)) != virtualmachinescalesets.IPVersionIPvSix {
+				continue
+			}
 
-	if domainNameLabel := raw["domain_name_label"].(string); domainNameLabel != "" {
-		dns := &virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationDnsSettings{
-			DomainNameLabel: domainNameLabel,
+			if raw["load_balancer_backend_address_pool_ids"].(*pluginsdk.Set).Len() == 0 {
+				continue
+			}
+
+			warnings = append(warnings, virtualMachineScaleSetWarning("IPv6 IP Configuration references a Load Balancer", fmt.Sprintf("the IPv6 `ip_configuration` %q references a Load Balancer Backend Address Pool - the Load Balancer must use the `Standard` SKU and have an IPv6 Frontend IP Configuration, otherwise provisioning the instances will fail", raw["name"].(string))))
 		}
-		publicIPAddressConfig.Properties.DnsSettings = dns
 	}
 
+	return warnings
+}
+
+func expandVirtualMachineScaleSetPublicIPAddress(raw map[string]interface{}, sku *virtualmachinescalesets.PublicIPAddressSku) (*virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration, error) {
+	ipTagsRaw := raw["ip_tag"].([]interface{})
+	if err := validateVirtualMachineScaleSetPublicIPAddressIPTags(raw["name"].(string), ipTagsRaw, sku); err != nil {
+		return nil, err
+	}
+
+	ipTags := make([]virtualmachinescalesets.VirtualMachineScaleSetIPTag, 0)
+	for _, ipTagV := range ipTagsRaw {
+		ipTagRaw := ipTagV.(map[string]interface{})
+		ipTags = append(ipTags, virtualmachinescalesets.VirtualMachineScaleSetIPTag{
+			Tag:       pointer.To(ipTagRaw["tag"].(string)),
+			IPTagType: pointer.To(ipTagRaw["type"].(string)),
+		})
+	}
+
+	publicIPAddressConfig := virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration{
+		Name: raw["name"].(string),
+		Properties: &virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationProperties{
+			IPTags:                 &ipTags,
+			PublicIPAddressVersion: pointer.To(virtualmachinescalesets.IPVersion(raw["version"].(string))),
+		},
+	}
+
+	if deleteOption := raw["delete_option"].(string); deleteOption != "" {
+		publicIPAddressConfig.Properties.DeleteOption = pointer.To(virtualmachinescalesets.DeleteOptions(deleteOption))
+	}
+
+	dnsSettings, err := expandVirtualMachineScaleSetPublicIPAddressDnsSettings(raw)
+	if err != nil {
+		return nil, err
+	}
+	publicIPAddressConfig.Properties.DnsSettings = dnsSettings
+
 	if idleTimeout := raw["idle_timeout_in_minutes"].(int); idleTimeout > 0 {
 		publicIPAddressConfig.Properties.IdleTimeoutInMinutes = pointer.To(int64(raw["idle_timeout_in_minutes"].(int)))
 	}
@@ -1050,78 +1633,384 @@ func expandVirtualMachineScaleSetPublicIPAddressUpdate(raw map[string]interface{
 		}
 	}
 
-	return &publicIPAddressConfig
+	return &publicIPAddressConfig, nil
 }
 
-func FlattenVirtualMachineScaleSetNetworkInterface(input *[]virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration) []interface{} {
-	if input == nil {
-		return []interface{}{}
+// validateVirtualMachineScaleSetPublicIPAddressIPTags ensures `ip_tag` is only specified when the Public IP Addresses use the Standard
+// SKU, since IP Tags aren't supported for Basic SKU Public IP Addresses. When the SKU isn't known this is left to the API.
+func validateVirtualMachineScaleSetPublicIPAddressIPTags(name string, ipTagsRaw []interface{}, sku *virtualmachinescalesets.PublicIPAddressSku) error {
+	if len(ipTagsRaw) == 0 || sku == nil {
+		return nil
 	}
 
-	results := make([]interface{}, 0)
-	for _, v := range *input {
-		var networkSecurityGroupId string
-		var enableAcceleratedNetworking, enableIPForwarding, primary bool
-		var dnsServers, ipConfigurations []interface{}
-		if props := v.Properties; props != nil {
-			if props.NetworkSecurityGroup != nil && props.NetworkSecurityGroup.Id != nil {
-				networkSecurityGroupId = *props.NetworkSecurityGroup.Id
-			}
-			if props.EnableAcceleratedNetworking != nil {
-				enableAcceleratedNetworking = *props.EnableAcceleratedNetworking
-			}
-			if props.EnableIPForwarding != nil {
-				enableIPForwarding = *props.EnableIPForwarding
+	if pointer.From(sku.Name) == virtualmachinescalesets.PublicIPAddressSkuNameBasic {
+		return fmt.Errorf("`ip_tag` cannot be specified for the `public_ip_address` %q since the Public IP Addresses of the Scale Set use the `Basic` SKU (which is inherited from the Load Balancer the Scale Set is connected to) - IP Tags require a `Standard` SKU", name)
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetPublicIPAddressSku determines the SKU of the Public IP Addresses of the Scale Set when `ip_tag` is specified,
+// which for a Uniform Scale Set is inherited from the SKU of the Load Balancers it's connected to. Since this requires retrieving the
+// Load Balancers it's only done when Enhanced Validation is enabled - otherwise nil is returned and the validation is left to the API.
+func virtualMachineScaleSetPublicIPAddressSku(ctx context.Context, client *loadbalancers.LoadBalancersClient, networkInterfacesRaw []interface{}) (*virtualmachinescalesets.PublicIPAddressSku, error) {
+	if !features.EnhancedValidationEnabled() {
+		return nil, nil
+	}
+
+	ipTagsSpecified := false
+	loadBalancerIds := make([]loadbalancers.ProviderLoadBalancerId, 0)
+	seen := make(map[string]struct{})
+	for _, v := range networkInterfacesRaw {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		ipConfigurationsRaw, _ := raw["ip_configuration"].([]interface{})
+		for _, configV := range ipConfigurationsRaw {
+			configRaw, ok := configV.(map[string]interface{})
+			if !ok {
+				continue
 			}
-			if props.Primary != nil {
-				primary = *props.Primary
+
+			if publicIPConfigsRaw, _ := configRaw["public_ip_address"].([]interface{}); len(publicIPConfigsRaw) > 0 && publicIPConfigsRaw[0] != nil {
+				if ipTagsRaw, _ := publicIPConfigsRaw[0].(map[string]interface{})["ip_tag"].([]interface{}); len(ipTagsRaw) > 0 {
+					ipTagsSpecified = true
+				}
 			}
 
-			if settings := props.DnsSettings; settings != nil {
-				dnsServers = utils.FlattenStringSlice(props.DnsSettings.DnsServers)
+			poolIdsRaw, ok := configRaw["load_balancer_backend_address_pool_ids"].(*pluginsdk.Set)
+			if !ok {
+				continue
 			}
+			for _, poolIdRaw := range poolIdsRaw.List() {
+				poolId, err := loadbalancers.ParseLoadBalancerBackendAddressPoolIDInsensitively(poolIdRaw.(string))
+				if err != nil {
+					return nil, err
+				}
 
-			for _, configRaw := range props.IPConfigurations {
-				config := flattenVirtualMachineScaleSetIPConfiguration(configRaw)
-				ipConfigurations = append(ipConfigurations, config)
+				loadBalancerId := loadbalancers.NewProviderLoadBalancerID(poolId.SubscriptionId, poolId.ResourceGroupName, poolId.LoadBalancerName)
+				key := strings.ToLower(loadBalancerId.ID())
+				if _, exists := seen[key]; exists {
+					continue
+				}
+				seen[key] = struct{}{}
+				loadBalancerIds = append(loadBalancerIds, loadBalancerId)
 			}
+		}
+	}
 
-			results = append(results, map[string]interface{}{
-				"name":                          v.Name,
-				"dns_servers":                   dnsServers,
-				"enable_accelerated_networking": enableAcceleratedNetworking,
-				"enable_ip_forwarding":          enableIPForwarding,
-				"ip_configuration":              ipConfigurations,
-				"network_security_group_id":     networkSecurityGroupId,
-				"primary":                       primary,
-			})
+	if !ipTagsSpecified || len(loadBalancerIds) == 0 {
+		return nil, nil
+	}
+
+	loadBalancers := make([]loadbalancers.LoadBalancer, 0, len(loadBalancerIds))
+	for _, loadBalancerId := range loadBalancerIds {
+		resp, err := client.Get(ctx, loadBalancerId, loadbalancers.DefaultGetOperationOptions())
+		if err != nil {
+			return nil, fmt.Errorf("retrieving %s to determine the SKU of the Public IP Addresses: %+v", loadBalancerId, err)
+		}
+		if resp.Model != nil {
+			loadBalancers = append(loadBalancers, *resp.Model)
 		}
 	}
 
-	return results
+	return virtualMachineScaleSetPublicIPAddressSkuForLoadBalancers(loadBalancers)
 }
 
-func flattenVirtualMachineScaleSetIPConfiguration(input virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration) map[string]interface{} {
-	var subnetId string
-	var primary bool
-	var publicIPAddresses []interface{}
+// virtualMachineScaleSetPublicIPAddressSkuForLoadBalancers returns the SKU of the Public IP Addresses inherited from the Load Balancers
+// the Scale Set is connected to - since a Scale Set can't be connected to both `Basic` and `Standard` Load Balancers an error is
+// returned when their SKUs differ, and nil is returned when none of the SKUs are known
+func virtualMachineScaleSetPublicIPAddressSkuForLoadBalancers(input []loadbalancers.LoadBalancer) (*virtualmachinescalesets.PublicIPAddressSku, error) {
+	var output *virtualmachinescalesets.PublicIPAddressSku
+	var outputLoadBalancer loadbalancers.LoadBalancer
+	for _, loadBalancer := range input {
+		if loadBalancer.Sku == nil || loadBalancer.Sku.Name == nil {
+			continue
+		}
 
-	if props := input.Properties; props != nil {
-		if props.Subnet != nil && props.Subnet.Id != nil {
-			subnetId = *props.Subnet.Id
+		skuName := virtualmachinescalesets.PublicIPAddressSkuNameStandard
+		if *loadBalancer.Sku.Name == loadbalancers.LoadBalancerSkuNameBasic {
+			skuName = virtualmachinescalesets.PublicIPAddressSkuNameBasic
 		}
 
-		if props.Primary != nil {
-			primary = *props.Primary
+		if output != nil && pointer.From(output.Name) != skuName {
+			return nil, fmt.Errorf("the Load Balancers %q (SKU %q) and %q (SKU %q) referenced by `load_balancer_backend_address_pool_ids` use different SKUs - a Virtual Machine Scale Set can't be connected to both `Basic` and `Standard` Load Balancers", pointer.From(outputLoadBalancer.Id), string(*outputLoadBalancer.Sku.Name), pointer.From(loadBalancer.Id), string(*loadBalancer.Sku.Name))
+		}
+
+		output = &virtualmachinescalesets.PublicIPAddressSku{
+			Name: pointer.To(skuName),
+		}
+		outputLoadBalancer = loadBalancer
+	}
+
+	return output, nil
+}
+
+// expandVirtualMachineScaleSetPublicIPAddressDnsSettings expands the DNS Settings for a Public IP Address, a
+// `domain_name_label_scope` is only meaningful alongside a `domain_name_label` and is otherwise rejected.
+func expandVirtualMachineScaleSetPublicIPAddressDnsSettings(raw map[string]interface{}) (*virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationDnsSettings, error) {
+	domainNameLabel := raw["domain_name_label"].(string)
+	domainNameLabelScope, _ := raw["domain_name_label_scope"].(string)
+
+	if domainNameLabel == "" {
+		if domainNameLabelScope != "" {
+			return nil, fmt.Errorf("`domain_name_label_scope` can only be specified when `domain_name_label` is specified for the `public_ip_address` %q", raw["name"].(string))
+		}
+		return nil, nil
+	}
+
+	dns := &virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationDnsSettings{
+		DomainNameLabel: domainNameLabel,
+	}
+	if domainNameLabelScope != "" {
+		dns.DomainNameLabelScope = pointer.To(virtualmachinescalesets.DomainNameLabelScopeTypes(domainNameLabelScope))
+	}
+
+	return dns, nil
+}
+
+func ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(input []interface{}, publicIPAddressSku *virtualmachinescalesets.PublicIPAddressSku) (*[]virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration, error) {
+	output := make([]virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration, 0)
+
+	primaryCount := 0
+	for _, v := range input {
+		raw := v.(map[string]interface{})
+		if raw["primary"].(bool) {
+			primaryCount++
+		}
+
+		dnsServers := utils.ExpandStringSlice(raw["dns_servers"].([]interface{}))
+
+		ipConfigurations := make([]virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfiguration, 0)
+		for _, configV := range raw["ip_configuration"].([]interface{}) {
+			configRaw := configV.(map[string]interface{})
+			ipConfiguration, err := expandVirtualMachineScaleSetIPConfigurationUpdate(configRaw, publicIPAddressSku)
+			if err != nil {
+				return nil, err
+			}
+
+			ipConfigurations = append(ipConfigurations, *ipConfiguration)
+		}
+
+		config := virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfiguration{
+			Name: pointer.To(raw["name"].(string)),
+			Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkConfigurationProperties{
+				DnsSettings: &virtualmachinescalesets.VirtualMachineScaleSetNetworkConfigurationDnsSettings{
+					DnsServers: dnsServers,
+				},
+				DisableTcpStateTracking:     pointer.To(!raw["tcp_state_tracking_enabled"].(bool)),
+				EnableAcceleratedNetworking: pointer.To(raw["enable_accelerated_networking"].(bool)),
+				EnableFpga:                  pointer.To(raw["fpga_enabled"].(bool)),
+				EnableIPForwarding:          pointer.To(raw["enable_ip_forwarding"].(bool)),
+				IPConfigurations:            &ipConfigurations,
+				Primary:                     pointer.To(raw["primary"].(bool)),
+			},
+		}
+
+		if nsgId := raw["network_security_group_id"].(string); nsgId != "" {
+			config.Properties.NetworkSecurityGroup = &virtualmachinescalesets.SubResource{
+				Id: pointer.To(nsgId),
+			}
+		}
+
+		output = append(output, config)
+	}
+
+	if len(output) > 1 && primaryCount != 1 {
+		return nil, fmt.Errorf("exactly one `network_interface` must be marked as `primary` when multiple are specified but got %d", primaryCount)
+	}
+
+	return &output, nil
+}
+
+func expandVirtualMachineScaleSetIPConfigurationUpdate(raw map[string]interface{}, publicIPAddressSku *virtualmachinescalesets.PublicIPAddressSku) (*virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfiguration, error) {
+	applicationGatewayBackendAddressPoolIdsRaw := raw["application_gateway_backend_address_pool_ids"].(*pluginsdk.Set).List()
+	applicationGatewayBackendAddressPoolIds := expandIDsToSubResources(applicationGatewayBackendAddressPoolIdsRaw)
+
+	applicationSecurityGroupIdsRaw := raw["application_security_group_ids"].(*pluginsdk.Set).List()
+	applicationSecurityGroupIds := expandIDsToSubResources(applicationSecurityGroupIdsRaw)
+
+	loadBalancerBackendAddressPoolIdsRaw := raw["load_balancer_backend_address_pool_ids"].(*pluginsdk.Set).List()
+	loadBalancerBackendAddressPoolIds := expandIDsToSubResources(loadBalancerBackendAddressPoolIdsRaw)
+
+	loadBalancerInboundNatPoolIdsRaw := raw["load_balancer_inbound_nat_rules_ids"].(*pluginsdk.Set).List()
+	loadBalancerInboundNatPoolIds := expandIDsToSubResources(loadBalancerInboundNatPoolIdsRaw)
+
+	primary := raw["primary"].(bool)
+	version := virtualmachinescalesets.IPVersion(raw["version"].(string))
+
+	if primary && version == virtualmachinescalesets.IPVersionIPvSix {
+		return nil, fmt.Errorf("an IPv6 Primary IP Configuration is unsupported - instead add a IPv4 IP Configuration as the Primary and make the IPv6 IP Configuration the secondary")
+	}
+	if err := validateVirtualMachineScaleSetIPConfigurationAllocation(raw); err != nil {
+		return nil, err
+	}
+
+	ipConfiguration := virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfiguration{
+		Name: pointer.To(raw["name"].(string)),
+		Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdateIPConfigurationProperties{
+			Primary:                               pointer.To(primary),
+			PrivateIPAddressVersion:               pointer.To(version),
+			ApplicationGatewayBackendAddressPools: applicationGatewayBackendAddressPoolIds,
+			ApplicationSecurityGroups:             applicationSecurityGroupIds,
+			LoadBalancerBackendAddressPools:       loadBalancerBackendAddressPoolIds,
+			LoadBalancerInboundNatPools:           loadBalancerInboundNatPoolIds,
+		},
+	}
+
+	if subnetId := raw["subnet_id"].(string); subnetId != "" {
+		ipConfiguration.Properties.Subnet = &virtualmachinescalesets.ApiEntityReference{
+			Id: pointer.To(subnetId),
+		}
+	}
+
+	publicIPConfigsRaw := raw["public_ip_address"].([]interface{})
+	if len(publicIPConfigsRaw) > 0 {
+		publicIPConfigRaw := publicIPConfigsRaw[0].(map[string]interface{})
+		publicIPAddressConfig, err := expandVirtualMachineScaleSetPublicIPAddressUpdate(publicIPConfigRaw, publicIPAddressSku)
+		if err != nil {
+			return nil, err
+		}
+		ipConfiguration.Properties.PublicIPAddressConfiguration = publicIPAddressConfig
+	}
+
+	return &ipConfiguration, nil
+}
+
+func expandVirtualMachineScaleSetPublicIPAddressUpdate(raw map[string]interface{}, sku *virtualmachinescalesets.PublicIPAddressSku) (*virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration, error) {
+	if err := validateVirtualMachineScaleSetPublicIPAddressIPTags(raw["name"].(string), raw["ip_tag"].([]interface{}), sku); err != nil {
+		return nil, err
+	}
+
+	publicIPAddressConfig := virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfiguration{
+		Name:       pointer.To(raw["name"].(string)),
+		Properties: &virtualmachinescalesets.VirtualMachineScaleSetUpdatePublicIPAddressConfigurationProperties{},
+	}
+
+	if deleteOption := raw["delete_option"].(string); deleteOption != "" {
+		publicIPAddressConfig.Properties.DeleteOption = pointer.To(virtualmachinescalesets.DeleteOptions(deleteOption))
+	}
+
+	dnsSettings, err := expandVirtualMachineScaleSetPublicIPAddressDnsSettings(raw)
+	if err != nil {
+		return nil, err
+	}
+	publicIPAddressConfig.Properties.DnsSettings = dnsSettings
+
+	if idleTimeout := raw["idle_timeout_in_minutes"].(int); idleTimeout > 0 {
+		publicIPAddressConfig.Properties.IdleTimeoutInMinutes = pointer.To(int64(raw["idle_timeout_in_minutes"].(int)))
+	}
+
+	if publicIPPrefixID := raw["public_ip_prefix_id"].(string); publicIPPrefixID != "" {
+		publicIPAddressConfig.Properties.PublicIPPrefix = &virtualmachinescalesets.SubResource{
+			Id: pointer.To(publicIPPrefixID),
+		}
+	}
+
+	return &publicIPAddressConfig, nil
+}
+
+func FlattenVirtualMachineScaleSetNetworkInterface(input *[]virtualmachinescalesets.VirtualMachineScaleSetNetworkConfiguration) []interface{} {
+	if input == nil {
+		return []interface{}{}
+	}
+
+	results := make([]interface{}, 0)
+	for _, v := range *input {
+		var networkSecurityGroupId string
+		var enableAcceleratedNetworking, enableFpga, enableIPForwarding, primary bool
+		// TCP State Tracking is enabled unless it's explicitly disabled
+		tcpStateTrackingEnabled := true
+		var dnsServers, ipConfigurations []interface{}
+		if props := v.Properties; props != nil {
+			if props.DisableTcpStateTracking != nil {
+				tcpStateTrackingEnabled = !*props.DisableTcpStateTracking
+			}
+			if props.NetworkSecurityGroup != nil && props.NetworkSecurityGroup.Id != nil {
+				networkSecurityGroupId = *props.NetworkSecurityGroup.Id
+			}
+			if props.EnableAcceleratedNetworking != nil {
+				enableAcceleratedNetworking = *props.EnableAcceleratedNetworking
+			}
+			if props.EnableFpga != nil {
+				enableFpga = *props.EnableFpga
+			}
+			if props.EnableIPForwarding != nil {
+				enableIPForwarding = *props.EnableIPForwarding
+			}
+			if props.Primary != nil {
+				primary = *props.Primary
+			}
+
+			if settings := props.DnsSettings; settings != nil {
+				dnsServers = utils.FlattenStringSlice(props.DnsSettings.DnsServers)
+			}
+
+			for _, configRaw := range props.IPConfigurations {
+				config := flattenVirtualMachineScaleSetIPConfiguration(configRaw)
+				ipConfigurations = append(ipConfigurations, config)
+			}
+
+			results = append(results, map[string]interface{}{
+				"name":                          v.Name,
+				"dns_servers":                   dnsServers,
+				"enable_accelerated_networking": enableAcceleratedNetworking,
+				"enable_ip_forwarding":          enableIPForwarding,
+				"fpga_enabled":                  enableFpga,
+				"ip_configuration":              ipConfigurations,
+				"network_security_group_id":     networkSecurityGroupId,
+				"primary":                       primary,
+				"tcp_state_tracking_enabled":    tcpStateTrackingEnabled,
+			})
+		}
+	}
+
+	return results
+}
+
+func flattenVirtualMachineScaleSetIPConfiguration(input virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration) map[string]interface{} {
+	var subnetId string
+	var primary bool
+	var publicIPAddresses []interface{}
+
+	if props := input.Properties; props != nil {
+		if props.Subnet != nil && props.Subnet.Id != nil {
+			subnetId = *props.Subnet.Id
+		}
+
+		if props.Primary != nil {
+			primary = *props.Primary
 		}
 
 		if props.PublicIPAddressConfiguration != nil {
 			publicIPAddresses = append(publicIPAddresses, flattenVirtualMachineScaleSetPublicIPAddress(*props.PublicIPAddressConfiguration))
 		}
 
-		applicationGatewayBackendAddressPoolIds := flattenSubResourcesToIDs(props.ApplicationGatewayBackendAddressPools)
-		applicationSecurityGroupIds := flattenSubResourcesToIDs(props.ApplicationSecurityGroups)
-		loadBalancerBackendAddressPoolIds := flattenSubResourcesToIDs(props.LoadBalancerBackendAddressPools)
+		// the API can return these IDs in a different casing to which they were sent, so these are normalized to ensure the
+		// members of these sets match the configuration
+		applicationGatewayBackendAddressPoolIds := normalizeVirtualMachineScaleSetSubResourceIDs(flattenSubResourcesToIDs(props.ApplicationGatewayBackendAddressPools), func(input string) (string, error) {
+			id, err := networkParse.ParseApplicationGatewayBackendAddressPoolIDInsensitively(input)
+			if err != nil {
+				return "", err
+			}
+			return id.ID(), nil
+		})
+		applicationSecurityGroupIds := normalizeVirtualMachineScaleSetSubResourceIDs(flattenSubResourcesToIDs(props.ApplicationSecurityGroups), func(input string) (string, error) {
+			id, err := applicationsecuritygroups.ParseApplicationSecurityGroupIDInsensitively(input)
+			if err != nil {
+				return "", err
+			}
+			return id.ID(), nil
+		})
+		loadBalancerBackendAddressPoolIds := normalizeVirtualMachineScaleSetSubResourceIDs(flattenSubResourcesToIDs(props.LoadBalancerBackendAddressPools), func(input string) (string, error) {
+			id, err := loadbalancers.ParseLoadBalancerBackendAddressPoolIDInsensitively(input)
+			if err != nil {
+				return "", err
+			}
+			return id.ID(), nil
+		})
 		loadBalancerInboundNatRuleIds := flattenSubResourcesToIDs(props.LoadBalancerInboundNatPools)
 
 		return map[string]interface{}{
@@ -1139,9 +2028,24 @@ func flattenVirtualMachineScaleSetIPConfiguration(input virtualmachinescalesets.
 	return map[string]interface{}{}
 }
 
+// normalizeVirtualMachineScaleSetSubResourceIDs returns the IDs in the casing returned by the parse function, any IDs which can't
+// be parsed are returned as-is
+func normalizeVirtualMachineScaleSetSubResourceIDs(input []interface{}, parse func(input string) (string, error)) []interface{} {
+	output := make([]interface{}, 0, len(input))
+	for _, v := range input {
+		id := v.(string)
+		if normalized, err := parse(id); err == nil {
+			id = normalized
+		}
+		output = append(output, id)
+	}
+
+	return output
+}
+
 func flattenVirtualMachineScaleSetPublicIPAddress(input virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration) map[string]interface{} {
 	ipTags := make([]interface{}, 0)
-	var domainNameLabel, publicIPPrefixId, version string
+	var deleteOption, domainNameLabel, domainNameLabelScope, publicIPPrefixId, version string
 	var idleTimeoutInMinutes int
 
 	if props := input.Properties; props != nil {
@@ -1163,8 +2067,13 @@ func flattenVirtualMachineScaleSetPublicIPAddress(input virtualmachinescalesets.
 				})
 			}
 		}
+		if props.DeleteOption != nil {
+			deleteOption = string(*props.DeleteOption)
+		}
+
 		if props.DnsSettings != nil {
 			domainNameLabel = props.DnsSettings.DomainNameLabel
+			domainNameLabelScope = string(pointer.From(props.DnsSettings.DomainNameLabelScope))
 		}
 
 		if props.PublicIPPrefix != nil && props.PublicIPPrefix.Id != nil {
@@ -1182,7 +2091,9 @@ func flattenVirtualMachineScaleSetPublicIPAddress(input virtualmachinescalesets.
 
 	return map[string]interface{}{
 		"name":                    input.Name,
+		"delete_option":           deleteOption,
 		"domain_name_label":       domainNameLabel,
+		"domain_name_label_scope": domainNameLabelScope,
 		"idle_timeout_in_minutes": idleTimeoutInMinutes,
 		"ip_tag":                  ipTags,
 		"public_ip_prefix_id":     publicIPPrefixId,
@@ -1280,20 +2191,46 @@ func VirtualMachineScaleSetDataDiskSchema() *pluginsdk.Schema {
 					ValidateFunc: validation.IntAtLeast(1),
 					Computed:     true,
 				},
+
+				// these are aliases of the `ultra_ssd_*` fields above ahead of their rename in 4.0, since these also apply to
+				// `PremiumV2_LRS` disks. These intentionally aren't Computed, so that we can tell which of the fields is used
+				"disk_iops_read_write": {
+					Type:         pluginsdk.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+
+				"disk_mbps_read_write": {
+					Type:         pluginsdk.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
 			},
 		},
 	}
 }
 
-func ExpandVirtualMachineScaleSetDataDisk(input []interface{}, ultraSSDEnabled bool) (*[]virtualmachinescalesets.VirtualMachineScaleSetDataDisk, error) {
+func ExpandVirtualMachineScaleSetDataDisk(input []interface{}, ultraSSDEnabled bool, zones []string) (*[]virtualmachinescalesets.VirtualMachineScaleSetDataDisk, error) {
 	disks := make([]virtualmachinescalesets.VirtualMachineScaleSetDataDisk, 0)
 
 	for _, v := range input {
 		raw := v.(map[string]interface{})
 
 		storageAccountType := virtualmachinescalesets.StorageAccountTypes(raw["storage_account_type"].(string))
+		// from 4.0 a zone-redundant Data Disk is rejected when the Scale Set is pinned to a single zone, since existing
+		// configurations can't be broken in a minor release
+		isZoneRedundant := storageAccountType == virtualmachinescalesets.StorageAccountTypesPremiumZRS || storageAccountType == virtualmachinescalesets.StorageAccountTypesStandardSSDZRS
+		if features.FourPointOhBeta() && isZoneRedundant && len(zones) == 1 {
+			return nil, fmt.Errorf("the Data Disk with `lun` %d cannot use the zone-redundant `storage_account_type` %q when the Virtual Machine Scale Set is pinned to a single zone (%q)", raw["lun"].(int), string(storageAccountType), zones[0])
+		}
+
+		caching := raw["caching"].(string)
+		if raw["write_accelerator_enabled"].(bool) && caching == string(virtualmachinescalesets.CachingTypesReadWrite) {
+			return nil, fmt.Errorf("`write_accelerator_enabled` can only be enabled on the Data Disk with `lun` %d when `caching` is set to `None` or `ReadOnly`", raw["lun"].(int))
+		}
+
 		disk := virtualmachinescalesets.VirtualMachineScaleSetDataDisk{
-			Caching:    pointer.To(virtualmachinescalesets.CachingTypes(raw["caching"].(string))),
+			Caching:    pointer.To(virtualmachinescalesets.CachingTypes(caching)),
 			DiskSizeGB: pointer.To(int64(raw["disk_size_gb"].(int))),
 			Lun:        int64(raw["lun"].(int)),
 			ManagedDisk: &virtualmachinescalesets.VirtualMachineScaleSetManagedDiskParameters{
@@ -1313,22 +2250,33 @@ func ExpandVirtualMachineScaleSetDataDisk(input []interface{}, ultraSSDEnabled b
 			}
 		}
 
+		// the `disk_*` aliases take precedence since the `ultra_ssd_*` fields are Computed, and so are populated from the state
+		iopsField := "ultra_ssd_disk_iops_read_write"
 		var iops int
 		if diskIops, ok := raw["ultra_ssd_disk_iops_read_write"]; ok && diskIops.(int) > 0 {
 			iops = diskIops.(int)
 		}
+		if diskIops, ok := raw["disk_iops_read_write"]; ok && diskIops.(int) > 0 {
+			iopsField = "disk_iops_read_write"
+			iops = diskIops.(int)
+		}
 
 		if iops > 0 && !ultraSSDEnabled && storageAccountType != virtualmachinescalesets.StorageAccountTypesPremiumVTwoLRS {
-			return nil, fmt.Errorf("`ultra_ssd_disk_iops_read_write` can only be set when `storage_account_type` is set to `PremiumV2_LRS` or `UltraSSD_LRS`")
+			return nil, fmt.Errorf("`%s` can only be set when `storage_account_type` is set to `PremiumV2_LRS` or `UltraSSD_LRS`", iopsField)
 		}
 
+		mbpsField := "ultra_ssd_disk_mbps_read_write"
 		var mbps int
 		if diskMbps, ok := raw["ultra_ssd_disk_mbps_read_write"]; ok && diskMbps.(int) > 0 {
 			mbps = diskMbps.(int)
 		}
+		if diskMbps, ok := raw["disk_mbps_read_write"]; ok && diskMbps.(int) > 0 {
+			mbpsField = "disk_mbps_read_write"
+			mbps = diskMbps.(int)
+		}
 
 		if mbps > 0 && !ultraSSDEnabled && storageAccountType != virtualmachinescalesets.StorageAccountTypesPremiumVTwoLRS {
-			return nil, fmt.Errorf("`ultra_ssd_disk_mbps_read_write` can only be set when `storage_account_type` is set to `PremiumV2_LRS` or `UltraSSD_LRS`")
+			return nil, fmt.Errorf("`%s` can only be set when `storage_account_type` is set to `PremiumV2_LRS` or `UltraSSD_LRS`", mbpsField)
 		}
 
 		// Do not set value unless value is greater than 0 - issue 15516
@@ -1347,11 +2295,22 @@ func ExpandVirtualMachineScaleSetDataDisk(input []interface{}, ultraSSDEnabled b
 	return &disks, nil
 }
 
-func FlattenVirtualMachineScaleSetDataDisk(input *[]virtualmachinescalesets.VirtualMachineScaleSetDataDisk) []interface{} {
+// FlattenVirtualMachineScaleSetDataDisk flattens the Data Disks, using the existing Data Disks from the state to determine
+// whether the `disk_*` aliases of the `ultra_ssd_*` fields are in use, since these are only set when they're configured
+func FlattenVirtualMachineScaleSetDataDisk(input *[]virtualmachinescalesets.VirtualMachineScaleSetDataDisk, existing []interface{}) []interface{} {
 	if input == nil {
 		return []interface{}{}
 	}
 
+	existingByLun := make(map[int64]map[string]interface{})
+	for _, v := range existing {
+		if v == nil {
+			continue
+		}
+		raw := v.(map[string]interface{})
+		existingByLun[int64(raw["lun"].(int))] = raw
+	}
+
 	output := make([]interface{}, 0)
 
 	for _, v := range *input {
@@ -1389,6 +2348,17 @@ func FlattenVirtualMachineScaleSetDataDisk(input *[]virtualmachinescalesets.Virt
 			mbps = int(*v.DiskMBpsReadWrite)
 		}
 
+		diskIops := 0
+		diskMbps := 0
+		if existingDisk, ok := existingByLun[v.Lun]; ok {
+			if configured, ok := existingDisk["disk_iops_read_write"].(int); ok && configured > 0 {
+				diskIops = iops
+			}
+			if configured, ok := existingDisk["disk_mbps_read_write"].(int); ok && configured > 0 {
+				diskMbps = mbps
+			}
+		}
+
 		dataDisk := map[string]interface{}{
 			"name":                           name,
 			"caching":                        string(pointer.From(v.Caching)),
@@ -1396,6 +2366,8 @@ func FlattenVirtualMachineScaleSetDataDisk(input *[]virtualmachinescalesets.Virt
 			"lun":                            v.Lun,
 			"disk_encryption_set_id":         diskEncryptionSetId,
 			"disk_size_gb":                   diskSizeGb,
+			"disk_iops_read_write":           diskIops,
+			"disk_mbps_read_write":           diskMbps,
 			"storage_account_type":           storageAccountType,
 			"ultra_ssd_disk_iops_read_write": iops,
 			"ultra_ssd_disk_mbps_read_write": mbps,
@@ -1408,6 +2380,40 @@ func FlattenVirtualMachineScaleSetDataDisk(input *[]virtualmachinescalesets.Virt
 	return output
 }
 
+func VirtualMachineScaleSetDiskControllerTypeSchema() *pluginsdk.Schema {
+	return &pluginsdk.Schema{
+		Type:     pluginsdk.TypeString,
+		Optional: true,
+		Computed: true,
+		// NOTE: `NVMe` is only supported by Generation 2 images on VM Sizes which support it, changing this
+		// requires the instances to be reimaged/upgraded before it takes effect.
+		ValidateFunc: validation.StringInSlice([]string{
+			string(virtualmachines.DiskControllerTypesNVMe),
+			string(virtualmachines.DiskControllerTypesSCSI),
+		}, false),
+	}
+}
+
+func ExpandVirtualMachineScaleSetDiskControllerType(input string) *string {
+	if input == "" {
+		return nil
+	}
+
+	return pointer.To(input)
+}
+
+func FlattenVirtualMachineScaleSetDiskControllerType(input *string) string {
+	// the API doesn't guarantee the casing of the Disk Controller Type so normalize it to the values in the schema
+	controllerType := pointer.From(input)
+	for _, v := range []string{string(virtualmachines.DiskControllerTypesNVMe), string(virtualmachines.DiskControllerTypesSCSI)} {
+		if strings.EqualFold(controllerType, v) {
+			return v
+		}
+	}
+
+	return controllerType
+}
+
 func VirtualMachineScaleSetOSDiskSchema() *pluginsdk.Schema {
 	return &pluginsdk.Schema{
 		Type:     pluginsdk.TypeList,
@@ -1429,15 +2435,8 @@ func VirtualMachineScaleSetOSDiskSchema() *pluginsdk.Schema {
 					Required: true,
 					// whilst this appears in the Update block the API returns this when changing:
 					// Changing property 'osDisk.managedDisk.storageAccountType' is not allowed
-					ForceNew: true,
-					ValidateFunc: validation.StringInSlice([]string{
-						// note: OS Disks don't support Ultra SSDs or PremiumV2_LRS
-						string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
-						string(virtualmachinescalesets.StorageAccountTypesPremiumZRS),
-						string(virtualmachinescalesets.StorageAccountTypesStandardLRS),
-						string(virtualmachinescalesets.StorageAccountTypesStandardSSDLRS),
-						string(virtualmachinescalesets.StorageAccountTypesStandardSSDZRS),
-					}, false),
+					ForceNew:     true,
+					ValidateFunc: validateVirtualMachineScaleSetOSDiskStorageAccountType,
 				},
 
 				"diff_disk_settings": {
@@ -1515,13 +2514,53 @@ func VirtualMachineScaleSetOSDiskSchema() *pluginsdk.Schema {
 	}
 }
 
+// validateVirtualMachineScaleSetOSDiskStorageAccountType validates the `storage_account_type` of the OS Disk, returning a targeted
+// error for the Ultra SSD and Premium SSD v2 types (which are only supported for Data Disks) rather than listing the allowed values
+func validateVirtualMachineScaleSetOSDiskStorageAccountType(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	if err := virtualMachineScaleSetOSDiskUnsupportedStorageAccountTypeError(v); err != nil {
+		errors = append(errors, err)
+		return
+	}
+
+	return validation.StringInSlice([]string{
+		string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
+		string(virtualmachinescalesets.StorageAccountTypesPremiumZRS),
+		string(virtualmachinescalesets.StorageAccountTypesStandardLRS),
+		string(virtualmachinescalesets.StorageAccountTypesStandardSSDLRS),
+		string(virtualmachinescalesets.StorageAccountTypesStandardSSDZRS),
+	}, false)(i, k)
+}
+
+func virtualMachineScaleSetOSDiskUnsupportedStorageAccountTypeError(storageAccountType string) error {
+	if storageAccountType == string(virtualmachinescalesets.StorageAccountTypesUltraSSDLRS) || storageAccountType == string(virtualmachinescalesets.StorageAccountTypesPremiumVTwoLRS) {
+		return fmt.Errorf("`storage_account_type` cannot be set to %q for the OS Disk since OS Disks don't support Ultra SSDs or Premium SSD v2 - these can only be used for Data Disks", storageAccountType)
+	}
+	return nil
+}
+
 func ExpandVirtualMachineScaleSetOSDisk(input []interface{}, osType virtualmachinescalesets.OperatingSystemTypes) (*virtualmachinescalesets.VirtualMachineScaleSetOSDisk, error) {
 	raw := input[0].(map[string]interface{})
 	caching := raw["caching"].(string)
+	if raw["write_accelerator_enabled"].(bool) && caching == string(virtualmachinescalesets.CachingTypesReadWrite) {
+		return nil, fmt.Errorf("`write_accelerator_enabled` can only be enabled on the OS Disk when `caching` is set to `None` or `ReadOnly`")
+	}
+
+	// the schema validates this, however this is also checked here since this function is exported and the value may be interpolated
+	storageAccountType := raw["storage_account_type"].(string)
+	if err := virtualMachineScaleSetOSDiskUnsupportedStorageAccountTypeError(storageAccountType); err != nil {
+		return nil, err
+	}
+
 	disk := virtualmachinescalesets.VirtualMachineScaleSetOSDisk{
 		Caching: pointer.To(virtualmachinescalesets.CachingTypes(caching)),
 		ManagedDisk: &virtualmachinescalesets.VirtualMachineScaleSetManagedDiskParameters{
-			StorageAccountType: pointer.To(virtualmachinescalesets.StorageAccountTypes(raw["storage_account_type"].(string))),
+			StorageAccountType: pointer.To(virtualmachinescalesets.StorageAccountTypes(storageAccountType)),
 		},
 		WriteAcceleratorEnabled: pointer.To(raw["write_accelerator_enabled"].(bool)),
 
@@ -1530,6 +2569,11 @@ func ExpandVirtualMachineScaleSetOSDisk(input []interface{}, osType virtualmachi
 		OsType:       pointer.To(osType),
 	}
 
+	// the schema marks these as conflicting, however this is also checked here since this function is exported
+	if raw["disk_encryption_set_id"].(string) != "" && raw["secure_vm_disk_encryption_set_id"].(string) != "" {
+		return nil, fmt.Errorf("only one of `disk_encryption_set_id` and `secure_vm_disk_encryption_set_id` can be specified")
+	}
+
 	securityEncryptionType := raw["security_encryption_type"].(string)
 	if securityEncryptionType != "" {
 		disk.ManagedDisk.SecurityProfile = &virtualmachinescalesets.VMDiskSecurityProfile{
@@ -1571,6 +2615,41 @@ func ExpandVirtualMachineScaleSetOSDisk(input []interface{}, osType virtualmachi
 	return &disk, nil
 }
 
+// virtualMachineScaleSetOSDiskSizeIncreaseWarnings returns a warning when the `disk_size_gb` of the OS Disk is increased, since
+// whilst the disk is resized at the platform level the partition and filesystem within the guest OS aren't extended
+func virtualMachineScaleSetOSDiskSizeIncreaseWarnings(oldSizeGB, newSizeGB int) diag.Diagnostics {
+	if newSizeGB <= oldSizeGB {
+		return nil
+	}
+
+	detail := fmt.Sprintf("the OS Disk `disk_size_gb` is being increased from %d GB to %d GB", oldSizeGB, newSizeGB)
+	if oldSizeGB == 0 {
+		detail = fmt.Sprintf("the OS Disk `disk_size_gb` is being set to %d GB", newSizeGB)
+	}
+
+	return diag.Diagnostics{
+		virtualMachineScaleSetWarning("The guest filesystem isn't extended", fmt.Sprintf("%s - the disk is resized at the platform level once each instance is updated to the latest model (which may require restarting the instance), however the partition and filesystem within the guest OS must be extended separately", detail)),
+	}
+}
+
+// ValidateConfidentialVMDiskEncryption ensures that the Security Profile of a Confidential VM supports the OS Disk's
+// `security_encryption_type` - vTPM must be enabled for all encryption types, and Secure Boot must also be enabled when
+// the OS Disk is encrypted alongside the VM Guest State (`DiskWithVMGuestState`)
+func ValidateConfidentialVMDiskEncryption(securityEncryptionType string, vtpmEnabled, secureBootEnabled bool) error {
+	if securityEncryptionType == "" {
+		return nil
+	}
+
+	if virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState == virtualmachinescalesets.SecurityEncryptionTypes(securityEncryptionType) && !secureBootEnabled {
+		return fmt.Errorf("`secure_boot_enabled` must be set to `true` when `os_disk.0.security_encryption_type` is set to `DiskWithVMGuestState`")
+	}
+	if !vtpmEnabled {
+		return fmt.Errorf("`vtpm_enabled` must be set to `true` when `os_disk.0.security_encryption_type` is set")
+	}
+
+	return nil
+}
+
 func ExpandVirtualMachineScaleSetOSDiskUpdate(input []interface{}) *virtualmachinescalesets.VirtualMachineScaleSetUpdateOSDisk {
 	raw := input[0].(map[string]interface{})
 	disk := virtualmachinescalesets.VirtualMachineScaleSetUpdateOSDisk{
@@ -1851,7 +2930,7 @@ func VirtualMachineScaleSetTerminateNotificationSchema() *pluginsdk.Schema {
 				"timeout": {
 					Type:         pluginsdk.TypeString,
 					Optional:     true,
-					ValidateFunc: azValidate.ISO8601DurationBetween("PT5M", "PT15M"),
+					ValidateFunc: azValidate.ISO8601DurationBetweenWithMessage("PT5M", "PT15M"),
 					Default:      "PT5M",
 				},
 			},
@@ -1875,7 +2954,7 @@ func VirtualMachineScaleSetTerminationNotificationSchema() *pluginsdk.Schema {
 				"timeout": {
 					Type:         pluginsdk.TypeString,
 					Optional:     true,
-					ValidateFunc: azValidate.ISO8601DurationBetween("PT5M", "PT15M"),
+					ValidateFunc: azValidate.ISO8601DurationBetweenWithMessage("PT5M", "PT15M"),
 					Default:      "PT5M",
 				},
 			},
@@ -1928,6 +3007,279 @@ func FlattenVirtualMachineScaleSetScheduledEventsProfile(input *virtualmachinesc
 	}
 }
 
+func VirtualMachineScaleSetBootDiagnosticsSchema() *pluginsdk.Schema {
+	// lintignore:XS003
+	return &pluginsdk.Schema{
+		Type:     pluginsdk.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &pluginsdk.Resource{
+			Schema: map[string]*pluginsdk.Schema{
+				// when omitted Managed Boot Diagnostics are used, which don't require a Storage Account
+				"storage_account_uri": {
+					Type:     pluginsdk.TypeString,
+					Optional: true,
+					ValidateFunc: validation.Any(
+						validation.StringIsEmpty,
+						validation.IsURLWithHTTPS,
+					),
+				},
+			},
+		},
+	}
+}
+
+// ExpandVirtualMachineScaleSetBootDiagnostics expands the `boot_diagnostics` block - when the block is omitted Boot Diagnostics
+// are disabled, when it's specified without a `storage_account_uri` Managed Boot Diagnostics are enabled (matching the behaviour
+// of the Portal/API) and otherwise Boot Diagnostics are written to the specified Storage Account
+func ExpandVirtualMachineScaleSetBootDiagnostics(input []interface{}) *virtualmachinescalesets.DiagnosticsProfile {
+	if len(input) == 0 {
+		return &virtualmachinescalesets.DiagnosticsProfile{
+			BootDiagnostics: &virtualmachinescalesets.BootDiagnostics{
+				Enabled:    pointer.To(false),
+				StorageUri: pointer.To(""),
+			},
+		}
+	}
+
+	// an empty block (e.g. `boot_diagnostics {}`) is represented as a nil element
+	storageAccountUri := ""
+	if raw, ok := input[0].(map[string]interface{}); ok {
+		storageAccountUri = raw["storage_account_uri"].(string)
+	}
+
+	return &virtualmachinescalesets.DiagnosticsProfile{
+		BootDiagnostics: &virtualmachinescalesets.BootDiagnostics{
+			Enabled:    pointer.To(true),
+			StorageUri: pointer.To(storageAccountUri),
+		},
+	}
+}
+
+// FlattenVirtualMachineScaleSetBootDiagnostics flattens the `boot_diagnostics` block. The API normalises the `storage_account_uri`
+// (for example appending a trailing slash), so where this only differs from the value in the existing state by its casing or a
+// trailing slash the existing value is retained, to avoid a perpetual diff.
+func FlattenVirtualMachineScaleSetBootDiagnostics(input *virtualmachinescalesets.DiagnosticsProfile, existing []interface{}) []interface{} {
+	if input == nil || input.BootDiagnostics == nil || !pointer.From(input.BootDiagnostics.Enabled) {
+		return []interface{}{}
+	}
+
+	storageAccountUri := pointer.From(input.BootDiagnostics.StorageUri)
+	if len(existing) > 0 {
+		if raw, ok := existing[0].(map[string]interface{}); ok {
+			if existingUri, ok := raw["storage_account_uri"].(string); ok && strings.EqualFold(strings.TrimSuffix(existingUri, "/"), strings.TrimSuffix(storageAccountUri, "/")) {
+				storageAccountUri = existingUri
+			}
+		}
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"storage_account_uri": storageAccountUri,
+		},
+	}
+}
+
+// ExpandVirtualMachineScaleSetEncryptionAtHost sets `EncryptionAtHost` on the existing Security Profile, creating
+// one if it doesn't exist, so that it doesn't overwrite any other settings in the Security Profile
+func ExpandVirtualMachineScaleSetEncryptionAtHost(securityProfile *virtualmachinescalesets.SecurityProfile, enabled bool) *virtualmachinescalesets.SecurityProfile {
+	if securityProfile == nil {
+		securityProfile = &virtualmachinescalesets.SecurityProfile{}
+	}
+	securityProfile.EncryptionAtHost = pointer.To(enabled)
+
+	return securityProfile
+}
+
+func FlattenVirtualMachineScaleSetEncryptionAtHost(input *virtualmachinescalesets.SecurityProfile) bool {
+	// the API omits this when it's disabled, so this defaults to false
+	if input == nil || input.EncryptionAtHost == nil {
+		return false
+	}
+
+	return *input.EncryptionAtHost
+}
+
+// checkVirtualMachineScaleSetEncryptionAtHostSupported checks that the SKU supports Encryption at Host in the specified
+// Location using the cached SKU capabilities, to avoid the less actionable error returned from the API at provisioning time.
+// This is only done when Enhanced Validation is enabled, and if the SKU can't be retrieved this is left to the API.
+func checkVirtualMachineScaleSetEncryptionAtHostSupported(ctx context.Context, client *client.Client, location string, skuName string) error {
+	if !features.EnhancedValidationEnabled() {
+		return nil
+	}
+
+	sku, err := client.GetSkuCapabilities(ctx, location, skuName)
+	if err != nil {
+		log.Printf("[DEBUG] unable to retrieve the capabilities of the SKU %q, leaving the validation of `encryption_at_host_enabled` to the API: %+v", skuName, err)
+		return nil
+	}
+
+	return validateVirtualMachineScaleSetEncryptionAtHostSupported(sku, location, skuName)
+}
+
+func validateVirtualMachineScaleSetEncryptionAtHostSupported(sku *client.SkuCapabilities, location string, skuName string) error {
+	// if the SKU can't be found we leave it to the API to return an error
+	if sku == nil {
+		return nil
+	}
+
+	if !sku.HasCapability("EncryptionAtHostSupported") {
+		return fmt.Errorf("`encryption_at_host_enabled` cannot be set to `true` since the SKU %q doesn't support Encryption at Host in %q - please choose a different `sku` and ensure that the `EncryptionAtHost` feature is registered for the `Microsoft.Compute` Resource Provider", skuName, location)
+	}
+
+	return nil
+}
+
+// checkVirtualMachineScaleSetOSDiskPlacementSupported checks that the SKU has a cache disk when an Ephemeral OS Disk is placed on
+// the cache disk (which is the default), since not all SKUs have one and the API otherwise fails at provisioning time. This is
+// only done when Enhanced Validation is enabled, and if the SKU can't be retrieved this is left to the API.
+func checkVirtualMachineScaleSetOSDiskPlacementSupported(ctx context.Context, client *client.Client, location string, skuName string, osDisk *virtualmachinescalesets.VirtualMachineScaleSetOSDisk) error {
+	if !features.EnhancedValidationEnabled() {
+		return nil
+	}
+	if osDisk == nil || osDisk.DiffDiskSettings == nil || pointer.From(osDisk.DiffDiskSettings.Placement) != virtualmachinescalesets.DiffDiskPlacementCacheDisk {
+		return nil
+	}
+
+	sku, err := client.GetSkuCapabilities(ctx, location, skuName)
+	if err != nil {
+		log.Printf("[DEBUG] unable to retrieve the capabilities of the SKU %q, leaving the validation of `os_disk.0.diff_disk_settings.0.placement` to the API: %+v", skuName, err)
+		return nil
+	}
+
+	return validateVirtualMachineScaleSetOSDiskPlacementSupported(sku, location, skuName, pointer.From(osDisk.DiffDiskSettings.Placement))
+}
+
+func validateVirtualMachineScaleSetOSDiskPlacementSupported(sku *client.SkuCapabilities, location string, skuName string, placement virtualmachinescalesets.DiffDiskPlacement) error {
+	// if the SKU can't be found we leave it to the API to return an error
+	if sku == nil || placement != virtualmachinescalesets.DiffDiskPlacementCacheDisk {
+		return nil
+	}
+
+	// SKUs without a cache disk either omit `CachedDiskBytes` or return it as `0`
+	if cachedDiskBytes, ok := sku.CapabilityInt("CachedDiskBytes"); ok && cachedDiskBytes > 0 {
+		return nil
+	}
+
+	if resourceVolume, ok := sku.CapabilityInt("MaxResourceVolumeMB"); ok && resourceVolume > 0 {
+		return fmt.Errorf("`os_disk.0.diff_disk_settings.0.placement` cannot be set to %q since the SKU %q doesn't have a cache disk in %q - please set `placement` to %q instead", string(placement), skuName, location, string(virtualmachinescalesets.DiffDiskPlacementResourceDisk))
+	}
+
+	return fmt.Errorf("`os_disk.0.diff_disk_settings.0.placement` cannot be set to %q since the SKU %q doesn't have a cache disk in %q - please choose a different `sku`", string(placement), skuName, location)
+}
+
+// virtualMachineScaleSetEphemeralOSDiskSizeCustomizeDiff ensures the `disk_size_gb` of an Ephemeral OS Disk fits within the cache
+// or resource disk of the SKU at plan time, since otherwise this is only rejected by the API once the Scale Set is being provisioned
+func virtualMachineScaleSetEphemeralOSDiskSizeCustomizeDiff(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+	// the size only needs to be checked when creating the Scale Set or when the OS Disk or SKU changes
+	if diff.Id() != "" && !diff.HasChanges("os_disk", "sku") {
+		return nil
+	}
+
+	osDiskRaw := diff.Get("os_disk").([]interface{})
+	if len(osDiskRaw) == 0 || osDiskRaw[0] == nil {
+		return nil
+	}
+
+	osDisk := osDiskRaw[0].(map[string]interface{})
+	diffDiskSettingsRaw := osDisk["diff_disk_settings"].([]interface{})
+	if len(diffDiskSettingsRaw) == 0 || diffDiskSettingsRaw[0] == nil {
+		return nil
+	}
+
+	// the size can't be checked until the SKU, Location and Disk Size are known
+	for _, field := range []string{"sku", "location", "os_disk.0.disk_size_gb"} {
+		if !diff.NewValueKnown(field) {
+			return nil
+		}
+	}
+
+	diskSizeGB := int64(osDisk["disk_size_gb"].(int))
+	if diskSizeGB <= 0 {
+		return nil
+	}
+
+	skuLocation := location.Normalize(diff.Get("location").(string))
+	skuName := diff.Get("sku").(string)
+	sku, err := meta.(*clients.Client).Compute.GetSkuCapabilities(ctx, skuLocation, skuName)
+	if err != nil {
+		log.Printf("[DEBUG] unable to retrieve the capabilities of the SKU %q in %q, leaving the validation of `os_disk.0.disk_size_gb` to the API: %+v", skuName, skuLocation, err)
+		return nil
+	}
+
+	placement := virtualmachinescalesets.DiffDiskPlacement(diffDiskSettingsRaw[0].(map[string]interface{})["placement"].(string))
+	return validateVirtualMachineScaleSetEphemeralOSDiskSize(sku, skuLocation, skuName, placement, diskSizeGB)
+}
+
+func validateVirtualMachineScaleSetEphemeralOSDiskSize(sku *client.SkuCapabilities, location string, skuName string, placement virtualmachinescalesets.DiffDiskPlacement, diskSizeGB int64) error {
+	// if the SKU can't be found we leave it to the API to return an error
+	if sku == nil {
+		return nil
+	}
+
+	// `CachedDiskBytes` is returned in Bytes whereas `MaxResourceVolumeMB` is returned in MB
+	var maxSizeGB int64
+	var diskName string
+	switch placement {
+	case virtualmachinescalesets.DiffDiskPlacementCacheDisk:
+		cachedDiskBytes, ok := sku.CapabilityInt("CachedDiskBytes")
+		if !ok || cachedDiskBytes <= 0 {
+			// a SKU without a cache disk is reported by `validateVirtualMachineScaleSetOSDiskPlacementSupported`
+			return nil
+		}
+		maxSizeGB = cachedDiskBytes / (1024 * 1024 * 1024)
+		diskName = "cache disk"
+
+	case virtualmachinescalesets.DiffDiskPlacementResourceDisk:
+		resourceVolumeMB, ok := sku.CapabilityInt("MaxResourceVolumeMB")
+		if !ok || resourceVolumeMB <= 0 {
+			return nil
+		}
+		maxSizeGB = resourceVolumeMB / 1024
+		diskName = "resource disk"
+
+	default:
+		return nil
+	}
+
+	if diskSizeGB > maxSizeGB {
+		return fmt.Errorf("`os_disk.0.disk_size_gb` cannot be greater than %d when `os_disk.0.diff_disk_settings.0.placement` is set to %q since that's the size of the %s of the SKU %q in %q - please reduce `disk_size_gb` or choose a larger `sku`", maxSizeGB, string(placement), diskName, skuName, location)
+	}
+
+	return nil
+}
+
+// checkVirtualMachineScaleSetDiskEncryptionSetTypes checks that the Disk Encryption Sets referenced by the OS and Data Disks are
+// of the encryption type expected by the field referencing them, rather than surfacing a less actionable error at provisioning time
+func checkVirtualMachineScaleSetDiskEncryptionSetTypes(ctx context.Context, client *diskencryptionsets.DiskEncryptionSetsClient, osDiskRaw []interface{}, dataDisksRaw []interface{}) error {
+	if len(osDiskRaw) > 0 && osDiskRaw[0] != nil {
+		osDisk := osDiskRaw[0].(map[string]interface{})
+		if id := osDisk["disk_encryption_set_id"].(string); id != "" {
+			if err := validateDiskEncryptionSetEncryptionType(ctx, client, id, false); err != nil {
+				return fmt.Errorf("validating `os_disk.0.disk_encryption_set_id`: %+v", err)
+			}
+		}
+		if id := osDisk["secure_vm_disk_encryption_set_id"].(string); id != "" {
+			if err := validateDiskEncryptionSetEncryptionType(ctx, client, id, true); err != nil {
+				return fmt.Errorf("validating `os_disk.0.secure_vm_disk_encryption_set_id`: %+v", err)
+			}
+		}
+	}
+
+	for i, v := range dataDisksRaw {
+		if v == nil {
+			continue
+		}
+		if id := v.(map[string]interface{})["disk_encryption_set_id"].(string); id != "" {
+			if err := validateDiskEncryptionSetEncryptionType(ctx, client, id, false); err != nil {
+				return fmt.Errorf("validating `data_disk.%d.disk_encryption_set_id`: %+v", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func VirtualMachineScaleSetAutomaticRepairsPolicySchema() *pluginsdk.Schema {
 	return &pluginsdk.Schema{
 		Type:     pluginsdk.TypeList,
@@ -1941,256 +3293,950 @@ func VirtualMachineScaleSetAutomaticRepairsPolicySchema() *pluginsdk.Schema {
 					Required: true,
 				},
 				"grace_period": {
-					Type:     pluginsdk.TypeString,
-					Optional: true,
-					Default:  "PT30M",
-					// this field actually has a range from 30m to 90m, is there a function that can do this validation?
-					ValidateFunc: azValidate.ISO8601Duration,
+					Type:         pluginsdk.TypeString,
+					Optional:     true,
+					Default:      "PT30M",
+					ValidateFunc: azValidate.ISO8601DurationBetweenWithMessage("PT30M", "PT90M"),
 				},
 			},
 		},
 	}
 }
 
-func ExpandVirtualMachineScaleSetAutomaticRepairsPolicy(input []interface{}) *virtualmachinescalesets.AutomaticRepairsPolicy {
-	if len(input) == 0 {
+func ExpandVirtualMachineScaleSetAutomaticRepairsPolicy(input []interface{}) *virtualmachinescalesets.AutomaticRepairsPolicy {
+	if len(input) == 0 {
+		return nil
+	}
+
+	raw := input[0].(map[string]interface{})
+
+	return &virtualmachinescalesets.AutomaticRepairsPolicy{
+		Enabled:     pointer.To(raw["enabled"].(bool)),
+		GracePeriod: pointer.To(raw["grace_period"].(string)),
+	}
+}
+
+// validateVirtualMachineScaleSetAutomaticRepairsPolicy ensures that the health of the instances is being monitored when
+// automatic instance repairs are enabled, since otherwise the API rejects the request
+func validateVirtualMachineScaleSetAutomaticRepairsPolicy(input []interface{}, healthProbeId string, hasHealthExtension bool) error {
+	if len(input) == 0 || input[0] == nil {
+		return nil
+	}
+
+	raw := input[0].(map[string]interface{})
+	if enabled, ok := raw["enabled"].(bool); !ok || !enabled {
+		return nil
+	}
+
+	if healthProbeId == "" && !hasHealthExtension {
+		return fmt.Errorf("`health_probe_id` must be set or an Application Health extension must be specified when `automatic_instance_repair` is enabled")
+	}
+
+	return nil
+}
+
+func FlattenVirtualMachineScaleSetAutomaticRepairsPolicy(input *virtualmachinescalesets.AutomaticRepairsPolicy) []interface{} {
+	// if enabled is set to false, there will be no AutomaticRepairsPolicy in response, to avoid plan non empty when
+	// a user explicitly set enabled to false, we need to assign a default block to this field
+
+	enabled := false
+	if input != nil && input.Enabled != nil {
+		enabled = *input.Enabled
+	}
+
+	gracePeriod := "PT30M"
+	if input != nil && input.GracePeriod != nil {
+		gracePeriod = *input.GracePeriod
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"enabled":      enabled,
+			"grace_period": gracePeriod,
+		},
+	}
+}
+
+func VirtualMachineScaleSetExtensionsSchema() *pluginsdk.Schema {
+	return &pluginsdk.Schema{
+		Type:     pluginsdk.TypeSet,
+		Optional: true,
+		Computed: true,
+		Elem: &pluginsdk.Resource{
+			Schema: map[string]*pluginsdk.Schema{
+				"name": {
+					Type:         pluginsdk.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+
+				"publisher": {
+					Type:         pluginsdk.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+
+				"type": {
+					Type:         pluginsdk.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+
+				"type_handler_version": {
+					Type:         pluginsdk.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+
+				"auto_upgrade_minor_version": {
+					Type:     pluginsdk.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+
+				"automatic_upgrade_enabled": {
+					Type:     pluginsdk.TypeBool,
+					Optional: true,
+				},
+
+				"force_update_tag": {
+					Type:     pluginsdk.TypeString,
+					Optional: true,
+				},
+
+				"protected_settings": {
+					Type:         pluginsdk.TypeString,
+					Optional:     true,
+					Sensitive:    true,
+					ValidateFunc: validation.StringIsJSON,
+				},
+
+				// Need to check `protected_settings_from_key_vault` conflicting with `protected_settings` in iteration
+				"protected_settings_from_key_vault": protectedSettingsFromKeyVaultSchema(false),
+
+				"provision_after_extensions": {
+					Type:     pluginsdk.TypeList,
+					Optional: true,
+					Elem: &pluginsdk.Schema{
+						Type: pluginsdk.TypeString,
+					},
+				},
+
+				// NOTE: this isn't supported by the API for each Extension, so is instead used to bound how long we wait
+				// for the Scale Set to be provisioned
+				"provisioning_timeout": {
+					Type:         pluginsdk.TypeString,
+					Optional:     true,
+					ValidateFunc: validateVirtualMachineScaleSetExtensionProvisioningTimeout,
+				},
+
+				"settings": {
+					Type:             pluginsdk.TypeString,
+					Optional:         true,
+					ValidateFunc:     validate.ExtensionSettings,
+					DiffSuppressFunc: virtualMachineScaleSetExtensionSettingsDiffSuppress,
+				},
+			},
+		},
+		Set: virtualMachineScaleSetExtensionHash,
+	}
+}
+
+func virtualMachineScaleSetExtensionHash(v interface{}) int {
+	var buf bytes.Buffer
+
+	if m, ok := v.(map[string]interface{}); ok {
+		buf.WriteString(fmt.Sprintf("%s-", m["name"].(string)))
+		buf.WriteString(fmt.Sprintf("%s-", m["publisher"].(string)))
+		buf.WriteString(fmt.Sprintf("%s-", m["type"].(string)))
+		buf.WriteString(fmt.Sprintf("%s-", m["type_handler_version"].(string)))
+		buf.WriteString(fmt.Sprintf("%t-", m["auto_upgrade_minor_version"].(bool)))
+
+		if v, ok := m["automatic_upgrade_enabled"]; ok {
+			buf.WriteString(fmt.Sprintf("%t-", v.(bool)))
+		}
+
+		if v, ok = m["force_update_tag"]; ok {
+			buf.WriteString(fmt.Sprintf("%s-", v))
+		}
+
+		if v, ok := m["provision_after_extensions"]; ok {
+			buf.WriteString(fmt.Sprintf("%s-", v))
+		}
+
+		// we need to ensure the whitespace is consistent
+		settings := m["settings"].(string)
+		if settings != "" {
+			expandedSettings, err := pluginsdk.ExpandJsonFromString(settings)
+			if err == nil {
+				serializedSettings, err := pluginsdk.FlattenJsonToString(expandedSettings)
+				if err == nil {
+					buf.WriteString(fmt.Sprintf("%s-", serializedSettings))
+				}
+			}
+		}
+
+		if v, ok := m["protected_settings"]; ok {
+			settings := v.(string)
+			if settings != "" {
+				expandedSettings, err := pluginsdk.ExpandJsonFromString(settings)
+				if err == nil {
+					serializedSettings, err := pluginsdk.FlattenJsonToString(expandedSettings)
+					if err == nil {
+						buf.WriteString(fmt.Sprintf("%s-", serializedSettings))
+					}
+				}
+			}
+		}
+
+		if v, ok := m["protected_settings_from_key_vault"]; ok {
+			protectedSettingsFromKeyVault := v.([]interface{})
+			if len(protectedSettingsFromKeyVault) > 0 {
+				buf.WriteString(fmt.Sprintf("%s-", protectedSettingsFromKeyVault[0].(map[string]interface{})["secret_url"].(string)))
+				buf.WriteString(fmt.Sprintf("%s-", protectedSettingsFromKeyVault[0].(map[string]interface{})["source_vault_id"].(string)))
+			}
+		}
+	}
+
+	return pluginsdk.HashString(buf.String())
+}
+
+// isVirtualMachineScaleSetHealthExtension returns whether the extension type is an Application Health extension - this is
+// matched case-insensitively and regardless of the publisher, since the API accepts the type in any casing
+func isVirtualMachineScaleSetHealthExtension(extensionType string) bool {
+	return strings.EqualFold(extensionType, "ApplicationHealthLinux") || strings.EqualFold(extensionType, "ApplicationHealthWindows")
+}
+
+// expandVirtualMachineScaleSetExtensions expands the extensions, returning whether the health of the instances is monitored -
+// either by an Application Health extension or by the specified `health_probe_id`, since both satisfy the same requirements
+func expandVirtualMachineScaleSetExtensions(input []interface{}, healthProbeId string) (extensionProfile *virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile, hasHealthExtension bool, err error) {
+	extensionProfile = &virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile{}
+	hasHealthExtension = healthProbeId != ""
+	if len(input) == 0 {
+		return extensionProfile, hasHealthExtension, nil
+	}
+
+	extensions := make([]virtualmachinescalesets.VirtualMachineScaleSetExtension, 0)
+	for _, v := range input {
+		extension, err := expandVirtualMachineScaleSetExtension(v.(map[string]interface{}))
+		if err != nil {
+			return nil, false, err
+		}
+
+		if isVirtualMachineScaleSetHealthExtension(pointer.From(extension.Properties.Type)) {
+			hasHealthExtension = true
+		}
+
+		extensions = append(extensions, *extension)
+	}
+	extensionProfile.Extensions = &extensions
+
+	if err := validateVirtualMachineScaleSetExtensionProvisionAfterExtensions(input); err != nil {
+		return nil, false, err
+	}
+	if _, err := virtualMachineScaleSetExtensionProvisionOrder(input); err != nil {
+		return nil, false, err
+	}
+
+	return extensionProfile, hasHealthExtension, nil
+}
+
+// expandVirtualMachineScaleSetExtension expands a single `extension` block
+func expandVirtualMachineScaleSetExtension(extensionRaw map[string]interface{}) (*virtualmachinescalesets.VirtualMachineScaleSetExtension, error) {
+	extension := virtualmachinescalesets.VirtualMachineScaleSetExtension{
+		Name: pointer.To(extensionRaw["name"].(string)),
+	}
+	extensionType := extensionRaw["type"].(string)
+
+	extensionProps := virtualmachinescalesets.VirtualMachineScaleSetExtensionProperties{
+		Publisher:                pointer.To(extensionRaw["publisher"].(string)),
+		Type:                     &extensionType,
+		TypeHandlerVersion:       pointer.To(extensionRaw["type_handler_version"].(string)),
+		AutoUpgradeMinorVersion:  pointer.To(extensionRaw["auto_upgrade_minor_version"].(bool)),
+		EnableAutomaticUpgrade:   pointer.To(extensionRaw["automatic_upgrade_enabled"].(bool)),
+		ProvisionAfterExtensions: utils.ExpandStringSlice(extensionRaw["provision_after_extensions"].([]interface{})),
+	}
+
+	if forceUpdateTag := extensionRaw["force_update_tag"]; forceUpdateTag != nil {
+		extensionProps.ForceUpdateTag = pointer.To(forceUpdateTag.(string))
+	}
+
+	if val, ok := extensionRaw["settings"]; ok && val.(string) != "" {
+		var result interface{}
+		err := json.Unmarshal([]byte(val.(string)), &result)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshaling `settings`: %+v", err)
+		}
+		extensionProps.Settings = pointer.To(result)
+	}
+
+	protectedSettingsFromKeyVault := expandProtectedSettingsFromKeyVaultVMSS(extensionRaw["protected_settings_from_key_vault"].([]interface{}))
+	extensionProps.ProtectedSettingsFromKeyVault = protectedSettingsFromKeyVault
+
+	if val, ok := extensionRaw["protected_settings"]; ok && val.(string) != "" {
+		if protectedSettingsFromKeyVault != nil {
+			return nil, fmt.Errorf("`protected_settings_from_key_vault` cannot be used with `protected_settings`")
+		}
+
+		var result interface{}
+		err := json.Unmarshal([]byte(val.(string)), &result)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshaling `protected_settings`: %+v", err)
+		}
+		extensionProps.ProtectedSettings = pointer.To(result)
+	}
+
+	extension.Properties = &extensionProps
+
+	return &extension, nil
+}
+
+// validateVirtualMachineScaleSetExtensionProvisionAfterExtensions ensures that the `provision_after_extensions` of each extension
+// only references extensions defined within the Scale Set, since the API otherwise rejects the request with an opaque error
+func validateVirtualMachineScaleSetExtensionProvisionAfterExtensions(input []interface{}) error {
+	names := make(map[string]struct{})
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		names[strings.ToLower(extensionRaw["name"].(string))] = struct{}{}
+	}
+
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		provisionAfter, _ := extensionRaw["provision_after_extensions"].([]interface{})
+		for _, after := range provisionAfter {
+			after, ok := after.(string)
+			if !ok {
+				continue
+			}
+			if _, ok := names[strings.ToLower(after)]; !ok {
+				return fmt.Errorf("the extension %q is provisioned after the extension %q, which isn't defined within the `extension` blocks", extensionRaw["name"].(string), after)
+			}
+		}
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetExtensionProvisionOrder returns the names of the extensions in the order they're provisioned in, as
+// determined by their `provision_after_extensions` - extensions which can be provisioned at the same point are ordered by
+// name. Dependencies on extensions which aren't defined are ignored, however an error is returned for a circular dependency.
+func virtualMachineScaleSetExtensionProvisionOrder(input []interface{}) ([]string, error) {
+	// extension names are case-insensitive, so dependencies are matched on the lower-cased name
+	names := make(map[string]string)
+	dependencies := make(map[string][]string)
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		name := extensionRaw["name"].(string)
+		names[strings.ToLower(name)] = name
+
+		provisionAfter, _ := extensionRaw["provision_after_extensions"].([]interface{})
+		for _, after := range provisionAfter {
+			if after, ok := after.(string); ok {
+				dependencies[strings.ToLower(name)] = append(dependencies[strings.ToLower(name)], strings.ToLower(after))
+			}
+		}
+	}
+
+	remaining := make(map[string]int)
+	dependents := make(map[string][]string)
+	for key := range names {
+		remaining[key] = 0
+		for _, after := range dependencies[key] {
+			if _, ok := names[after]; !ok {
+				continue
+			}
+			remaining[key]++
+			dependents[after] = append(dependents[after], key)
+		}
+	}
+
+	output := make([]string, 0, len(names))
+	for len(remaining) > 0 {
+		ready := make([]string, 0)
+		for key, count := range remaining {
+			if count == 0 {
+				ready = append(ready, key)
+			}
+		}
+		if len(ready) == 0 {
+			// the remaining extensions include those which are only provisioned after the circular dependency, so these are
+			// excluded to only name the extensions which form it
+			for pruned := true; pruned; {
+				pruned = false
+				for key := range remaining {
+					isDependency := false
+					for _, dependent := range dependents[key] {
+						if _, ok := remaining[dependent]; ok {
+							isDependency = true
+							break
+						}
+					}
+					if !isDependency {
+						delete(remaining, key)
+						pruned = true
+					}
+				}
+			}
+
+			cycle := make([]string, 0, len(remaining))
+			for key := range remaining {
+				cycle = append(cycle, fmt.Sprintf("%q", names[key]))
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("the `provision_after_extensions` of the extensions %s form a circular dependency", strings.Join(cycle, ", "))
+		}
+
+		sort.Slice(ready, func(i, j int) bool {
+			return names[ready[i]] < names[ready[j]]
+		})
+		for _, key := range ready {
+			output = append(output, names[key])
+			delete(remaining, key)
+			for _, dependent := range dependents[key] {
+				remaining[dependent]--
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// parseVirtualMachineScaleSetExtensionProvisioningTimeout parses the `provisioning_timeout` of an Extension, which can be
+// specified either as an ISO8601 duration (e.g. `PT30M`) or as a duration (e.g. `30m`)
+func parseVirtualMachineScaleSetExtensionProvisioningTimeout(input string) (time.Duration, error) {
+	if p, err := period.Parse(input); err == nil {
+		duration, _ := p.Duration()
+		return duration, nil
+	}
+
+	duration, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, fmt.Errorf("expected an ISO8601 duration (e.g. `PT30M`) or a duration (e.g. `30m`) but got %q", input)
+	}
+
+	return duration, nil
+}
+
+func validateVirtualMachineScaleSetExtensionProvisioningTimeout(input interface{}, key string) (warnings []string, errors []error) {
+	v, ok := input.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected %q to be a string", key))
+		return
+	}
+
+	duration, err := parseVirtualMachineScaleSetExtensionProvisioningTimeout(v)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("%q: %+v", key, err))
+		return
+	}
+
+	if duration <= 0 {
+		errors = append(errors, fmt.Errorf("expected %q to be greater than zero but got %q", key, v))
+	}
+
+	return
+}
+
+// virtualMachineScaleSetExtensionProvisioningTimeoutPollInterval is how often the provisioning state of the Extensions
+// is checked once the `provisioning_timeout` of one of them has elapsed
+const virtualMachineScaleSetExtensionProvisioningTimeoutPollInterval = 30 * time.Second
+
+// virtualMachineScaleSetExtensionProvisioningTimeouts returns the `provisioning_timeout` of each of the Extensions which specify one
+func virtualMachineScaleSetExtensionProvisioningTimeouts(input []interface{}) map[string]time.Duration {
+	output := make(map[string]time.Duration)
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		if provisioningTimeout, ok := extensionRaw["provisioning_timeout"].(string); ok && provisioningTimeout != "" {
+			// this has been validated in the schema
+			duration, _ := parseVirtualMachineScaleSetExtensionProvisioningTimeout(provisioningTimeout)
+			output[extensionRaw["name"].(string)] = duration
+		}
+	}
+
+	return output
+}
+
+// virtualMachineScaleSetProvisionedExtensions returns whether each of the Extensions within the Instance View has been
+// provisioned successfully on all of the instances
+func virtualMachineScaleSetProvisionedExtensions(input *virtualmachinescalesets.VirtualMachineScaleSetInstanceView) map[string]bool {
+	output := make(map[string]bool)
+	if input == nil || input.Extensions == nil {
+		return output
+	}
+
+	for _, extension := range *input.Extensions {
+		if extension.Name == nil || extension.StatusesSummary == nil {
+			continue
+		}
+
+		provisioned := len(*extension.StatusesSummary) > 0
+		for _, status := range *extension.StatusesSummary {
+			if !strings.EqualFold(pointer.From(status.Code), "ProvisioningState/succeeded") {
+				provisioned = false
+			}
+		}
+		output[*extension.Name] = provisioned
+	}
+
+	return output
+}
+
+// checkVirtualMachineScaleSetExtensionProvisioningTimeouts returns an error for the first Extension whose `provisioning_timeout`
+// has elapsed without it having been provisioned
+func checkVirtualMachineScaleSetExtensionProvisioningTimeouts(timeouts map[string]time.Duration, elapsed time.Duration, provisioned map[string]bool) error {
+	names := make([]string, 0)
+	for name, timeout := range timeouts {
+		if timeout <= elapsed && !provisioned[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("the Extension %q wasn't provisioned within its `provisioning_timeout` of %s", names[0], timeouts[names[0]])
+}
+
+// pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts calls poll whilst checking that each of the Extensions is
+// provisioned within its `provisioning_timeout`, since the API doesn't support a timeout for each Extension. When one of
+// these elapses we stop waiting and return an error - however the operation itself continues within Azure.
+func pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(ctx context.Context, timeouts map[string]time.Duration, pollInterval time.Duration, poll func(ctx context.Context) error, provisionedExtensions func(ctx context.Context) (map[string]bool, error)) error {
+	if len(timeouts) == 0 {
+		return poll(ctx)
+	}
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- poll(pollCtx)
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-result:
+			return err
+
+		case <-ticker.C:
+			elapsed := time.Since(start)
+			if checkVirtualMachineScaleSetExtensionProvisioningTimeouts(timeouts, elapsed, nil) == nil {
+				// none of the timeouts have elapsed yet
+				continue
+			}
+
+			provisioned, err := provisionedExtensions(ctx)
+			if err != nil {
+				log.Printf("[DEBUG] retrieving the provisioning state of the Extensions: %+v", err)
+				continue
+			}
+
+			if err := checkVirtualMachineScaleSetExtensionProvisioningTimeouts(timeouts, elapsed, provisioned); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// virtualMachineScaleSetProvisionedExtensionsFunc returns a function retrieving whether each of the Extensions within the
+// Scale Set has been provisioned, for use with pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts
+func virtualMachineScaleSetProvisionedExtensionsFunc(client *virtualmachinescalesets.VirtualMachineScaleSetsClient, id virtualmachinescalesets.VirtualMachineScaleSetId) func(ctx context.Context) (map[string]bool, error) {
+	return func(ctx context.Context) (map[string]bool, error) {
+		resp, err := client.GetInstanceView(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving the Instance View of %s: %+v", id, err)
+		}
+
+		return virtualMachineScaleSetProvisionedExtensions(resp.Model), nil
+	}
+}
+
+const (
+	// virtualMachineScaleSetProximityPlacementGroupNotFoundRetries is the number of times creating the Scale Set is retried when
+	// the Proximity Placement Group can't be found, which happens when it was only just created (e.g. in the same apply)
+	virtualMachineScaleSetProximityPlacementGroupNotFoundRetries = 5
+
+	virtualMachineScaleSetProximityPlacementGroupNotFoundRetryInterval = 15 * time.Second
+)
+
+// isVirtualMachineScaleSetProximityPlacementGroupNotFoundError returns whether the error is the (transient) 404 returned
+// by the API when the Proximity Placement Group referenced by the Scale Set hasn't yet replicated, e.g.:
+// > Code="NotFound" Message="The Resource 'Microsoft.Compute/proximityPlacementGroups/example' under resource group 'example' was not found."
+func isVirtualMachineScaleSetProximityPlacementGroupNotFoundError(err error, proximityPlacementGroupId string) bool {
+	if err == nil || proximityPlacementGroupId == "" {
+		return false
+	}
+
+	id, parseErr := proximityplacementgroups.ParseProximityPlacementGroupIDInsensitively(proximityPlacementGroupId)
+	if parseErr != nil {
+		return false
+	}
+
+	message := strings.ToLower(err.Error())
+	if !strings.Contains(message, "notfound") && !strings.Contains(message, "not found") {
+		return false
+	}
+
+	return strings.Contains(message, strings.ToLower(fmt.Sprintf("proximityPlacementGroups/%s", id.ProximityPlacementGroupName)))
+}
+
+// createVirtualMachineScaleSetRetryingOnProximityPlacementGroupNotFound calls create, retrying up to the specified number
+// of times whilst the API returns a 404 for the Proximity Placement Group - any other error is returned immediately
+func createVirtualMachineScaleSetRetryingOnProximityPlacementGroupNotFound(ctx context.Context, proximityPlacementGroupId string, retries int, interval time.Duration, create func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := create(ctx)
+		if attempt > retries || !isVirtualMachineScaleSetProximityPlacementGroupNotFoundError(err, proximityPlacementGroupId) {
+			return err
+		}
+
+		log.Printf("[DEBUG] the Proximity Placement Group %q wasn't found, retrying in %s (attempt %d of %d)", proximityPlacementGroupId, interval, attempt, retries)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+	}
+}
+
+// virtualMachineScaleSetExtensionsCustomizeDiff validates the `extension` blocks against the rest of the Scale Set at plan time. This
+// is only done from 4.0, since erroring on these would otherwise break configurations which currently apply
+func virtualMachineScaleSetExtensionsCustomizeDiff(ctx context.Context, diff *pluginsdk.ResourceDiff, meta interface{}) error {
+	if !features.FourPointOhBeta() || !diff.HasChanges("extension", "identity") {
+		return nil
+	}
+
+	// the Extensions can't be checked until they're known, for example when the settings come from another resource
+	for _, field := range []string{"extension", "identity"} {
+		if !diff.NewValueKnown(field) {
+			return nil
+		}
+	}
+
+	return validateVirtualMachineScaleSetKeyVaultExtensionsIdentity(diff.Get("extension").(*pluginsdk.Set).List(), diff.Get("identity").([]interface{}))
+}
+
+// validateVirtualMachineScaleSetKeyVaultExtensionsIdentity ensures that a Managed Identity is configured on the Scale Set when
+// Extensions retrieve their protected settings from a Key Vault, since without one the Extensions fail to provision
+func validateVirtualMachineScaleSetKeyVaultExtensionsIdentity(extensions []interface{}, identity []interface{}) error {
+	names := virtualMachineScaleSetKeyVaultExtensionsWithoutIdentity(extensions, identity)
+	if len(names) == 0 {
 		return nil
 	}
 
-	raw := input[0].(map[string]interface{})
+	return fmt.Errorf("the Extensions %s retrieve their protected settings using `protected_settings_from_key_vault` but no `identity` is configured - an `identity` block must be specified, and the Managed Identity must be granted access to the Key Vault", strings.Join(names, ", "))
+}
 
-	return &virtualmachinescalesets.AutomaticRepairsPolicy{
-		Enabled:     pointer.To(raw["enabled"].(bool)),
-		GracePeriod: pointer.To(raw["grace_period"].(string)),
+// virtualMachineScaleSetKeyVaultExtensionsWithoutIdentity returns the quoted names of the Extensions which use
+// `protected_settings_from_key_vault` when no Managed Identity is configured on the Scale Set
+func virtualMachineScaleSetKeyVaultExtensionsWithoutIdentity(extensions []interface{}, identity []interface{}) []string {
+	if len(identity) > 0 && identity[0] != nil {
+		if identityType, ok := identity[0].(map[string]interface{})["type"].(string); ok && identityType != "" {
+			return nil
+		}
+	}
+
+	names := make([]string, 0)
+	for _, v := range extensions {
+		extensionRaw := v.(map[string]interface{})
+		if keyVault, ok := extensionRaw["protected_settings_from_key_vault"].([]interface{}); ok && len(keyVault) > 0 && keyVault[0] != nil {
+			names = append(names, fmt.Sprintf("%q", extensionRaw["name"].(string)))
+		}
 	}
+	sort.Strings(names)
+
+	return names
 }
 
-func FlattenVirtualMachineScaleSetAutomaticRepairsPolicy(input *virtualmachinescalesets.AutomaticRepairsPolicy) []interface{} {
-	// if enabled is set to false, there will be no AutomaticRepairsPolicy in response, to avoid plan non empty when
-	// a user explicitly set enabled to false, we need to assign a default block to this field
+// virtualMachineScaleSetOverprovisionSettleTimeout bounds how long we wait for the additional instances created when the Scale
+// Set is overprovisioned to be removed, since these are only removed once the requested instances have been provisioned
+const virtualMachineScaleSetOverprovisionSettleTimeout = 10 * time.Minute
+
+// waitForVirtualMachineScaleSetOverprovisionedInstancesToBeRemoved waits for the number of instances within the Scale Set to
+// settle at the requested capacity - since this only avoids reading transient instances, a failure is logged rather than
+// returned so that it doesn't fail the apply
+func waitForVirtualMachineScaleSetOverprovisionedInstancesToBeRemoved(ctx context.Context, client *client.Client, id virtualmachinescalesets.VirtualMachineScaleSetId, capacity int64) {
+	virtualMachineScaleSetId := virtualmachinescalesetvms.NewVirtualMachineScaleSetID(id.SubscriptionId, id.ResourceGroupName, id.VirtualMachineScaleSetName)
+	instanceCount := func(ctx context.Context) (int64, error) {
+		result, err := client.VirtualMachineScaleSetVMsClient.ListComplete(ctx, virtualMachineScaleSetId, virtualmachinescalesetvms.DefaultListOperationOptions())
+		if err != nil {
+			return 0, fmt.Errorf("listing the instances of %s: %+v", id, err)
+		}
+		return int64(len(result.Items)), nil
+	}
 
-	enabled := false
-	if input != nil && input.Enabled != nil {
-		enabled = *input.Enabled
+	log.Printf("[DEBUG] Waiting for the overprovisioned instances of %s to be removed..", id)
+	if err := waitForVirtualMachineScaleSetInstancesToSettle(ctx, capacity, virtualMachineScaleSetOverprovisionSettleTimeout, 15*time.Second, instanceCount); err != nil {
+		log.Printf("[WARN] waiting for the overprovisioned instances of %s to be removed: %+v", id, err)
+		return
 	}
+	log.Printf("[DEBUG] The overprovisioned instances of %s have been removed.", id)
+}
 
-	gracePeriod := "PT30M"
-	if input != nil && input.GracePeriod != nil {
-		gracePeriod = *input.GracePeriod
+// waitForVirtualMachineScaleSetInstancesToSettle polls the number of instances until it's no more than the requested capacity,
+// or the timeout (bounded by the context's deadline) elapses
+func waitForVirtualMachineScaleSetInstancesToSettle(ctx context.Context, capacity int64, timeout time.Duration, pollInterval time.Duration, instanceCount func(ctx context.Context) (int64, error)) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
 	}
 
-	return []interface{}{
-		map[string]interface{}{
-			"enabled":      enabled,
-			"grace_period": gracePeriod,
+	stateConf := &pluginsdk.StateChangeConf{
+		Pending: []string{"Overprovisioned"},
+		Target:  []string{"Settled"},
+		Refresh: func() (interface{}, string, error) {
+			count, err := instanceCount(ctx)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if count > capacity {
+				log.Printf("[DEBUG] %d instances exist but the capacity is %d", count, capacity)
+				return count, "Overprovisioned", nil
+			}
+
+			return count, "Settled", nil
 		},
+		PollInterval: pollInterval,
+		Timeout:      timeout,
 	}
-}
 
-func VirtualMachineScaleSetExtensionsSchema() *pluginsdk.Schema {
-	return &pluginsdk.Schema{
-		Type:     pluginsdk.TypeSet,
-		Optional: true,
-		Computed: true,
-		Elem: &pluginsdk.Resource{
-			Schema: map[string]*pluginsdk.Schema{
-				"name": {
-					Type:         pluginsdk.TypeString,
-					Required:     true,
-					ValidateFunc: validation.StringIsNotEmpty,
-				},
+	if _, err := stateConf.WaitForStateContext(ctx); err != nil {
+		return err
+	}
 
-				"publisher": {
-					Type:         pluginsdk.TypeString,
-					Required:     true,
-					ValidateFunc: validation.StringIsNotEmpty,
-				},
+	return nil
+}
 
-				"type": {
-					Type:         pluginsdk.TypeString,
-					Required:     true,
-					ValidateFunc: validation.StringIsNotEmpty,
-				},
+// virtualMachineScaleSetExtensionAutomaticUpgradeVersionWarnings returns a warning for extensions which have automatic upgrades
+// enabled whilst pinning a specific `type_handler_version` - automatic upgrades take precedence, so the extension is still sent
+// with automatic upgrades enabled and will be upgraded beyond the pinned version as the Publisher releases new versions
+func virtualMachineScaleSetExtensionAutomaticUpgradeVersionWarnings(input []interface{}) diag.Diagnostics {
+	var warnings diag.Diagnostics
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		if enabled, ok := extensionRaw["automatic_upgrade_enabled"].(bool); !ok || !enabled {
+			continue
+		}
 
-				"type_handler_version": {
-					Type:         pluginsdk.TypeString,
-					Required:     true,
-					ValidateFunc: validation.StringIsNotEmpty,
-				},
+		version := extensionRaw["type_handler_version"].(string)
+		if !virtualMachineScaleSetExtensionVersionIsPinned(version) {
+			continue
+		}
 
-				"auto_upgrade_minor_version": {
-					Type:     pluginsdk.TypeBool,
-					Optional: true,
-					Default:  true,
-				},
+		majorVersion := strings.Split(version, ".")[0]
+		warnings = append(warnings, virtualMachineScaleSetWarning("Extension version is superseded by automatic upgrades", fmt.Sprintf("the Extension %q has `automatic_upgrade_enabled` set to `true` but pins `type_handler_version` to %q - automatic upgrades take precedence, so the Extension will be upgraded beyond this version as new versions are released. `type_handler_version` should be set to %q instead", extensionRaw["name"].(string), version, fmt.Sprintf("%s.0", majorVersion))))
+	}
 
-				"automatic_upgrade_enabled": {
-					Type:     pluginsdk.TypeBool,
-					Optional: true,
-				},
+	return warnings
+}
 
-				"force_update_tag": {
-					Type:     pluginsdk.TypeString,
-					Optional: true,
-				},
+// virtualMachineScaleSetExtensionVersionIsPinned returns whether the version pins more than the major version of an
+// extension, e.g. `1.2` or `1.0.3` rather than `1` or `1.0`
+func virtualMachineScaleSetExtensionVersionIsPinned(version string) bool {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) > 2 {
+		return true
+	}
 
-				"protected_settings": {
-					Type:         pluginsdk.TypeString,
-					Optional:     true,
-					Sensitive:    true,
-					ValidateFunc: validation.StringIsJSON,
-				},
+	return len(parts) == 2 && strings.TrimLeft(parts[1], "0") != ""
+}
 
-				// Need to check `protected_settings_from_key_vault` conflicting with `protected_settings` in iteration
-				"protected_settings_from_key_vault": protectedSettingsFromKeyVaultSchema(false),
+// virtualMachineScaleSetHealthExtensionOrderingWarnings returns a warning when the health extension doesn't depend on any of the
+// other extensions, since it should typically be provisioned last so that it monitors a fully configured instance
+func virtualMachineScaleSetHealthExtensionOrderingWarnings(input []interface{}) diag.Diagnostics {
+	healthExtensionName := ""
+	healthExtensionHasDependencies := false
+	otherExtensions := make([]string, 0)
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		if !isVirtualMachineScaleSetHealthExtension(extensionRaw["type"].(string)) {
+			otherExtensions = append(otherExtensions, extensionRaw["name"].(string))
+			continue
+		}
 
-				"provision_after_extensions": {
-					Type:     pluginsdk.TypeList,
-					Optional: true,
-					Elem: &pluginsdk.Schema{
-						Type: pluginsdk.TypeString,
-					},
-				},
+		healthExtensionName = extensionRaw["name"].(string)
+		if provisionAfter, ok := extensionRaw["provision_after_extensions"].([]interface{}); ok && len(provisionAfter) > 0 {
+			healthExtensionHasDependencies = true
+		}
+	}
 
-				"settings": {
-					Type:             pluginsdk.TypeString,
-					Optional:         true,
-					ValidateFunc:     validation.StringIsJSON,
-					DiffSuppressFunc: pluginsdk.SuppressJsonDiff,
-				},
-			},
-		},
-		Set: virtualMachineScaleSetExtensionHash,
+	if healthExtensionName == "" || healthExtensionHasDependencies || len(otherExtensions) == 0 {
+		return nil
+	}
+
+	sort.Strings(otherExtensions)
+	return diag.Diagnostics{
+		virtualMachineScaleSetWarning("Health Extension isn't provisioned last", fmt.Sprintf("the health extension %q has no `provision_after_extensions` and may start monitoring instances before the other extensions have been provisioned - consider setting `provision_after_extensions` to %q", healthExtensionName, otherExtensions)),
 	}
 }
 
-func virtualMachineScaleSetExtensionHash(v interface{}) int {
-	var buf bytes.Buffer
+// virtualMachineScaleSetZeroInstancesExtensionsWarnings returns a warning when Extensions are defined on a Scale Set with no
+// instances, to clarify that they're applied to the instances as they're created (rather than being ignored)
+func virtualMachineScaleSetZeroInstancesExtensionsWarnings(instances int, extensions []interface{}) diag.Diagnostics {
+	if instances != 0 || len(extensions) == 0 {
+		return nil
+	}
 
-	if m, ok := v.(map[string]interface{}); ok {
-		buf.WriteString(fmt.Sprintf("%s-", m["name"].(string)))
-		buf.WriteString(fmt.Sprintf("%s-", m["publisher"].(string)))
-		buf.WriteString(fmt.Sprintf("%s-", m["type"].(string)))
-		buf.WriteString(fmt.Sprintf("%s-", m["type_handler_version"].(string)))
-		buf.WriteString(fmt.Sprintf("%t-", m["auto_upgrade_minor_version"].(bool)))
+	names := make([]string, 0, len(extensions))
+	for _, v := range extensions {
+		names = append(names, fmt.Sprintf("%q", v.(map[string]interface{})["name"].(string)))
+	}
+	sort.Strings(names)
 
-		if v, ok = m["force_update_tag"]; ok {
-			buf.WriteString(fmt.Sprintf("%s-", v))
-		}
+	return diag.Diagnostics{
+		virtualMachineScaleSetWarning("Extensions won't run until instances are created", fmt.Sprintf("the Scale Set has no instances, so the Extensions %s won't run until instances are created - they'll be applied to each instance as it's created when the Scale Set is scaled out", strings.Join(names, ", "))),
+	}
+}
 
-		if v, ok := m["provision_after_extensions"]; ok {
-			buf.WriteString(fmt.Sprintf("%s-", v))
-		}
+// virtualMachineScaleSetZoneBalanceCapacityWarnings returns a warning when `zone_balance` is enabled but the number of instances
+// can't be spread evenly across the zones, in which case some zones will have an additional instance
+func virtualMachineScaleSetZoneBalanceCapacityWarnings(instances int, zones []string, zoneBalance bool) diag.Diagnostics {
+	if !zoneBalance || len(zones) < 2 || instances == 0 || instances%len(zones) == 0 {
+		return nil
+	}
 
-		// we need to ensure the whitespace is consistent
-		settings := m["settings"].(string)
-		if settings != "" {
-			expandedSettings, err := pluginsdk.ExpandJsonFromString(settings)
-			if err == nil {
-				serializedSettings, err := pluginsdk.FlattenJsonToString(expandedSettings)
-				if err == nil {
-					buf.WriteString(fmt.Sprintf("%s-", serializedSettings))
-				}
-			}
-		}
+	return diag.Diagnostics{
+		virtualMachineScaleSetWarning("Instances can't be spread evenly across zones", fmt.Sprintf("`zone_balance` is enabled but %d instances can't be spread evenly across the %d zones %q - %d zone(s) will have an additional instance, consider setting `instances` to a multiple of %d", instances, len(zones), zones, instances%len(zones), len(zones))),
+	}
+}
 
-		if v, ok := m["protected_settings"]; ok {
-			settings := v.(string)
-			if settings != "" {
-				expandedSettings, err := pluginsdk.ExpandJsonFromString(settings)
-				if err == nil {
-					serializedSettings, err := pluginsdk.FlattenJsonToString(expandedSettings)
-					if err == nil {
-						buf.WriteString(fmt.Sprintf("%s-", serializedSettings))
-					}
-				}
-			}
+// virtualMachineScaleSetKnownExtensionSettings contains checks for the settings of well-known extensions, keyed by
+// `{publisher}/{type}`. Each check receives the `settings` and `protected_settings` (merged, since most extensions accept keys
+// in either) and returns any misconfigurations - these are limited to keys which the extensions require, since the extensions
+// evolve independently of the provider.
+var virtualMachineScaleSetKnownExtensionSettings = map[string]func(settings map[string]interface{}) []string{
+	"Microsoft.Azure.Extensions/CustomScript": func(settings map[string]interface{}) []string {
+		if !virtualMachineScaleSetExtensionSettingsHasAnyKey(settings, "commandToExecute", "script") {
+			return []string{"one of `commandToExecute` or `script` should be specified"}
+		}
+		return nil
+	},
+	"Microsoft.Compute/CustomScriptExtension": func(settings map[string]interface{}) []string {
+		if !virtualMachineScaleSetExtensionSettingsHasAnyKey(settings, "commandToExecute") {
+			return []string{"`commandToExecute` should be specified"}
 		}
+		return nil
+	},
+	"Microsoft.Azure.Monitoring.DependencyAgent/DependencyAgentLinux":   virtualMachineScaleSetDependencyAgentSettingsErrors,
+	"Microsoft.Azure.Monitoring.DependencyAgent/DependencyAgentWindows": virtualMachineScaleSetDependencyAgentSettingsErrors,
+	"Microsoft.ManagedServices/ApplicationHealthLinux":                  virtualMachineScaleSetApplicationHealthSettingsErrors,
+	"Microsoft.ManagedServices/ApplicationHealthWindows":                virtualMachineScaleSetApplicationHealthSettingsErrors,
+}
 
-		if v, ok := m["protected_settings_from_key_vault"]; ok {
-			protectedSettingsFromKeyVault := v.([]interface{})
-			if len(protectedSettingsFromKeyVault) > 0 {
-				buf.WriteString(fmt.Sprintf("%s-", protectedSettingsFromKeyVault[0].(map[string]interface{})["secret_url"].(string)))
-				buf.WriteString(fmt.Sprintf("%s-", protectedSettingsFromKeyVault[0].(map[string]interface{})["source_vault_id"].(string)))
-			}
+func virtualMachineScaleSetDependencyAgentSettingsErrors(settings map[string]interface{}) []string {
+	if v, ok := virtualMachineScaleSetExtensionSettingsValue(settings, "enableAMA"); ok {
+		if _, isBool := v.(bool); !isBool {
+			return []string{fmt.Sprintf("`enableAMA` should be a boolean but got %v", v)}
 		}
 	}
-
-	return pluginsdk.HashString(buf.String())
+	return nil
 }
 
-func expandVirtualMachineScaleSetExtensions(input []interface{}) (extensionProfile *virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile, hasHealthExtension bool, err error) {
-	extensionProfile = &virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile{}
-	if len(input) == 0 {
-		return extensionProfile, false, nil
+func virtualMachineScaleSetApplicationHealthSettingsErrors(settings map[string]interface{}) []string {
+	protocol, ok := virtualMachineScaleSetExtensionSettingsValue(settings, "protocol")
+	if !ok {
+		return []string{"`protocol` should be specified"}
 	}
 
-	extensions := make([]virtualmachinescalesets.VirtualMachineScaleSetExtension, 0)
-	for _, v := range input {
-		extensionRaw := v.(map[string]interface{})
-		extension := virtualmachinescalesets.VirtualMachineScaleSetExtension{
-			Name: pointer.To(extensionRaw["name"].(string)),
+	errors := make([]string, 0)
+	switch strings.ToLower(fmt.Sprintf("%v", protocol)) {
+	case "tcp":
+		if !virtualMachineScaleSetExtensionSettingsHasAnyKey(settings, "port") {
+			errors = append(errors, "`port` should be specified when the `protocol` is `tcp`")
 		}
-		extensionType := extensionRaw["type"].(string)
-
-		extensionProps := virtualmachinescalesets.VirtualMachineScaleSetExtensionProperties{
-			Publisher:                pointer.To(extensionRaw["publisher"].(string)),
-			Type:                     &extensionType,
-			TypeHandlerVersion:       pointer.To(extensionRaw["type_handler_version"].(string)),
-			AutoUpgradeMinorVersion:  pointer.To(extensionRaw["auto_upgrade_minor_version"].(bool)),
-			EnableAutomaticUpgrade:   pointer.To(extensionRaw["automatic_upgrade_enabled"].(bool)),
-			ProvisionAfterExtensions: utils.ExpandStringSlice(extensionRaw["provision_after_extensions"].([]interface{})),
+	case "http", "https":
+		if !virtualMachineScaleSetExtensionSettingsHasAnyKey(settings, "requestPath") {
+			errors = append(errors, fmt.Sprintf("`requestPath` should be specified when the `protocol` is `%v`", protocol))
 		}
+	default:
+		errors = append(errors, fmt.Sprintf("`protocol` should be one of `tcp`, `http` or `https` but got %q", protocol))
+	}
 
-		if extensionType == "ApplicationHealthLinux" || extensionType == "ApplicationHealthWindows" {
-			hasHealthExtension = true
+	return errors
+}
+
+// virtualMachineScaleSetExtensionSettingsValue returns the value of the (case-insensitive) key within the settings
+func virtualMachineScaleSetExtensionSettingsValue(settings map[string]interface{}, key string) (interface{}, bool) {
+	for k, v := range settings {
+		if strings.EqualFold(k, key) {
+			return v, true
 		}
+	}
+	return nil, false
+}
 
-		if forceUpdateTag := extensionRaw["force_update_tag"]; forceUpdateTag != nil {
-			extensionProps.ForceUpdateTag = pointer.To(forceUpdateTag.(string))
+func virtualMachineScaleSetExtensionSettingsHasAnyKey(settings map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := virtualMachineScaleSetExtensionSettingsValue(settings, key); ok {
+			return true
 		}
+	}
+	return false
+}
 
-		if val, ok := extensionRaw["settings"]; ok && val.(string) != "" {
-			var result interface{}
-			err := json.Unmarshal([]byte(val.(string)), &result)
-			if err != nil {
-				return nil, false, fmt.Errorf("unmarshaling `settings`: %+v", err)
-			}
-			extensionProps.Settings = pointer.To(result)
+// virtualMachineScaleSetExtensionSettingsWarnings returns a warning for each misconfiguration within the settings of the extensions,
+// based on the checks for well-known extensions in virtualMachineScaleSetKnownExtensionSettings. These are advisory since the
+// extensions evolve independently of the provider, so they mustn't block a configuration which the extension accepts.
+func virtualMachineScaleSetExtensionSettingsWarnings(input []interface{}) diag.Diagnostics {
+	var warnings diag.Diagnostics
+	for _, v := range input {
+		extensionRaw := v.(map[string]interface{})
+		check, ok := virtualMachineScaleSetKnownExtensionSettings[fmt.Sprintf("%s/%s", extensionRaw["publisher"].(string), extensionRaw["type"].(string))]
+		if !ok {
+			continue
 		}
 
-		protectedSettingsFromKeyVault := expandProtectedSettingsFromKeyVaultVMSS(extensionRaw["protected_settings_from_key_vault"].([]interface{}))
-		extensionProps.ProtectedSettingsFromKeyVault = protectedSettingsFromKeyVault
+		// the settings from Key Vault can't be inspected, so any required keys may be defined there
+		if protectedSettingsFromKeyVault, ok := extensionRaw["protected_settings_from_key_vault"].([]interface{}); ok && len(protectedSettingsFromKeyVault) > 0 {
+			continue
+		}
 
-		if val, ok := extensionRaw["protected_settings"]; ok && val.(string) != "" {
-			if protectedSettingsFromKeyVault != nil {
-				return nil, false, fmt.Errorf("`protected_settings_from_key_vault` cannot be used with `protected_settings`")
+		settings := make(map[string]interface{})
+		for _, key := range []string{"settings", "protected_settings"} {
+			raw, ok := extensionRaw[key].(string)
+			if !ok || raw == "" {
+				continue
 			}
 
-			var result interface{}
-			err := json.Unmarshal([]byte(val.(string)), &result)
-			if err != nil {
-				return nil, false, fmt.Errorf("unmarshaling `protected_settings`: %+v", err)
+			values := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(raw), &values); err != nil {
+				// invalid JSON is surfaced as an error elsewhere
+				continue
+			}
+			for k, v := range values {
+				settings[k] = v
 			}
-			extensionProps.ProtectedSettings = pointer.To(result)
 		}
 
-		extension.Properties = &extensionProps
-		extensions = append(extensions, extension)
+		for _, misconfiguration := range check(settings) {
+			warnings = append(warnings, virtualMachineScaleSetWarning("Extension settings may be misconfigured", fmt.Sprintf("the settings for the %s extension %q may be misconfigured: %s", extensionRaw["type"].(string), extensionRaw["name"].(string), misconfiguration)))
+		}
 	}
-	extensionProfile.Extensions = &extensions
 
-	return extensionProfile, hasHealthExtension, nil
+	return warnings
+}
+
+// virtualMachineScaleSetWarning returns a Warning diagnostic for an advisory check, which is surfaced to the user without
+// failing the apply
+func virtualMachineScaleSetWarning(summary string, detail string) diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  summary,
+		Detail:   detail,
+	}
 }
 
 func flattenVirtualMachineScaleSetExtensions(input *virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile, d *pluginsdk.ResourceData) ([]map[string]interface{}, error) {
-	result := make([]map[string]interface{}, 0)
 	if input == nil || input.Extensions == nil {
-		return result, nil
+		return make([]map[string]interface{}, 0), nil
 	}
 
 	// extensionsFromState holds the "extension" block, which is used to retrieve the "protected_settings" to fill it back the state,
-	// since it is not returned from the API.
+	// since it is not returned from the API. The Set is only iterated once here, so each extension is a single map lookup below.
 	extensionsFromState := map[string]map[string]interface{}{}
 	if extSet, ok := d.GetOk("extension"); ok && extSet != nil {
 		extensions := extSet.(*pluginsdk.Set).List()
@@ -2203,6 +4249,119 @@ func flattenVirtualMachineScaleSetExtensions(input *virtualmachinescalesets.Virt
 		}
 	}
 
+	return flattenVirtualMachineScaleSetExtensionsWithState(input, extensionsFromState)
+}
+
+// FlattenVirtualMachineScaleSetExtensionsForDataSource flattens the Extensions without requiring the existing state, for use
+// within Data Sources - as such `protected_settings` and `provisioning_timeout` are omitted since these aren't returned by the API
+func FlattenVirtualMachineScaleSetExtensionsForDataSource(input *virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile) ([]map[string]interface{}, error) {
+	result, err := flattenVirtualMachineScaleSetExtensionsWithState(input, map[string]map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, extension := range result {
+		delete(extension, "protected_settings")
+		delete(extension, "provisioning_timeout")
+	}
+
+	return result, nil
+}
+
+// virtualMachineScaleSetExtensionServerAddedSettings are the keys which the API is known to add to the `settings` of an Extension
+// when they've not been specified (for example the defaults of the Application Health extension), keyed by `{publisher}/{type}`.
+// These are ignored unless they're present in the user's `settings` to avoid a diff - further keys can be added here as they're
+// identified, other Extensions may legitimately use the same key names and so are left as-is
+var virtualMachineScaleSetExtensionServerAddedSettings = map[string][]string{
+	"Microsoft.ManagedServices/ApplicationHealthLinux": {
+		"gracePeriod",
+		"intervalInSeconds",
+		"numberOfProbes",
+	},
+	"Microsoft.ManagedServices/ApplicationHealthWindows": {
+		"gracePeriod",
+		"intervalInSeconds",
+		"numberOfProbes",
+	},
+}
+
+// removeVirtualMachineScaleSetExtensionServerAddedSettings returns a copy of the settings without the keys the API adds to the
+// settings of this Extension which aren't present in the user's settings
+func removeVirtualMachineScaleSetExtensionServerAddedSettings(publisher string, extensionType string, settings map[string]interface{}, userSettings map[string]interface{}) map[string]interface{} {
+	output := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		output[k] = v
+	}
+
+	for _, key := range virtualMachineScaleSetExtensionServerAddedSettings[fmt.Sprintf("%s/%s", publisher, extensionType)] {
+		if _, ok := userSettings[key]; !ok {
+			delete(output, key)
+		}
+	}
+
+	return output
+}
+
+// removeVirtualMachineScaleSetExtensionServerAddedSettingsFromState removes the server-added keys from the settings returned by the
+// API which aren't present in the settings within the state - nil is returned when only server-added keys were returned
+func removeVirtualMachineScaleSetExtensionServerAddedSettingsFromState(publisher string, extensionType string, settings interface{}, settingsFromState interface{}) interface{} {
+	settingsMap, ok := settings.(map[string]interface{})
+	if !ok {
+		return settings
+	}
+
+	userSettings := make(map[string]interface{})
+	if v, ok := settingsFromState.(string); ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &userSettings); err != nil {
+			return settings
+		}
+	}
+
+	output := removeVirtualMachineScaleSetExtensionServerAddedSettings(publisher, extensionType, settingsMap, userSettings)
+	if len(output) == 0 && len(settingsMap) > 0 {
+		return nil
+	}
+
+	return output
+}
+
+// virtualMachineScaleSetExtensionSettingsDiffSuppress suppresses the diff for semantically equal `settings`, including when the
+// only difference is keys the API adds to the settings of this Extension which aren't present in the configuration
+func virtualMachineScaleSetExtensionSettingsDiffSuppress(k, old, new string, d *pluginsdk.ResourceData) bool {
+	if pluginsdk.SuppressJsonDiff(k, old, new, d) {
+		return true
+	}
+
+	if d == nil {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(k, "settings")
+	publisher, _ := d.Get(prefix + "publisher").(string)
+	extensionType, _ := d.Get(prefix + "type").(string)
+	return virtualMachineScaleSetExtensionSettingsEqualIgnoringServerAdded(publisher, extensionType, old, new)
+}
+
+// virtualMachineScaleSetExtensionSettingsEqualIgnoringServerAdded returns whether the settings are equal once the keys the API adds
+// to the settings of this Extension, which aren't present in the new settings, are removed from the old settings
+func virtualMachineScaleSetExtensionSettingsEqualIgnoringServerAdded(publisher string, extensionType string, old string, new string) bool {
+	var oldSettings, newSettings map[string]interface{}
+	if err := json.Unmarshal([]byte(old), &oldSettings); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(new), &newSettings); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(removeVirtualMachineScaleSetExtensionServerAddedSettings(publisher, extensionType, oldSettings, newSettings), newSettings)
+}
+
+func flattenVirtualMachineScaleSetExtensionsWithState(input *virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile, extensionsFromState map[string]map[string]interface{}) ([]map[string]interface{}, error) {
+	if input == nil || input.Extensions == nil {
+		return make([]map[string]interface{}, 0), nil
+	}
+
+	result := make([]map[string]interface{}, 0, len(*input.Extensions))
 	for _, v := range *input.Extensions {
 		name := ""
 		if v.Name != nil {
@@ -2250,22 +4409,33 @@ func flattenVirtualMachineScaleSetExtensions(input *virtualmachinescalesets.Virt
 			}
 
 			if props.Settings != nil {
-				extSettingsRaw, err := json.Marshal(props.Settings)
-				if err != nil {
-					return nil, fmt.Errorf("marshaling `settings`: %+v", err)
+				settings := *props.Settings
+				if ext, ok := extensionsFromState[name]; ok {
+					settings = removeVirtualMachineScaleSetExtensionServerAddedSettingsFromState(extPublisher, extType, settings, ext["settings"])
+				}
+
+				if settings != nil {
+					extSettingsRaw, err := json.Marshal(settings)
+					if err != nil {
+						return nil, fmt.Errorf("marshaling `settings`: %+v", err)
+					}
+					extSettings = string(extSettingsRaw)
 				}
-				extSettings = string(extSettingsRaw)
 			}
 
 			protectedSettingsFromKeyVault = props.ProtectedSettingsFromKeyVault
 		}
-		// protected_settings isn't returned, so we attempt to get it from state otherwise set to empty string
+		// protected_settings and provisioning_timeout aren't returned, so we attempt to get them from state otherwise set to empty string
+		provisioningTimeout := ""
 		if ext, ok := extensionsFromState[name]; ok {
 			if protectedSettingsFromState, ok := ext["protected_settings"]; ok {
 				if protectedSettingsFromState.(string) != "" && protectedSettingsFromState.(string) != "{}" {
 					protectedSettings = protectedSettingsFromState.(string)
 				}
 			}
+			if provisioningTimeoutFromState, ok := ext["provisioning_timeout"]; ok {
+				provisioningTimeout = provisioningTimeoutFromState.(string)
+			}
 		}
 
 		result = append(result, map[string]interface{}{
@@ -2274,6 +4444,7 @@ func flattenVirtualMachineScaleSetExtensions(input *virtualmachinescalesets.Virt
 			"automatic_upgrade_enabled":         enableAutomaticUpgrade,
 			"force_update_tag":                  forceUpdateTag,
 			"provision_after_extensions":        provisionAfterExtension,
+			"provisioning_timeout":              provisioningTimeout,
 			"protected_settings":                protectedSettings,
 			"protected_settings_from_key_vault": flattenProtectedSettingsFromKeyVaultVMSS(protectedSettingsFromKeyVault),
 			"publisher":                         extPublisher,
@@ -45,8 +45,30 @@ func dataSourceVirtualMachineScaleSet() *pluginsdk.Resource {
 
 			"location": commonschema.LocationComputed(),
 
+			"edge_zone": commonschema.EdgeZoneComputed(),
+
+			"extensions_time_budget": {
+				Type:     pluginsdk.TypeString,
+				Computed: true,
+			},
+
 			"network_interface": VirtualMachineScaleSetNetworkInterfaceSchemaForDataSource(),
 
+			"orchestration_mode": {
+				Type:     pluginsdk.TypeString,
+				Computed: true,
+			},
+
+			"platform_fault_domain_count": {
+				Type:     pluginsdk.TypeInt,
+				Computed: true,
+			},
+
+			"single_placement_group": {
+				Type:     pluginsdk.TypeBool,
+				Computed: true,
+			},
+
 			"identity": commonschema.SystemAssignedUserAssignedIdentityComputed(),
 
 			"instances": {
@@ -114,9 +136,19 @@ func dataSourceVirtualMachineScaleSet() *pluginsdk.Resource {
 							Type:     pluginsdk.TypeString,
 							Computed: true,
 						},
+
+						"protected_from_scale_in": {
+							Type:     pluginsdk.TypeBool,
+							Computed: true,
+						},
 					},
 				},
 			},
+
+			"protected_from_scale_in_instance_count": {
+				Type:     pluginsdk.TypeInt,
+				Computed: true,
+			},
 		},
 	}
 }
@@ -147,6 +179,7 @@ func dataSourceVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta interf
 
 	if model := resp.Model; model != nil {
 		d.Set("location", location.Normalize(model.Location))
+		d.Set("edge_zone", flattenEdgeZone(model.ExtendedLocation))
 
 		identityFlattened, err := identity.FlattenSystemAndUserAssignedMap(model.Identity)
 		if err != nil {
@@ -157,7 +190,21 @@ func dataSourceVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta interf
 		}
 
 		if props := model.Properties; props != nil {
+			// the Orchestration Mode isn't returned for Scale Sets created prior to the introduction of Flexible Orchestration
+			orchestrationMode := string(virtualmachinescalesets.OrchestrationModeUniform)
+			if props.OrchestrationMode != nil {
+				orchestrationMode = string(*props.OrchestrationMode)
+			}
+			d.Set("orchestration_mode", orchestrationMode)
+			d.Set("platform_fault_domain_count", pointer.From(props.PlatformFaultDomainCount))
+			d.Set("single_placement_group", pointer.From(props.SinglePlacementGroup))
+
+			extensionsTimeBudget := ""
 			if profile := props.VirtualMachineProfile; profile != nil {
+				if profile.ExtensionProfile != nil {
+					extensionsTimeBudget = pointer.From(profile.ExtensionProfile.ExtensionsTimeBudget)
+				}
+
 				if nwProfile := profile.NetworkProfile; nwProfile != nil {
 					flattenedNics := FlattenVirtualMachineScaleSetNetworkInterface(nwProfile.NetworkInterfaceConfigurations)
 					if err := d.Set("network_interface", flattenedNics); err != nil {
@@ -165,6 +212,7 @@ func dataSourceVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta interf
 					}
 				}
 			}
+			d.Set("extensions_time_budget", extensionsTimeBudget)
 		}
 	}
 
@@ -207,6 +255,7 @@ func dataSourceVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta interf
 	if err := d.Set("instances", instances); err != nil {
 		return fmt.Errorf("setting `instances`: %+v", err)
 	}
+	d.Set("protected_from_scale_in_instance_count", virtualMachineScaleSetVMsProtectedFromScaleInCount(result.Items))
 
 	return nil
 }
@@ -289,6 +338,8 @@ func flattenVirtualMachineScaleSetVM(input virtualmachinescalesetvms.VirtualMach
 			output["virtual_machine_id"] = *props.VMId
 		}
 
+		output["protected_from_scale_in"] = virtualMachineScaleSetVMProtectedFromScaleIn(input)
+
 		if profile := props.OsProfile; profile != nil && profile.ComputerName != nil {
 			output["computer_name"] = *profile.ComputerName
 		}
@@ -321,3 +372,22 @@ func flattenVirtualMachineScaleSetVM(input virtualmachinescalesetvms.VirtualMach
 
 	return output
 }
+
+func virtualMachineScaleSetVMProtectedFromScaleIn(input virtualmachinescalesetvms.VirtualMachineScaleSetVM) bool {
+	if props := input.Properties; props != nil && props.ProtectionPolicy != nil {
+		return pointer.From(props.ProtectionPolicy.ProtectFromScaleIn)
+	}
+	return false
+}
+
+// virtualMachineScaleSetVMsProtectedFromScaleInCount returns the number of instances which are protected from scale-in, since
+// instance protection takes precedence over the scale-in policy and so limits how far the Scale Set can be scaled in
+func virtualMachineScaleSetVMsProtectedFromScaleInCount(input []virtualmachinescalesetvms.VirtualMachineScaleSetVM) int {
+	count := 0
+	for _, item := range input {
+		if virtualMachineScaleSetVMProtectedFromScaleIn(item) {
+			count++
+		}
+	}
+	return count
+}
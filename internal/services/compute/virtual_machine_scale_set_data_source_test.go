@@ -27,6 +27,8 @@ func TestAccDataSourceVirtualMachineScaleSet_basicLinux(t *testing.T) {
 				check.That(data.ResourceName).Key("instances.#").HasValue("1"),
 				check.That(data.ResourceName).Key("instances.0.instance_id").HasValue("0"),
 				check.That(data.ResourceName).Key("instances.0.private_ip_address").HasValue("10.0.2.4"),
+				check.That(data.ResourceName).Key("orchestration_mode").HasValue("Uniform"),
+				check.That(data.ResourceName).Key("extensions_time_budget").HasValue("PT1H30M"),
 			),
 		},
 	})
@@ -57,6 +59,8 @@ func TestAccDataSourceVirtualMachineScaleSet_orchestrated(t *testing.T) {
 			Config: r.orchestrated(data),
 			Check: acceptance.ComposeTestCheckFunc(
 				check.That(data.ResourceName).Key("id").Exists(),
+				check.That(data.ResourceName).Key("orchestration_mode").HasValue("Flexible"),
+				check.That(data.ResourceName).Key("platform_fault_domain_count").Exists(),
 			),
 		},
 	})
@@ -0,0 +1,3777 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesets"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetvms"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/applicationsecuritygroups"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/loadbalancers"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/services/compute/client"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/pluginsdk"
+)
+
+func buildVirtualMachineScaleSetExtensionsForTest(count int) (*virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile, map[string]map[string]interface{}) {
+	extensions := make([]virtualmachinescalesets.VirtualMachineScaleSetExtension, 0, count)
+	extensionsFromState := make(map[string]map[string]interface{}, count)
+
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("extension%d", i)
+		extensions = append(extensions, virtualmachinescalesets.VirtualMachineScaleSetExtension{
+			Name: pointer.To(name),
+			Properties: &virtualmachinescalesets.VirtualMachineScaleSetExtensionProperties{
+				Publisher:          pointer.To("Microsoft.Azure.Extensions"),
+				Type:               pointer.To("CustomScript"),
+				TypeHandlerVersion: pointer.To("2.0"),
+				Settings: pointer.To(interface{}(map[string]interface{}{
+					"commandToExecute": fmt.Sprintf("echo %d", i),
+				})),
+			},
+		})
+		extensionsFromState[name] = map[string]interface{}{
+			"name":               name,
+			"protected_settings": fmt.Sprintf(`{"secret":"%d"}`, i),
+		}
+	}
+
+	return &virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile{
+		Extensions: &extensions,
+	}, extensionsFromState
+}
+
+func TestFlattenVirtualMachineScaleSetExtensionsWithState(t *testing.T) {
+	input, extensionsFromState := buildVirtualMachineScaleSetExtensionsForTest(50)
+
+	actual, err := flattenVirtualMachineScaleSetExtensionsWithState(input, extensionsFromState)
+	if err != nil {
+		t.Fatalf("flattening extensions: %+v", err)
+	}
+
+	if len(actual) != 50 {
+		t.Fatalf("expected 50 extensions but got %d", len(actual))
+	}
+
+	for i, v := range actual {
+		expectedName := fmt.Sprintf("extension%d", i)
+		if v["name"] != expectedName {
+			t.Fatalf("expected extension %d to be named %q but got %q", i, expectedName, v["name"])
+		}
+
+		expectedProtectedSettings := fmt.Sprintf(`{"secret":"%d"}`, i)
+		if v["protected_settings"] != expectedProtectedSettings {
+			t.Fatalf("expected `protected_settings` for %q to be %q but got %q", expectedName, expectedProtectedSettings, v["protected_settings"])
+		}
+
+		expectedSettings := fmt.Sprintf(`{"commandToExecute":"echo %d"}`, i)
+		if v["settings"] != expectedSettings {
+			t.Fatalf("expected `settings` for %q to be %q but got %q", expectedName, expectedSettings, v["settings"])
+		}
+	}
+}
+
+func TestFlattenVirtualMachineScaleSetExtensionsWithState_notInState(t *testing.T) {
+	input, _ := buildVirtualMachineScaleSetExtensionsForTest(3)
+
+	actual, err := flattenVirtualMachineScaleSetExtensionsWithState(input, map[string]map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("flattening extensions: %+v", err)
+	}
+
+	for _, v := range actual {
+		if v["protected_settings"] != "" {
+			t.Fatalf("expected `protected_settings` for %q to be empty but got %q", v["name"], v["protected_settings"])
+		}
+	}
+}
+
+func TestFlattenVirtualMachineScaleSetExtensionsWithState_serverAddedSettings(t *testing.T) {
+	extensionOfType := func(name string, publisher string, extensionType string, settings interface{}) virtualmachinescalesets.VirtualMachineScaleSetExtension {
+		return virtualmachinescalesets.VirtualMachineScaleSetExtension{
+			Name: pointer.To(name),
+			Properties: &virtualmachinescalesets.VirtualMachineScaleSetExtensionProperties{
+				Publisher:          pointer.To(publisher),
+				Type:               pointer.To(extensionType),
+				TypeHandlerVersion: pointer.To("1.0"),
+				Settings:           pointer.To(settings),
+			},
+		}
+	}
+	extension := func(name string, settings interface{}) virtualmachinescalesets.VirtualMachineScaleSetExtension {
+		return extensionOfType(name, "Microsoft.ManagedServices", "ApplicationHealthLinux", settings)
+	}
+	input := &virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile{
+		Extensions: &[]virtualmachinescalesets.VirtualMachineScaleSetExtension{
+			extension("HealthExtension", map[string]interface{}{"port": float64(80), "protocol": "http", "intervalInSeconds": float64(5)}),
+			extension("UserAuthored", map[string]interface{}{"port": float64(80), "intervalInSeconds": float64(10)}),
+			extension("NoSettings", map[string]interface{}{"numberOfProbes": float64(1)}),
+			extension("Imported", map[string]interface{}{"port": float64(80), "intervalInSeconds": float64(5)}),
+			extensionOfType("CustomScript", "Microsoft.Azure.Extensions", "CustomScript", map[string]interface{}{"commandToExecute": "echo", "intervalInSeconds": float64(5)}),
+		},
+	}
+	extensionsFromState := map[string]map[string]interface{}{
+		"HealthExtension": {"settings": `{"port":80,"protocol":"http"}`},
+		"UserAuthored":    {"settings": `{"intervalInSeconds":10,"port":80}`},
+		"NoSettings":      {"settings": ""},
+		"CustomScript":    {"settings": `{"commandToExecute":"echo"}`},
+	}
+
+	actual, err := flattenVirtualMachineScaleSetExtensionsWithState(input, extensionsFromState)
+	if err != nil {
+		t.Fatalf("flattening extensions: %+v", err)
+	}
+
+	expected := map[string]string{
+		"HealthExtension": `{"port":80,"protocol":"http"}`,
+		"UserAuthored":    `{"intervalInSeconds":10,"port":80}`,
+		"NoSettings":      "",
+		// the user's settings aren't known when importing, so the settings are returned as-is
+		"Imported": `{"intervalInSeconds":5,"port":80}`,
+		// only the keys the API adds to the Application Health extension are removed
+		"CustomScript": `{"commandToExecute":"echo","intervalInSeconds":5}`,
+	}
+	for _, v := range actual {
+		if v["settings"] != expected[v["name"].(string)] {
+			t.Fatalf("expected `settings` for %q to be %q but got %q", v["name"], expected[v["name"].(string)], v["settings"])
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetExtensionSettingsEqualIgnoringServerAdded(t *testing.T) {
+	cases := []struct {
+		name          string
+		publisher     string
+		extensionType string
+		old           string
+		new           string
+		equal         bool
+	}{
+		{
+			name:          "semantically equal",
+			publisher:     "Microsoft.ManagedServices",
+			extensionType: "ApplicationHealthLinux",
+			old:           `{"port":80,"protocol":"http"}`,
+			new:           `{"protocol": "http", "port": 80}`,
+			equal:         true,
+		},
+		{
+			name:          "server-added key",
+			publisher:     "Microsoft.ManagedServices",
+			extensionType: "ApplicationHealthLinux",
+			old:           `{"intervalInSeconds":5,"port":80,"protocol":"http"}`,
+			new:           `{"port":80,"protocol":"http"}`,
+			equal:         true,
+		},
+		{
+			name:          "server-added key on Windows",
+			publisher:     "Microsoft.ManagedServices",
+			extensionType: "ApplicationHealthWindows",
+			old:           `{"gracePeriod":600,"port":80,"protocol":"http"}`,
+			new:           `{"port":80,"protocol":"http"}`,
+			equal:         true,
+		},
+		{
+			name:          "user-authored key changed",
+			publisher:     "Microsoft.ManagedServices",
+			extensionType: "ApplicationHealthLinux",
+			old:           `{"intervalInSeconds":5,"port":80}`,
+			new:           `{"intervalInSeconds":10,"port":80}`,
+			equal:         false,
+		},
+		{
+			name:          "same key on another extension",
+			publisher:     "Microsoft.Azure.Extensions",
+			extensionType: "CustomScript",
+			old:           `{"commandToExecute":"echo","intervalInSeconds":5}`,
+			new:           `{"commandToExecute":"echo"}`,
+			equal:         false,
+		},
+		{
+			name:          "other key added by the API",
+			publisher:     "Microsoft.ManagedServices",
+			extensionType: "ApplicationHealthLinux",
+			old:           `{"port":80,"unknown":true}`,
+			new:           `{"port":80}`,
+			equal:         false,
+		},
+		{
+			name:          "settings removed",
+			publisher:     "Microsoft.ManagedServices",
+			extensionType: "ApplicationHealthLinux",
+			old:           `{"port":80}`,
+			new:           "",
+			equal:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := virtualMachineScaleSetExtensionSettingsEqualIgnoringServerAdded(tc.publisher, tc.extensionType, tc.old, tc.new); actual != tc.equal {
+			t.Fatalf("expected the settings for %q to be equal: %t but got %t", tc.name, tc.equal, actual)
+		}
+	}
+}
+
+func BenchmarkFlattenVirtualMachineScaleSetExtensionsWithState(b *testing.B) {
+	input, extensionsFromState := buildVirtualMachineScaleSetExtensionsForTest(50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := flattenVirtualMachineScaleSetExtensionsWithState(input, extensionsFromState); err != nil {
+			b.Fatalf("flattening extensions: %+v", err)
+		}
+	}
+}
+
+func TestFlattenVirtualMachineScaleSetExtensionsForDataSource(t *testing.T) {
+	input := &virtualmachinescalesets.VirtualMachineScaleSetExtensionProfile{
+		Extensions: &[]virtualmachinescalesets.VirtualMachineScaleSetExtension{
+			{
+				Name: pointer.To("CustomScript"),
+				Properties: &virtualmachinescalesets.VirtualMachineScaleSetExtensionProperties{
+					Publisher:          pointer.To("Microsoft.Azure.Extensions"),
+					Type:               pointer.To("CustomScript"),
+					TypeHandlerVersion: pointer.To("2.0"),
+					Settings: pointer.To(interface{}(map[string]interface{}{
+						"commandToExecute": "echo $HOSTNAME",
+					})),
+				},
+			},
+		},
+	}
+
+	actual, err := FlattenVirtualMachineScaleSetExtensionsForDataSource(input)
+	if err != nil {
+		t.Fatalf("flattening the extensions: %+v", err)
+	}
+	if len(actual) != 1 {
+		t.Fatalf("expected 1 extension but got %d", len(actual))
+	}
+
+	extension := actual[0]
+	if extension["name"] != "CustomScript" || extension["type_handler_version"] != "2.0" || extension["settings"] != `{"commandToExecute":"echo $HOSTNAME"}` {
+		t.Fatalf("expected the public properties of the extension to be flattened but got %+v", extension)
+	}
+	for _, key := range []string{"protected_settings", "provisioning_timeout"} {
+		if _, ok := extension[key]; ok {
+			t.Fatalf("expected %q to be omitted but got %+v", key, extension)
+		}
+	}
+
+	if actual, err := FlattenVirtualMachineScaleSetExtensionsForDataSource(nil); err != nil || len(actual) != 0 {
+		t.Fatalf("expected no extensions when the profile is nil but got %+v / %+v", actual, err)
+	}
+}
+
+func TestExpandVirtualMachineScaleSetDataDisk_writeAcceleratorCaching(t *testing.T) {
+	cases := map[string]bool{
+		string(virtualmachinescalesets.CachingTypesNone):      false,
+		string(virtualmachinescalesets.CachingTypesReadOnly):  false,
+		string(virtualmachinescalesets.CachingTypesReadWrite): true,
+	}
+
+	for caching, shouldError := range cases {
+		input := []interface{}{
+			map[string]interface{}{
+				"caching":                        caching,
+				"create_option":                  string(virtualmachinescalesets.DiskCreateOptionTypesEmpty),
+				"disk_encryption_set_id":         "",
+				"disk_size_gb":                   10,
+				"lun":                            1,
+				"storage_account_type":           string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
+				"ultra_ssd_disk_iops_read_write": 0,
+				"ultra_ssd_disk_mbps_read_write": 0,
+				"write_accelerator_enabled":      true,
+			},
+		}
+
+		_, err := ExpandVirtualMachineScaleSetDataDisk(input, false, []string{})
+		if shouldError && err == nil {
+			t.Fatalf("expected an error for caching %q but didn't get one", caching)
+		}
+		if !shouldError && err != nil {
+			t.Fatalf("expected no error for caching %q but got: %+v", caching, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetDataDisk_diskPerformanceAliases(t *testing.T) {
+	dataDisk := func(storageAccountType string, ultraSSDIops, diskIops, ultraSSDMbps, diskMbps int) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"caching":                        string(virtualmachinescalesets.CachingTypesNone),
+				"create_option":                  string(virtualmachinescalesets.DiskCreateOptionTypesEmpty),
+				"disk_encryption_set_id":         "",
+				"disk_iops_read_write":           diskIops,
+				"disk_mbps_read_write":           diskMbps,
+				"disk_size_gb":                   10,
+				"lun":                            1,
+				"storage_account_type":           storageAccountType,
+				"ultra_ssd_disk_iops_read_write": ultraSSDIops,
+				"ultra_ssd_disk_mbps_read_write": ultraSSDMbps,
+				"write_accelerator_enabled":      false,
+			},
+		}
+	}
+	premiumV2 := string(virtualmachinescalesets.StorageAccountTypesPremiumVTwoLRS)
+
+	cases := []struct {
+		name         string
+		input        []interface{}
+		expectedIops int64
+		expectedMbps int64
+		shouldError  bool
+	}{
+		{
+			name:         "legacy fields",
+			input:        dataDisk(premiumV2, 3000, 0, 125, 0),
+			expectedIops: 3000,
+			expectedMbps: 125,
+		},
+		{
+			name:         "aliases",
+			input:        dataDisk(premiumV2, 0, 4000, 0, 150),
+			expectedIops: 4000,
+			expectedMbps: 150,
+		},
+		{
+			name:         "aliases changed with the legacy fields populated from the state",
+			input:        dataDisk(premiumV2, 3000, 4000, 125, 150),
+			expectedIops: 4000,
+			expectedMbps: 150,
+		},
+		{
+			name:        "aliases on an unsupported storage account type",
+			input:       dataDisk(string(virtualmachinescalesets.StorageAccountTypesPremiumLRS), 0, 4000, 0, 0),
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		disks, err := ExpandVirtualMachineScaleSetDataDisk(tc.input, false, []string{})
+		if tc.shouldError {
+			if err == nil || !strings.Contains(err.Error(), "`disk_iops_read_write`") {
+				t.Fatalf("expected an error naming `disk_iops_read_write` for %q but got: %+v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+
+		disk := (*disks)[0]
+		if pointer.From(disk.DiskIOPSReadWrite) != tc.expectedIops || pointer.From(disk.DiskMBpsReadWrite) != tc.expectedMbps {
+			t.Fatalf("expected %d IOPS and %d MBps for %q but got %d and %d", tc.expectedIops, tc.expectedMbps, tc.name, pointer.From(disk.DiskIOPSReadWrite), pointer.From(disk.DiskMBpsReadWrite))
+		}
+	}
+}
+
+func TestFlattenVirtualMachineScaleSetDataDisk_diskPerformanceAliases(t *testing.T) {
+	input := &[]virtualmachinescalesets.VirtualMachineScaleSetDataDisk{
+		{
+			Lun:               1,
+			DiskIOPSReadWrite: pointer.To(int64(4000)),
+			DiskMBpsReadWrite: pointer.To(int64(150)),
+		},
+		{
+			Lun:               2,
+			DiskIOPSReadWrite: pointer.To(int64(3000)),
+			DiskMBpsReadWrite: pointer.To(int64(125)),
+		},
+	}
+	existing := []interface{}{
+		map[string]interface{}{
+			"lun":                  1,
+			"disk_iops_read_write": 4000,
+			"disk_mbps_read_write": 150,
+		},
+		map[string]interface{}{
+			"lun":                  2,
+			"disk_iops_read_write": 0,
+			"disk_mbps_read_write": 0,
+		},
+	}
+
+	output := FlattenVirtualMachineScaleSetDataDisk(input, existing)
+
+	aliased := output[0].(map[string]interface{})
+	if aliased["disk_iops_read_write"] != 4000 || aliased["disk_mbps_read_write"] != 150 || aliased["ultra_ssd_disk_iops_read_write"] != 4000 {
+		t.Fatalf("expected both the aliases and the legacy fields to be set for the first Data Disk but got %+v", aliased)
+	}
+
+	legacy := output[1].(map[string]interface{})
+	if legacy["disk_iops_read_write"] != 0 || legacy["disk_mbps_read_write"] != 0 || legacy["ultra_ssd_disk_iops_read_write"] != 3000 {
+		t.Fatalf("expected only the legacy fields to be set for the second Data Disk but got %+v", legacy)
+	}
+}
+
+func TestExpandVirtualMachineScaleSetOSDisk_writeAcceleratorCaching(t *testing.T) {
+	cases := map[string]bool{
+		string(virtualmachinescalesets.CachingTypesNone):      false,
+		string(virtualmachinescalesets.CachingTypesReadOnly):  false,
+		string(virtualmachinescalesets.CachingTypesReadWrite): true,
+	}
+
+	for caching, shouldError := range cases {
+		input := []interface{}{
+			map[string]interface{}{
+				"caching":                          caching,
+				"diff_disk_settings":               []interface{}{},
+				"disk_encryption_set_id":           "",
+				"disk_size_gb":                     0,
+				"secure_vm_disk_encryption_set_id": "",
+				"security_encryption_type":         "",
+				"storage_account_type":             string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
+				"write_accelerator_enabled":        true,
+			},
+		}
+
+		_, err := ExpandVirtualMachineScaleSetOSDisk(input, virtualmachinescalesets.OperatingSystemTypesLinux)
+		if shouldError && err == nil {
+			t.Fatalf("expected an error for caching %q but didn't get one", caching)
+		}
+		if !shouldError && err != nil {
+			t.Fatalf("expected no error for caching %q but got: %+v", caching, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetOSDisk_diskEncryptionSets(t *testing.T) {
+	diskEncryptionSetId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/diskEncryptionSets/set1"
+	osDisk := func(diskEncryptionSetId, secureVMDiskEncryptionSetId string) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"caching":                          string(virtualmachinescalesets.CachingTypesReadWrite),
+				"diff_disk_settings":               []interface{}{},
+				"disk_encryption_set_id":           diskEncryptionSetId,
+				"disk_size_gb":                     0,
+				"secure_vm_disk_encryption_set_id": secureVMDiskEncryptionSetId,
+				"security_encryption_type":         string(virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState),
+				"storage_account_type":             string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
+				"write_accelerator_enabled":        false,
+			},
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		shouldError bool
+	}{
+		{
+			name:        "neither",
+			input:       osDisk("", ""),
+			shouldError: false,
+		},
+		{
+			name:        "disk encryption set",
+			input:       osDisk(diskEncryptionSetId, ""),
+			shouldError: false,
+		},
+		{
+			name:        "secure vm disk encryption set",
+			input:       osDisk("", diskEncryptionSetId),
+			shouldError: false,
+		},
+		{
+			name:        "both",
+			input:       osDisk(diskEncryptionSetId, diskEncryptionSetId),
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := ExpandVirtualMachineScaleSetOSDisk(tc.input, virtualmachinescalesets.OperatingSystemTypesLinux)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetOSDisk_storageAccountType(t *testing.T) {
+	cases := map[string]bool{
+		string(virtualmachinescalesets.StorageAccountTypesPremiumLRS):     false,
+		string(virtualmachinescalesets.StorageAccountTypesStandardSSDZRS): false,
+		string(virtualmachinescalesets.StorageAccountTypesUltraSSDLRS):    true,
+		string(virtualmachinescalesets.StorageAccountTypesPremiumVTwoLRS): true,
+	}
+
+	for storageAccountType, shouldError := range cases {
+		input := []interface{}{
+			map[string]interface{}{
+				"caching":                          string(virtualmachinescalesets.CachingTypesReadWrite),
+				"diff_disk_settings":               []interface{}{},
+				"disk_encryption_set_id":           "",
+				"disk_size_gb":                     0,
+				"secure_vm_disk_encryption_set_id": "",
+				"security_encryption_type":         "",
+				"storage_account_type":             storageAccountType,
+				"write_accelerator_enabled":        false,
+			},
+		}
+
+		_, err := ExpandVirtualMachineScaleSetOSDisk(input, virtualmachinescalesets.OperatingSystemTypesLinux)
+		if shouldError && (err == nil || !strings.Contains(err.Error(), storageAccountType)) {
+			t.Fatalf("expected an error naming the storage account type %q but got: %+v", storageAccountType, err)
+		}
+		if !shouldError && err != nil {
+			t.Fatalf("expected no error for storage account type %q but got: %+v", storageAccountType, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetOSDiskStorageAccountType(t *testing.T) {
+	cases := []struct {
+		input       string
+		message     string
+		shouldError bool
+	}{
+		{
+			input: string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
+		},
+		{
+			input: string(virtualmachinescalesets.StorageAccountTypesStandardSSDZRS),
+		},
+		{
+			input:       string(virtualmachinescalesets.StorageAccountTypesUltraSSDLRS),
+			message:     "OS Disks don't support",
+			shouldError: true,
+		},
+		{
+			input:       string(virtualmachinescalesets.StorageAccountTypesPremiumVTwoLRS),
+			message:     "OS Disks don't support",
+			shouldError: true,
+		},
+		{
+			input:       "Premium_GRS",
+			message:     "expected storage_account_type to be one of",
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		_, errors := validateVirtualMachineScaleSetOSDiskStorageAccountType(tc.input, "storage_account_type")
+		if tc.shouldError != (len(errors) > 0) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.input, tc.shouldError, errors)
+		}
+		if tc.shouldError && !strings.Contains(errors[0].Error(), tc.message) {
+			t.Fatalf("expected the error for %q to contain %q but got: %+v", tc.input, tc.message, errors[0])
+		}
+	}
+}
+
+func TestValidateConfidentialVMDiskEncryption(t *testing.T) {
+	cases := []struct {
+		securityEncryptionType virtualmachinescalesets.SecurityEncryptionTypes
+		vtpmEnabled            bool
+		secureBootEnabled      bool
+		shouldError            bool
+	}{
+		{
+			securityEncryptionType: "",
+			vtpmEnabled:            false,
+			secureBootEnabled:      false,
+			shouldError:            false,
+		},
+		{
+			securityEncryptionType: virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState,
+			vtpmEnabled:            true,
+			secureBootEnabled:      true,
+			shouldError:            false,
+		},
+		{
+			securityEncryptionType: virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState,
+			vtpmEnabled:            true,
+			secureBootEnabled:      false,
+			shouldError:            true,
+		},
+		{
+			securityEncryptionType: virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState,
+			vtpmEnabled:            false,
+			secureBootEnabled:      true,
+			shouldError:            true,
+		},
+		{
+			securityEncryptionType: virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState,
+			vtpmEnabled:            false,
+			secureBootEnabled:      false,
+			shouldError:            true,
+		},
+		{
+			securityEncryptionType: virtualmachinescalesets.SecurityEncryptionTypesVMGuestStateOnly,
+			vtpmEnabled:            true,
+			secureBootEnabled:      false,
+			shouldError:            false,
+		},
+		{
+			securityEncryptionType: virtualmachinescalesets.SecurityEncryptionTypesVMGuestStateOnly,
+			vtpmEnabled:            false,
+			secureBootEnabled:      true,
+			shouldError:            true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := ValidateConfidentialVMDiskEncryption(string(tc.securityEncryptionType), tc.vtpmEnabled, tc.secureBootEnabled)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q (vTPM %t / Secure Boot %t): %t but got: %+v", tc.securityEncryptionType, tc.vtpmEnabled, tc.secureBootEnabled, tc.shouldError, err)
+		}
+	}
+}
+
+func TestValidatePlanMatchesSourceImageReference(t *testing.T) {
+	reference := []interface{}{
+		map[string]interface{}{
+			"publisher": "Bitnami",
+			"offer":     "nginxstack",
+			"sku":       "1-9",
+			"version":   "latest",
+		},
+	}
+
+	cases := []struct {
+		name        string
+		plan        []interface{}
+		shouldError bool
+	}{
+		{
+			name:        "no plan",
+			plan:        []interface{}{},
+			shouldError: false,
+		},
+		{
+			name: "matching plan",
+			plan: []interface{}{
+				map[string]interface{}{
+					"name":      "1-9",
+					"product":   "nginxstack",
+					"publisher": "bitnami",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "mismatched product",
+			plan: []interface{}{
+				map[string]interface{}{
+					"name":      "1-9",
+					"product":   "wordpress",
+					"publisher": "bitnami",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "mismatched name",
+			plan: []interface{}{
+				map[string]interface{}{
+					"name":      "4-4",
+					"product":   "nginxstack",
+					"publisher": "bitnami",
+				},
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validatePlanMatchesSourceImageReference(tc.plan, reference)
+		if tc.shouldError && err == nil {
+			t.Fatalf("expected an error for %q but didn't get one", tc.name)
+		}
+		if !tc.shouldError && err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetExtensionHash_automaticUpgradeEnabled(t *testing.T) {
+	extension := func(automaticUpgradeEnabled bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                       "HealthExtension",
+			"publisher":                  "Microsoft.ManagedServices",
+			"type":                       "ApplicationHealthLinux",
+			"type_handler_version":       "1.0",
+			"auto_upgrade_minor_version": true,
+			"automatic_upgrade_enabled":  automaticUpgradeEnabled,
+			"force_update_tag":           "",
+			"provision_after_extensions": []interface{}{},
+			"settings":                   `{"port":80,"protocol":"http"}`,
+			"protected_settings":         "",
+		}
+	}
+
+	disabled := virtualMachineScaleSetExtensionHash(extension(false))
+	enabled := virtualMachineScaleSetExtensionHash(extension(true))
+	if disabled == enabled {
+		t.Fatalf("expected the hash to change when `automatic_upgrade_enabled` is toggled but got %d for both", enabled)
+	}
+
+	if again := virtualMachineScaleSetExtensionHash(extension(true)); again != enabled {
+		t.Fatalf("expected the hash to be stable but got %d and %d", enabled, again)
+	}
+}
+
+func TestVirtualMachineScaleSetHealthExtensionOrderingWarnings(t *testing.T) {
+	extension := func(name, extensionType string, provisionAfterExtensions ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                       name,
+			"type":                       extensionType,
+			"provision_after_extensions": provisionAfterExtensions,
+		}
+	}
+
+	cases := []struct {
+		name         string
+		input        []interface{}
+		shouldWarn   bool
+		warningMatch string
+	}{
+		{
+			name:       "no extensions",
+			input:      []interface{}{},
+			shouldWarn: false,
+		},
+		{
+			name: "health extension only",
+			input: []interface{}{
+				extension("HealthExtension", "ApplicationHealthLinux"),
+			},
+			shouldWarn: false,
+		},
+		{
+			name: "no health extension",
+			input: []interface{}{
+				extension("CustomScript", "CustomScript"),
+			},
+			shouldWarn: false,
+		},
+		{
+			name: "health extension without dependencies",
+			input: []interface{}{
+				extension("CustomScript", "CustomScript"),
+				extension("HealthExtension", "ApplicationHealthWindows"),
+			},
+			shouldWarn:   true,
+			warningMatch: `"HealthExtension"`,
+		},
+		{
+			name: "health extension provisioned after other extensions",
+			input: []interface{}{
+				extension("CustomScript", "CustomScript"),
+				extension("HealthExtension", "ApplicationHealthLinux", "CustomScript"),
+			},
+			shouldWarn: false,
+		},
+	}
+
+	for _, tc := range cases {
+		warnings := virtualMachineScaleSetHealthExtensionOrderingWarnings(tc.input)
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+		if tc.shouldWarn != (len(warnings) > 0) {
+			t.Fatalf("expected a warning for %q: %t but got: %+v", tc.name, tc.shouldWarn, warnings)
+		}
+		if tc.shouldWarn && !strings.Contains(warnings[0].Detail, tc.warningMatch) {
+			t.Fatalf("expected a warning containing %s for %q but got: %+v", tc.warningMatch, tc.name, warnings)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetExtensionAutomaticUpgradeVersionWarnings(t *testing.T) {
+	extension := func(version string, automaticUpgradeEnabled bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                              "Monitoring",
+			"publisher":                         "Microsoft.Azure.Monitor",
+			"type":                              "AzureMonitorLinuxAgent",
+			"type_handler_version":              version,
+			"auto_upgrade_minor_version":        true,
+			"automatic_upgrade_enabled":         automaticUpgradeEnabled,
+			"force_update_tag":                  "",
+			"provision_after_extensions":        []interface{}{},
+			"settings":                          "",
+			"protected_settings":                "",
+			"protected_settings_from_key_vault": []interface{}{},
+		}
+	}
+
+	cases := []struct {
+		name                    string
+		version                 string
+		automaticUpgradeEnabled bool
+		shouldWarn              bool
+	}{
+		{
+			name:                    "major version with automatic upgrades",
+			version:                 "1.0",
+			automaticUpgradeEnabled: true,
+			shouldWarn:              false,
+		},
+		{
+			name:                    "major version only with automatic upgrades",
+			version:                 "1",
+			automaticUpgradeEnabled: true,
+			shouldWarn:              false,
+		},
+		{
+			name:                    "pinned minor version with automatic upgrades",
+			version:                 "1.27",
+			automaticUpgradeEnabled: true,
+			shouldWarn:              true,
+		},
+		{
+			name:                    "pinned patch version with automatic upgrades",
+			version:                 "1.0.3",
+			automaticUpgradeEnabled: true,
+			shouldWarn:              true,
+		},
+		{
+			name:                    "pinned version without automatic upgrades",
+			version:                 "1.27",
+			automaticUpgradeEnabled: false,
+			shouldWarn:              false,
+		},
+	}
+
+	for _, tc := range cases {
+		input := []interface{}{extension(tc.version, tc.automaticUpgradeEnabled)}
+
+		warnings := virtualMachineScaleSetExtensionAutomaticUpgradeVersionWarnings(input)
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+		if tc.shouldWarn != (len(warnings) > 0) {
+			t.Fatalf("expected a warning for %q: %t but got: %+v", tc.name, tc.shouldWarn, warnings)
+		}
+		if tc.shouldWarn && !strings.Contains(warnings[0].Detail, `"1.0"`) {
+			t.Fatalf("expected the warning for %q to suggest the major version but got: %+v", tc.name, warnings)
+		}
+
+		// a pinned version must not prevent automatic upgrades from being enabled
+		profile, _, err := expandVirtualMachineScaleSetExtensions(input, "")
+		if err != nil {
+			t.Fatalf("expanding the extensions for %q: %+v", tc.name, err)
+		}
+		props := (*profile.Extensions)[0].Properties
+		if pointer.From(props.EnableAutomaticUpgrade) != tc.automaticUpgradeEnabled {
+			t.Fatalf("expected `EnableAutomaticUpgrade` to be %t for %q", tc.automaticUpgradeEnabled, tc.name)
+		}
+		if pointer.From(props.TypeHandlerVersion) != tc.version {
+			t.Fatalf("expected `TypeHandlerVersion` to be %q for %q but got %q", tc.version, tc.name, pointer.From(props.TypeHandlerVersion))
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetExtensionSettingsWarnings(t *testing.T) {
+	extension := func(publisher, extensionType, settings, protectedSettings string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                              "example",
+			"publisher":                         publisher,
+			"type":                              extensionType,
+			"settings":                          settings,
+			"protected_settings":                protectedSettings,
+			"protected_settings_from_key_vault": []interface{}{},
+		}
+	}
+
+	cases := []struct {
+		name         string
+		input        map[string]interface{}
+		warningMatch string
+	}{
+		{
+			name:         "custom script missing its required keys",
+			input:        extension("Microsoft.Azure.Extensions", "CustomScript", `{"fileUris": ["https://example.com/script.sh"]}`, ""),
+			warningMatch: "`commandToExecute` or `script`",
+		},
+		{
+			name:  "custom script with the command in the settings",
+			input: extension("Microsoft.Azure.Extensions", "CustomScript", `{"commandToExecute": "echo hello"}`, ""),
+		},
+		{
+			name:  "custom script with the command in the protected settings",
+			input: extension("Microsoft.Azure.Extensions", "CustomScript", `{"fileUris": []}`, `{"commandtoexecute": "echo hello"}`),
+		},
+		{
+			name: "custom script with the protected settings in key vault",
+			input: func() map[string]interface{} {
+				v := extension("Microsoft.Azure.Extensions", "CustomScript", "", "")
+				v["protected_settings_from_key_vault"] = []interface{}{map[string]interface{}{}}
+				return v
+			}(),
+		},
+		{
+			name:         "windows custom script missing its required keys",
+			input:        extension("Microsoft.Compute", "CustomScriptExtension", "", ""),
+			warningMatch: "`commandToExecute`",
+		},
+		{
+			name:         "dependency agent with an invalid setting",
+			input:        extension("Microsoft.Azure.Monitoring.DependencyAgent", "DependencyAgentLinux", `{"enableAMA": "yes"}`, ""),
+			warningMatch: "`enableAMA`",
+		},
+		{
+			name:  "dependency agent without settings",
+			input: extension("Microsoft.Azure.Monitoring.DependencyAgent", "DependencyAgentWindows", "", ""),
+		},
+		{
+			name:         "application health using tcp without a port",
+			input:        extension("Microsoft.ManagedServices", "ApplicationHealthLinux", `{"protocol": "tcp"}`, ""),
+			warningMatch: "`port`",
+		},
+		{
+			name:  "application health using http with a request path",
+			input: extension("Microsoft.ManagedServices", "ApplicationHealthWindows", `{"protocol": "http", "port": 80, "requestPath": "/health"}`, ""),
+		},
+		{
+			name:  "unknown extension",
+			input: extension("Example.Publisher", "Example", `{}`, ""),
+		},
+	}
+
+	for _, tc := range cases {
+		warnings := virtualMachineScaleSetExtensionSettingsWarnings([]interface{}{tc.input})
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+
+		if tc.warningMatch == "" {
+			if len(warnings) > 0 {
+				t.Fatalf("expected no warnings for %q but got: %+v", tc.name, warnings)
+			}
+			continue
+		}
+
+		if len(warnings) != 1 || !strings.Contains(warnings[0].Detail, tc.warningMatch) {
+			t.Fatalf("expected a warning containing %s for %q but got: %+v", tc.warningMatch, tc.name, warnings)
+		}
+	}
+}
+
+func TestLinuxVirtualMachineScaleSetResource_planWithMisconfiguredExtensionSettings(t *testing.T) {
+	// the checks on the Extensions within the CustomizeDiff are only performed from 4.0
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+
+	// these settings are both missing the keys required by the extension and contain keys which look sensitive, which
+	// should be surfaced as warnings rather than preventing the plan
+	err := planLinuxVirtualMachineScaleSetForTest([]interface{}{
+		map[string]interface{}{
+			"name":                 "CustomScript",
+			"publisher":            "Microsoft.Azure.Extensions",
+			"type":                 "CustomScript",
+			"type_handler_version": "2.0",
+			"settings":             `{"fileUris": ["https://example.com/script.sh"], "password": "P@55w0rd1234!"}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected the plan to succeed but got: %+v", err)
+	}
+}
+
+func TestLinuxVirtualMachineScaleSetResource_planWithPinnedExtensionVersionAndAutomaticUpgrades(t *testing.T) {
+	// the checks on the Extensions within the CustomizeDiff are only performed from 4.0
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+
+	err := planLinuxVirtualMachineScaleSetForTest([]interface{}{
+		map[string]interface{}{
+			"name":                      "Monitoring",
+			"publisher":                 "Microsoft.Azure.Monitor",
+			"type":                      "AzureMonitorLinuxAgent",
+			"type_handler_version":      "1.27",
+			"automatic_upgrade_enabled": true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected the plan to succeed but got: %+v", err)
+	}
+}
+
+func TestLinuxVirtualMachineScaleSetResource_planWithTooManyLoadBalancers(t *testing.T) {
+	pool := func(loadBalancerName string) string {
+		return fmt.Sprintf("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/loadBalancers/%s/backendAddressPools/pool1", loadBalancerName)
+	}
+
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":                            "example",
+		"resource_group_name":             "example",
+		"location":                        "westeurope",
+		"sku":                             "Standard_F2",
+		"instances":                       1,
+		"admin_username":                  "adminuser",
+		"admin_password":                  "P@55w0rd1234!",
+		"disable_password_authentication": false,
+		"network_interface": []interface{}{
+			map[string]interface{}{
+				"name":    "example",
+				"primary": true,
+				"ip_configuration": []interface{}{
+					map[string]interface{}{
+						"name":                                   "internal",
+						"primary":                                true,
+						"subnet_id":                              "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/virtualNetworks/vnet1/subnets/subnet1",
+						"load_balancer_backend_address_pool_ids": []interface{}{pool("lb1"), pool("lb2"), pool("lb3")},
+					},
+				},
+			},
+		},
+	})
+
+	_, err := resourceLinuxVirtualMachineScaleSet().Diff(context.Background(), nil, config, nil)
+	if err == nil || !strings.Contains(err.Error(), "/loadBalancers/lb3") {
+		t.Fatalf("expected the plan to fail listing the conflicting Load Balancers but got: %+v", err)
+	}
+}
+
+func TestLinuxVirtualMachineScaleSetResource_planSingleIPConfigurationNotPrimary(t *testing.T) {
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":                            "example",
+		"resource_group_name":             "example",
+		"location":                        "westeurope",
+		"sku":                             "Standard_F2",
+		"instances":                       1,
+		"admin_username":                  "adminuser",
+		"admin_password":                  "P@55w0rd1234!",
+		"disable_password_authentication": false,
+		"network_interface": []interface{}{
+			map[string]interface{}{
+				"name":    "example",
+				"primary": true,
+				"ip_configuration": []interface{}{
+					map[string]interface{}{
+						"name":      "internal",
+						"subnet_id": "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/virtualNetworks/vnet1/subnets/subnet1",
+					},
+				},
+			},
+		},
+	})
+
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "false")
+	if _, err := resourceLinuxVirtualMachineScaleSet().Diff(context.Background(), nil, config, nil); err != nil {
+		t.Fatalf("expected a single non-primary IP Configuration to be left to the API prior to 4.0 but got: %+v", err)
+	}
+
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+	_, err := resourceLinuxVirtualMachineScaleSet().Diff(context.Background(), nil, config, nil)
+	if err == nil || !strings.Contains(err.Error(), `"internal"`) || !strings.Contains(err.Error(), `"example"`) {
+		t.Fatalf("expected the plan to fail naming the IP Configuration and Network Interface in 4.0 but got: %+v", err)
+	}
+}
+
+// planLinuxVirtualMachineScaleSetForTest computes the diff (including the CustomizeDiff) to create a Linux Virtual Machine
+// Scale Set with the specified Extensions
+func planLinuxVirtualMachineScaleSetForTest(extensions []interface{}) error {
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":                            "example",
+		"resource_group_name":             "example",
+		"location":                        "westeurope",
+		"sku":                             "Standard_F2",
+		"instances":                       1,
+		"admin_username":                  "adminuser",
+		"admin_password":                  "P@55w0rd1234!",
+		"disable_password_authentication": false,
+		"extension":                       extensions,
+	})
+
+	_, err := resourceLinuxVirtualMachineScaleSet().Diff(context.Background(), nil, config, nil)
+	return err
+}
+
+func TestLinuxVirtualMachineScaleSetResource_planCustomDataChange(t *testing.T) {
+	attributes := map[string]interface{}{
+		"name":                            "example",
+		"resource_group_name":             "example",
+		"location":                        "westeurope",
+		"sku":                             "Standard_F2",
+		"instances":                       1,
+		"admin_username":                  "adminuser",
+		"admin_password":                  "P@55w0rd1234!",
+		"disable_password_authentication": false,
+	}
+	existingCustomData := "IyEvYmluL2Jhc2g="
+
+	cases := []struct {
+		name              string
+		customData        string
+		fourPointOh       bool
+		expectRequiresNew bool
+	}{
+		{
+			name:              "changed",
+			customData:        "IyEvYmluL3No",
+			expectRequiresNew: false,
+		},
+		{
+			name:              "removed",
+			customData:        "",
+			expectRequiresNew: false,
+		},
+		{
+			name:              "removed in 4.0",
+			customData:        "",
+			fourPointOh:       true,
+			expectRequiresNew: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ARM_FOURPOINTZERO_BETA", strconv.FormatBool(tc.fourPointOh))
+
+			raw := make(map[string]interface{})
+			for k, v := range attributes {
+				raw[k] = v
+			}
+			if tc.customData != "" {
+				raw["custom_data"] = tc.customData
+			}
+
+			state := &terraform.InstanceState{
+				ID: "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/example/providers/Microsoft.Compute/virtualMachineScaleSets/example",
+				Attributes: map[string]string{
+					"id":                              "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/example/providers/Microsoft.Compute/virtualMachineScaleSets/example",
+					"name":                            "example",
+					"resource_group_name":             "example",
+					"location":                        "westeurope",
+					"sku":                             "Standard_F2",
+					"instances":                       "1",
+					"admin_username":                  "adminuser",
+					"admin_password":                  "P@55w0rd1234!",
+					"disable_password_authentication": "false",
+					"custom_data":                     existingCustomData,
+				},
+			}
+
+			diff, err := resourceLinuxVirtualMachineScaleSet().Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), nil)
+			if err != nil {
+				t.Fatalf("expected the plan to succeed but got: %+v", err)
+			}
+			if diff == nil || diff.Attributes["custom_data"] == nil {
+				t.Fatalf("expected a diff for `custom_data` but got: %+v", diff)
+			}
+			// the partial state means other attributes may also differ, so only the reason for `custom_data` is checked
+			if requiresNew := diff.Attributes["custom_data"].RequiresNew; requiresNew != tc.expectRequiresNew {
+				t.Fatalf("expected the change to `custom_data` to require replacement: %t but got %t", tc.expectRequiresNew, requiresNew)
+			}
+		})
+	}
+}
+
+func TestLinuxVirtualMachineScaleSetResource_planUltraSSDEnabledChange(t *testing.T) {
+	cases := []struct {
+		name              string
+		instances         string
+		expectRequiresNew bool
+	}{
+		{
+			name:              "without instances",
+			instances:         "0",
+			expectRequiresNew: false,
+		},
+		{
+			name:              "with instances",
+			instances:         "2",
+			expectRequiresNew: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"name":                            "example",
+				"resource_group_name":             "example",
+				"location":                        "westeurope",
+				"sku":                             "Standard_F2",
+				"instances":                       tc.instances,
+				"admin_username":                  "adminuser",
+				"admin_password":                  "P@55w0rd1234!",
+				"disable_password_authentication": false,
+				"additional_capabilities": []interface{}{
+					map[string]interface{}{
+						"ultra_ssd_enabled": true,
+					},
+				},
+			}
+
+			// `instances` is refreshed from the capacity of the Scale Set, so the state reflects the current number of instances
+			state := &terraform.InstanceState{
+				ID: "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/example/providers/Microsoft.Compute/virtualMachineScaleSets/example",
+				Attributes: map[string]string{
+					"id":                              "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/example/providers/Microsoft.Compute/virtualMachineScaleSets/example",
+					"name":                            "example",
+					"resource_group_name":             "example",
+					"location":                        "westeurope",
+					"sku":                             "Standard_F2",
+					"instances":                       tc.instances,
+					"admin_username":                  "adminuser",
+					"admin_password":                  "P@55w0rd1234!",
+					"disable_password_authentication": "false",
+					"additional_capabilities.#":       "1",
+					"additional_capabilities.0.ultra_ssd_enabled": "false",
+				},
+			}
+
+			diff, err := resourceLinuxVirtualMachineScaleSet().Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), nil)
+			if err != nil {
+				t.Fatalf("expected the plan to succeed but got: %+v", err)
+			}
+			if diff == nil || diff.Attributes["additional_capabilities.0.ultra_ssd_enabled"] == nil {
+				t.Fatalf("expected a diff for `ultra_ssd_enabled` but got: %+v", diff)
+			}
+			if requiresNew := diff.Attributes["additional_capabilities.0.ultra_ssd_enabled"].RequiresNew; requiresNew != tc.expectRequiresNew {
+				t.Fatalf("expected the change to `ultra_ssd_enabled` to require replacement: %t but got %t", tc.expectRequiresNew, requiresNew)
+			}
+		})
+	}
+}
+
+func TestValidateVirtualMachineScaleSetExtensionProvisioningTimeout(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{input: "", valid: false},
+		{input: "thirty minutes", valid: false},
+		{input: "PT0S", valid: false},
+		{input: "-5m", valid: false},
+		{input: "PT30M", valid: true},
+		{input: "PT1H30M", valid: true},
+		{input: "45m", valid: true},
+		{input: "1h", valid: true},
+	}
+
+	for _, tc := range cases {
+		_, errors := validateVirtualMachineScaleSetExtensionProvisioningTimeout(tc.input, "provisioning_timeout")
+		if valid := len(errors) == 0; valid != tc.valid {
+			t.Fatalf("expected %q to be valid: %t but got %t", tc.input, tc.valid, valid)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetExtensionProvisioningTimeouts(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"name":                 "first",
+			"provisioning_timeout": "PT30M",
+		},
+		map[string]interface{}{
+			"name":                 "second",
+			"provisioning_timeout": "",
+		},
+		map[string]interface{}{
+			"name":                 "third",
+			"provisioning_timeout": "15m",
+		},
+	}
+
+	actual := virtualMachineScaleSetExtensionProvisioningTimeouts(input)
+	if len(actual) != 2 || actual["first"] != 30*time.Minute || actual["third"] != 15*time.Minute {
+		t.Fatalf("expected the provisioning timeouts of `first` and `third` but got %+v", actual)
+	}
+}
+
+func TestVirtualMachineScaleSetProvisionedExtensions(t *testing.T) {
+	extension := func(name string, codes ...string) virtualmachinescalesets.VirtualMachineScaleSetVMExtensionsSummary {
+		statuses := make([]virtualmachinescalesets.VirtualMachineStatusCodeCount, 0)
+		for _, code := range codes {
+			statuses = append(statuses, virtualmachinescalesets.VirtualMachineStatusCodeCount{
+				Code:  pointer.To(code),
+				Count: pointer.To(int64(1)),
+			})
+		}
+		return virtualmachinescalesets.VirtualMachineScaleSetVMExtensionsSummary{
+			Name:            pointer.To(name),
+			StatusesSummary: &statuses,
+		}
+	}
+
+	actual := virtualMachineScaleSetProvisionedExtensions(&virtualmachinescalesets.VirtualMachineScaleSetInstanceView{
+		Extensions: &[]virtualmachinescalesets.VirtualMachineScaleSetVMExtensionsSummary{
+			extension("succeeded", "ProvisioningState/succeeded"),
+			extension("partial", "ProvisioningState/succeeded", "ProvisioningState/creating"),
+			extension("pending"),
+		},
+	})
+	if !actual["succeeded"] || actual["partial"] || actual["pending"] {
+		t.Fatalf("expected only `succeeded` to be provisioned but got %+v", actual)
+	}
+}
+
+func TestPollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(t *testing.T) {
+	// a poll which hangs until it's cancelled, like an Extension which never finishes provisioning
+	hangingPoll := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	provisioned := func(ctx context.Context) (map[string]bool, error) {
+		return map[string]bool{
+			"fast": true,
+			"slow": false,
+		}, nil
+	}
+
+	err := pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(context.TODO(), map[string]time.Duration{"fast": 10 * time.Millisecond, "slow": 20 * time.Millisecond}, time.Millisecond, hangingPoll, provisioned)
+	if err == nil || !strings.Contains(err.Error(), `"slow"`) {
+		t.Fatalf("expected the wait to be bounded by the provisioning timeout of `slow` but got: %+v", err)
+	}
+
+	// the timeout of an Extension which has been provisioned doesn't bound the wait
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	err = pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(ctx, map[string]time.Duration{"fast": 10 * time.Millisecond}, time.Millisecond, hangingPoll, provisioned)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected the wait to continue until the overall timeout but got: %+v", err)
+	}
+
+	err = pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(context.TODO(), map[string]time.Duration{"slow": time.Minute}, time.Millisecond, func(ctx context.Context) error {
+		return nil
+	}, provisioned)
+	if err != nil {
+		t.Fatalf("expected the poll to complete within the provisioning timeout but got: %+v", err)
+	}
+
+	failing := func(ctx context.Context) (map[string]bool, error) {
+		return nil, fmt.Errorf("internal server error")
+	}
+	ctx, cancel = context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	err = pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(ctx, map[string]time.Duration{"slow": time.Millisecond}, time.Millisecond, hangingPoll, failing)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected failing to retrieve the provisioning state not to end the wait but got: %+v", err)
+	}
+}
+
+func TestVirtualMachineScaleSetZeroInstancesExtensionsWarnings(t *testing.T) {
+	extensions := []interface{}{
+		map[string]interface{}{"name": "HealthExtension"},
+		map[string]interface{}{"name": "CustomScript"},
+	}
+
+	cases := []struct {
+		name         string
+		instances    int
+		extensions   []interface{}
+		shouldWarn   bool
+		warningMatch string
+	}{
+		{
+			name:       "zero instances without extensions",
+			instances:  0,
+			extensions: []interface{}{},
+			shouldWarn: false,
+		},
+		{
+			name:       "instances with extensions",
+			instances:  2,
+			extensions: extensions,
+			shouldWarn: false,
+		},
+		{
+			name:         "zero instances with extensions",
+			instances:    0,
+			extensions:   extensions,
+			shouldWarn:   true,
+			warningMatch: `"CustomScript", "HealthExtension"`,
+		},
+	}
+
+	for _, tc := range cases {
+		warnings := virtualMachineScaleSetZeroInstancesExtensionsWarnings(tc.instances, tc.extensions)
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+		if tc.shouldWarn != (len(warnings) > 0) {
+			t.Fatalf("expected a warning for %q: %t but got: %+v", tc.name, tc.shouldWarn, warnings)
+		}
+		if tc.shouldWarn && !strings.Contains(warnings[0].Detail, tc.warningMatch) {
+			t.Fatalf("expected the warning for %q to contain %s but got: %+v", tc.name, tc.warningMatch, warnings)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetZoneBalanceCapacityWarnings(t *testing.T) {
+	cases := []struct {
+		name        string
+		instances   int
+		zones       []string
+		zoneBalance bool
+		shouldWarn  bool
+	}{
+		{
+			name:        "odd capacity across three zones",
+			instances:   5,
+			zones:       []string{"1", "2", "3"},
+			zoneBalance: true,
+			shouldWarn:  true,
+		},
+		{
+			name:        "even capacity across three zones",
+			instances:   6,
+			zones:       []string{"1", "2", "3"},
+			zoneBalance: true,
+			shouldWarn:  false,
+		},
+		{
+			name:        "odd capacity across three zones without zone balance",
+			instances:   5,
+			zones:       []string{"1", "2", "3"},
+			zoneBalance: false,
+			shouldWarn:  false,
+		},
+		{
+			name:        "single zone",
+			instances:   5,
+			zones:       []string{"1"},
+			zoneBalance: true,
+			shouldWarn:  false,
+		},
+		{
+			name:        "no instances",
+			instances:   0,
+			zones:       []string{"1", "2", "3"},
+			zoneBalance: true,
+			shouldWarn:  false,
+		},
+	}
+
+	for _, tc := range cases {
+		warnings := virtualMachineScaleSetZoneBalanceCapacityWarnings(tc.instances, tc.zones, tc.zoneBalance)
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+		if tc.shouldWarn != (len(warnings) > 0) {
+			t.Fatalf("expected a warning for %q: %t but got: %+v", tc.name, tc.shouldWarn, warnings)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetOSDiskSizeIncreaseWarnings(t *testing.T) {
+	cases := []struct {
+		name       string
+		oldSizeGB  int
+		newSizeGB  int
+		shouldWarn bool
+	}{
+		{
+			name:       "increased",
+			oldSizeGB:  30,
+			newSizeGB:  64,
+			shouldWarn: true,
+		},
+		{
+			name:       "set from the image default",
+			oldSizeGB:  0,
+			newSizeGB:  64,
+			shouldWarn: true,
+		},
+		{
+			name:       "unchanged",
+			oldSizeGB:  64,
+			newSizeGB:  64,
+			shouldWarn: false,
+		},
+		{
+			name:       "removed",
+			oldSizeGB:  64,
+			newSizeGB:  0,
+			shouldWarn: false,
+		},
+	}
+
+	for _, tc := range cases {
+		warnings := virtualMachineScaleSetOSDiskSizeIncreaseWarnings(tc.oldSizeGB, tc.newSizeGB)
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+		if tc.shouldWarn != (len(warnings) > 0) {
+			t.Fatalf("expected a warning for %q: %t but got: %+v", tc.name, tc.shouldWarn, warnings)
+		}
+		if tc.shouldWarn && !strings.Contains(warnings[0].Detail, "filesystem within the guest OS must be extended separately") {
+			t.Fatalf("expected the warning for %q to mention extending the guest filesystem but got: %+v", tc.name, warnings)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetIPv6LoadBalancerWarnings(t *testing.T) {
+	networkInterface := func(version string, backendAddressPoolIds ...interface{}) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"ip_configuration": []interface{}{
+					map[string]interface{}{
+						"name":                                   "internal",
+						"version":                                version,
+						"load_balancer_backend_address_pool_ids": pluginsdk.NewSet(pluginsdk.HashString, backendAddressPoolIds),
+					},
+				},
+			},
+		}
+	}
+	backendAddressPoolId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/loadBalancers/lb1/backendAddressPools/pool1"
+
+	cases := []struct {
+		name       string
+		input      []interface{}
+		shouldWarn bool
+	}{
+		{
+			name:       "IPv4 with a backend address pool",
+			input:      networkInterface("IPv4", backendAddressPoolId),
+			shouldWarn: false,
+		},
+		{
+			name:       "IPv6 without a backend address pool",
+			input:      networkInterface("IPv6"),
+			shouldWarn: false,
+		},
+		{
+			name:       "IPv6 with a backend address pool",
+			input:      networkInterface("IPv6", backendAddressPoolId),
+			shouldWarn: true,
+		},
+	}
+
+	for _, tc := range cases {
+		warnings := virtualMachineScaleSetIPv6LoadBalancerWarnings(tc.input)
+		if warnings.HasError() {
+			t.Fatalf("expected only warnings for %q but got: %+v", tc.name, warnings)
+		}
+		if tc.shouldWarn != (len(warnings) > 0) {
+			t.Fatalf("expected a warning for %q: %t but got: %+v", tc.name, tc.shouldWarn, warnings)
+		}
+		if tc.shouldWarn && !strings.Contains(warnings[0].Detail, `"internal"`) {
+			t.Fatalf("expected the warning for %q to name the IP Configuration but got: %+v", tc.name, warnings)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetEncryptionAtHost(t *testing.T) {
+	if actual := FlattenVirtualMachineScaleSetEncryptionAtHost(ExpandVirtualMachineScaleSetEncryptionAtHost(nil, true)); !actual {
+		t.Fatalf("expected `encryption_at_host_enabled` to round-trip as true but got false")
+	}
+
+	// the other settings within the Security Profile should be retained
+	existing := &virtualmachinescalesets.SecurityProfile{
+		UefiSettings: &virtualmachinescalesets.UefiSettings{
+			SecureBootEnabled: pointer.To(true),
+		},
+	}
+	actual := ExpandVirtualMachineScaleSetEncryptionAtHost(existing, false)
+	if actual.UefiSettings == nil || !pointer.From(actual.UefiSettings.SecureBootEnabled) {
+		t.Fatalf("expected the existing `UefiSettings` to be retained")
+	}
+	if FlattenVirtualMachineScaleSetEncryptionAtHost(actual) {
+		t.Fatalf("expected `encryption_at_host_enabled` to round-trip as false but got true")
+	}
+
+	if FlattenVirtualMachineScaleSetEncryptionAtHost(nil) {
+		t.Fatalf("expected `encryption_at_host_enabled` to default to false when the Security Profile is omitted")
+	}
+}
+
+func TestValidateVirtualMachineScaleSetEncryptionAtHostSupported(t *testing.T) {
+	cases := []struct {
+		name        string
+		sku         *client.SkuCapabilities
+		shouldError bool
+	}{
+		{
+			name:        "SKU not found",
+			sku:         nil,
+			shouldError: false,
+		},
+		{
+			name: "supported",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_D2s_v3",
+				Capabilities: map[string]string{
+					"EncryptionAtHostSupported": "True",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "unsupported",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_A1_v2",
+				Capabilities: map[string]string{
+					"EncryptionAtHostSupported": "False",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "capability omitted",
+			sku: &client.SkuCapabilities{
+				Name:         "Standard_A1_v2",
+				Capabilities: map[string]string{},
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetEncryptionAtHostSupported(tc.sku, "westeurope", "Standard_F2")
+		if tc.shouldError && err == nil {
+			t.Fatalf("expected an error for %q but didn't get one", tc.name)
+		}
+		if !tc.shouldError && err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetAdditionalCapabilitiesUpdate(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"ultra_ssd_enabled": true,
+		},
+	}
+
+	cases := []struct {
+		name              string
+		existingSku       *virtualmachinescalesets.Sku
+		orchestrationMode virtualmachinescalesets.OrchestrationMode
+		expectedError     string
+	}{
+		{
+			name:              "uniform without instances",
+			existingSku:       &virtualmachinescalesets.Sku{Capacity: pointer.To(int64(0))},
+			orchestrationMode: virtualmachinescalesets.OrchestrationModeUniform,
+		},
+		{
+			name:              "flexible without instances",
+			existingSku:       &virtualmachinescalesets.Sku{Capacity: pointer.To(int64(0))},
+			orchestrationMode: virtualmachinescalesets.OrchestrationModeFlexible,
+		},
+		{
+			name:              "flexible without a sku",
+			existingSku:       nil,
+			orchestrationMode: virtualmachinescalesets.OrchestrationModeFlexible,
+		},
+		{
+			name:              "uniform with instances",
+			existingSku:       &virtualmachinescalesets.Sku{Capacity: pointer.To(int64(2))},
+			orchestrationMode: virtualmachinescalesets.OrchestrationModeUniform,
+			expectedError:     "Uniform Scale Set",
+		},
+		{
+			name:              "flexible with instances",
+			existingSku:       &virtualmachinescalesets.Sku{Capacity: pointer.To(int64(2))},
+			orchestrationMode: virtualmachinescalesets.OrchestrationModeFlexible,
+			expectedError:     "Flexible Scale Set",
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := ExpandVirtualMachineScaleSetAdditionalCapabilitiesUpdate(input, tc.existingSku, tc.orchestrationMode)
+		if tc.expectedError != "" {
+			if err == nil {
+				t.Fatalf("expected an error for %q but didn't get one", tc.name)
+			}
+			if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("expected the error for %q to contain %q but got: %+v", tc.name, tc.expectedError, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+		if actual == nil || !pointer.From(actual.UltraSSDEnabled) {
+			t.Fatalf("expected `UltraSSDEnabled` to be true for %q", tc.name)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetDiskControllerType(t *testing.T) {
+	if actual := ExpandVirtualMachineScaleSetDiskControllerType(""); actual != nil {
+		t.Fatalf("expected no Disk Controller Type when unset but got %q", *actual)
+	}
+
+	if actual := ExpandVirtualMachineScaleSetDiskControllerType("NVMe"); pointer.From(actual) != "NVMe" {
+		t.Fatalf("expected the Disk Controller Type to be `NVMe` but got %q", pointer.From(actual))
+	}
+}
+
+func TestFlattenVirtualMachineScaleSetDiskControllerType(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    *string
+		expected string
+	}{
+		{
+			name:     "unset",
+			input:    nil,
+			expected: "",
+		},
+		{
+			name:     "NVMe",
+			input:    pointer.To("NVMe"),
+			expected: "NVMe",
+		},
+		{
+			name:     "SCSI",
+			input:    pointer.To("SCSI"),
+			expected: "SCSI",
+		},
+		{
+			name:     "NVMe with different casing",
+			input:    pointer.To("nvme"),
+			expected: "NVMe",
+		},
+		{
+			name:     "unknown value",
+			input:    pointer.To("Other"),
+			expected: "Other",
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := FlattenVirtualMachineScaleSetDiskControllerType(tc.input); actual != tc.expected {
+			t.Fatalf("expected %q for %q but got %q", tc.expected, tc.name, actual)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetOSDiskPlacementSupported(t *testing.T) {
+	cases := []struct {
+		name        string
+		sku         *client.SkuCapabilities
+		placement   virtualmachinescalesets.DiffDiskPlacement
+		shouldError bool
+	}{
+		{
+			name:        "SKU not found",
+			sku:         nil,
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			shouldError: false,
+		},
+		{
+			name: "cache disk placement on a SKU with a cache disk",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_D4s_v3",
+				Capabilities: map[string]string{
+					"CachedDiskBytes":     "107374182400",
+					"MaxResourceVolumeMB": "32768",
+				},
+			},
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			shouldError: false,
+		},
+		{
+			name: "cache disk placement on a SKU without a cache disk",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_D2d_v4",
+				Capabilities: map[string]string{
+					"MaxResourceVolumeMB": "76800",
+				},
+			},
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			shouldError: true,
+		},
+		{
+			name: "cache disk placement on a SKU with an empty cache disk",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_D2d_v4",
+				Capabilities: map[string]string{
+					"CachedDiskBytes":     "0",
+					"MaxResourceVolumeMB": "76800",
+				},
+			},
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			shouldError: true,
+		},
+		{
+			name: "resource disk placement on a SKU without a cache disk",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_D2d_v4",
+				Capabilities: map[string]string{
+					"MaxResourceVolumeMB": "76800",
+				},
+			},
+			placement:   virtualmachinescalesets.DiffDiskPlacementResourceDisk,
+			shouldError: false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetOSDiskPlacementSupported(tc.sku, "westeurope", "Standard_F2", tc.placement)
+		if tc.shouldError && err == nil {
+			t.Fatalf("expected an error for %q but didn't get one", tc.name)
+		}
+		if !tc.shouldError && err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetEphemeralOSDiskSize(t *testing.T) {
+	sku := &client.SkuCapabilities{
+		Name: "Standard_D4s_v3",
+		Capabilities: map[string]string{
+			"CachedDiskBytes":     "107374182400",
+			"MaxResourceVolumeMB": "32768",
+		},
+	}
+
+	cases := []struct {
+		name        string
+		sku         *client.SkuCapabilities
+		placement   virtualmachinescalesets.DiffDiskPlacement
+		diskSizeGB  int64
+		shouldError bool
+	}{
+		{
+			name:        "SKU not found",
+			sku:         nil,
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			diskSizeGB:  1024,
+			shouldError: false,
+		},
+		{
+			name:        "cache disk placement within the cache disk",
+			sku:         sku,
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			diskSizeGB:  100,
+			shouldError: false,
+		},
+		{
+			name:        "cache disk placement larger than the cache disk",
+			sku:         sku,
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			diskSizeGB:  101,
+			shouldError: true,
+		},
+		{
+			name:        "resource disk placement within the resource disk",
+			sku:         sku,
+			placement:   virtualmachinescalesets.DiffDiskPlacementResourceDisk,
+			diskSizeGB:  32,
+			shouldError: false,
+		},
+		{
+			name:        "resource disk placement larger than the resource disk",
+			sku:         sku,
+			placement:   virtualmachinescalesets.DiffDiskPlacementResourceDisk,
+			diskSizeGB:  64,
+			shouldError: true,
+		},
+		{
+			name: "cache disk placement on a SKU without a cache disk",
+			sku: &client.SkuCapabilities{
+				Name: "Standard_D2d_v4",
+				Capabilities: map[string]string{
+					"MaxResourceVolumeMB": "76800",
+				},
+			},
+			placement:   virtualmachinescalesets.DiffDiskPlacementCacheDisk,
+			diskSizeGB:  1024,
+			shouldError: false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetEphemeralOSDiskSize(tc.sku, "westeurope", "Standard_D4s_v3", tc.placement, tc.diskSizeGB)
+		if tc.shouldError && err == nil {
+			t.Fatalf("expected an error for %q but didn't get one", tc.name)
+		}
+		if !tc.shouldError && err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetDataDisk_zoneRedundantStorage(t *testing.T) {
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+
+	cases := []struct {
+		storageAccountType string
+		zones              []string
+		shouldError        bool
+	}{
+		{
+			storageAccountType: string(virtualmachinescalesets.StorageAccountTypesPremiumZRS),
+			zones:              []string{},
+			shouldError:        false,
+		},
+		{
+			storageAccountType: string(virtualmachinescalesets.StorageAccountTypesPremiumZRS),
+			zones:              []string{"1", "2", "3"},
+			shouldError:        false,
+		},
+		{
+			storageAccountType: string(virtualmachinescalesets.StorageAccountTypesPremiumZRS),
+			zones:              []string{"1"},
+			shouldError:        true,
+		},
+		{
+			storageAccountType: string(virtualmachinescalesets.StorageAccountTypesStandardSSDZRS),
+			zones:              []string{"2"},
+			shouldError:        true,
+		},
+		{
+			storageAccountType: string(virtualmachinescalesets.StorageAccountTypesPremiumLRS),
+			zones:              []string{"1"},
+			shouldError:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		input := []interface{}{
+			map[string]interface{}{
+				"caching":                        string(virtualmachinescalesets.CachingTypesNone),
+				"create_option":                  string(virtualmachinescalesets.DiskCreateOptionTypesEmpty),
+				"disk_encryption_set_id":         "",
+				"disk_size_gb":                   10,
+				"lun":                            1,
+				"storage_account_type":           tc.storageAccountType,
+				"ultra_ssd_disk_iops_read_write": 0,
+				"ultra_ssd_disk_mbps_read_write": 0,
+				"write_accelerator_enabled":      false,
+			},
+		}
+
+		_, err := ExpandVirtualMachineScaleSetDataDisk(input, false, tc.zones)
+		if tc.shouldError && err == nil {
+			t.Fatalf("expected an error for %q with zones %v but didn't get one", tc.storageAccountType, tc.zones)
+		}
+		if !tc.shouldError && err != nil {
+			t.Fatalf("expected no error for %q with zones %v but got: %+v", tc.storageAccountType, tc.zones, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetDataDisk_zoneRedundantStorageBeforeFourPointOh(t *testing.T) {
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "false")
+
+	input := []interface{}{
+		map[string]interface{}{
+			"caching":                        string(virtualmachinescalesets.CachingTypesNone),
+			"create_option":                  string(virtualmachinescalesets.DiskCreateOptionTypesEmpty),
+			"disk_encryption_set_id":         "",
+			"disk_size_gb":                   10,
+			"lun":                            1,
+			"storage_account_type":           string(virtualmachinescalesets.StorageAccountTypesPremiumZRS),
+			"ultra_ssd_disk_iops_read_write": 0,
+			"ultra_ssd_disk_mbps_read_write": 0,
+			"write_accelerator_enabled":      false,
+		},
+	}
+
+	if _, err := ExpandVirtualMachineScaleSetDataDisk(input, false, []string{"1"}); err != nil {
+		t.Fatalf("expected a zone-redundant Data Disk in a single zone to be left to the API prior to 4.0 but got: %+v", err)
+	}
+}
+
+func TestExpandVirtualMachineScaleSetNetworkInterfaceUpdate_primary(t *testing.T) {
+	networkInterface := func(name string, primary bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                          name,
+			"dns_servers":                   []interface{}{},
+			"enable_accelerated_networking": false,
+			"enable_ip_forwarding":          false,
+			"fpga_enabled":                  false,
+			"ip_configuration":              []interface{}{},
+			"network_security_group_id":     "",
+			"primary":                       primary,
+			"tcp_state_tracking_enabled":    true,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		primary     string
+		shouldError bool
+	}{
+		{
+			name:    "first primary",
+			input:   []interface{}{networkInterface("first", true), networkInterface("second", false)},
+			primary: "first",
+		},
+		{
+			name:    "flipped to second primary",
+			input:   []interface{}{networkInterface("first", false), networkInterface("second", true)},
+			primary: "second",
+		},
+		{
+			name:        "both primary",
+			input:       []interface{}{networkInterface("first", true), networkInterface("second", true)},
+			shouldError: true,
+		},
+		{
+			name:        "none primary",
+			input:       []interface{}{networkInterface("first", false), networkInterface("second", false)},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(tc.input, nil)
+		if tc.shouldError {
+			if err == nil {
+				t.Fatalf("expected an error for %q but didn't get one", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+
+		primaries := make([]string, 0)
+		for _, v := range *actual {
+			if pointer.From(v.Properties.Primary) {
+				primaries = append(primaries, pointer.From(v.Name))
+			}
+		}
+		if len(primaries) != 1 || primaries[0] != tc.primary {
+			t.Fatalf("expected %q to be the only primary Network Interface for %q but got %v", tc.primary, tc.name, primaries)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetNetworkInterface_tcpStateTracking(t *testing.T) {
+	networkInterface := func(tcpStateTrackingEnabled bool) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"name":                          "nic1",
+				"dns_servers":                   []interface{}{},
+				"enable_accelerated_networking": true,
+				"enable_ip_forwarding":          false,
+				"fpga_enabled":                  false,
+				"ip_configuration":              []interface{}{},
+				"network_security_group_id":     "",
+				"primary":                       true,
+				"tcp_state_tracking_enabled":    tcpStateTrackingEnabled,
+			},
+		}
+	}
+
+	for _, enabled := range []bool{true, false} {
+		created, err := ExpandVirtualMachineScaleSetNetworkInterface(networkInterface(enabled), nil)
+		if err != nil {
+			t.Fatalf("expanding with `tcp_state_tracking_enabled` set to %t: %+v", enabled, err)
+		}
+		if actual := pointer.From((*created)[0].Properties.DisableTcpStateTracking); actual == enabled {
+			t.Fatalf("expected `DisableTcpStateTracking` to be %t when `tcp_state_tracking_enabled` is %t", !enabled, enabled)
+		}
+
+		updated, err := ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(networkInterface(enabled), nil)
+		if err != nil {
+			t.Fatalf("expanding the update with `tcp_state_tracking_enabled` set to %t: %+v", enabled, err)
+		}
+		if actual := pointer.From((*updated)[0].Properties.DisableTcpStateTracking); actual == enabled {
+			t.Fatalf("expected `DisableTcpStateTracking` to be %t when updating with `tcp_state_tracking_enabled` set to %t", !enabled, enabled)
+		}
+
+		flattened := FlattenVirtualMachineScaleSetNetworkInterface(created)
+		if actual := flattened[0].(map[string]interface{})["tcp_state_tracking_enabled"].(bool); actual != enabled {
+			t.Fatalf("expected `tcp_state_tracking_enabled` to be flattened as %t but got %t", enabled, actual)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetLoadBalancerBackendAddressPools(t *testing.T) {
+	pool := func(loadBalancerName, poolName string) string {
+		return fmt.Sprintf("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/loadBalancers/%s/backendAddressPools/%s", loadBalancerName, poolName)
+	}
+	ipConfiguration := func(poolIds ...interface{}) interface{} {
+		return map[string]interface{}{
+			"name":                                   "internal",
+			"load_balancer_backend_address_pool_ids": pluginsdk.NewSet(pluginsdk.HashString, poolIds),
+		}
+	}
+	networkInterface := func(ipConfigurations ...interface{}) interface{} {
+		return map[string]interface{}{
+			"name":             "nic",
+			"ip_configuration": ipConfigurations,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		shouldError bool
+	}{
+		{
+			name:        "no backend address pools",
+			input:       []interface{}{networkInterface(ipConfiguration())},
+			shouldError: false,
+		},
+		{
+			name:        "multiple pools from a single load balancer",
+			input:       []interface{}{networkInterface(ipConfiguration(pool("lb1", "pool1"), pool("lb1", "pool2")))},
+			shouldError: false,
+		},
+		{
+			name:        "pools from a public and an internal load balancer",
+			input:       []interface{}{networkInterface(ipConfiguration(pool("public", "pool1")), ipConfiguration(pool("internal", "pool1")))},
+			shouldError: false,
+		},
+		{
+			name:        "same load balancer with different casing",
+			input:       []interface{}{networkInterface(ipConfiguration(pool("lb1", "pool1"))), networkInterface(ipConfiguration(pool("LB1", "pool2")))},
+			shouldError: false,
+		},
+		{
+			name:        "pools from three load balancers across network interfaces",
+			input:       []interface{}{networkInterface(ipConfiguration(pool("lb1", "pool1"), pool("lb2", "pool1"))), networkInterface(ipConfiguration(pool("lb3", "pool1")))},
+			shouldError: true,
+		},
+		{
+			name:        "unparsable backend address pool ids are left to the api",
+			input:       []interface{}{networkInterface(ipConfiguration(pool("lb1", "pool1"), pool("lb2", "pool1"), "74D93920-ED26-11E3-AC10-0800200C9A66"))},
+			shouldError: false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetLoadBalancerBackendAddressPools(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+
+	err := validateVirtualMachineScaleSetLoadBalancerBackendAddressPools([]interface{}{networkInterface(ipConfiguration(pool("lb1", "pool1"), pool("lb2", "pool1"), pool("lb3", "pool1")))})
+	if err == nil || !strings.Contains(err.Error(), "/loadBalancers/lb1") || !strings.Contains(err.Error(), "/loadBalancers/lb3") {
+		t.Fatalf("expected the error to include the conflicting Load Balancer IDs but got: %+v", err)
+	}
+}
+
+func TestValidateVirtualMachineScaleSetNetworkInterfaceFpga(t *testing.T) {
+	networkInterface := func(name string, fpgaEnabled bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":         name,
+			"fpga_enabled": fpgaEnabled,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		sku         string
+		shouldError bool
+	}{
+		{
+			name:        "fpga disabled on a standard sku",
+			input:       []interface{}{networkInterface("first", false)},
+			sku:         "Standard_F2",
+			shouldError: false,
+		},
+		{
+			name:        "fpga enabled on a standard sku",
+			input:       []interface{}{networkInterface("first", false), networkInterface("second", true)},
+			sku:         "Standard_F2",
+			shouldError: true,
+		},
+		{
+			name:        "fpga enabled on an np-series sku",
+			input:       []interface{}{networkInterface("first", true)},
+			sku:         "Standard_NP10s",
+			shouldError: false,
+		},
+		{
+			name:        "fpga enabled on an np-series sku with different casing",
+			input:       []interface{}{networkInterface("first", true)},
+			sku:         "standard_np40s",
+			shouldError: false,
+		},
+		{
+			name:        "fpga enabled on a similarly named sku",
+			input:       []interface{}{networkInterface("first", true)},
+			sku:         "Standard_NP10s_v2_Promo",
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetNetworkInterfaceFpga(tc.input, tc.sku)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetTags(t *testing.T) {
+	tagsOfCount := func(count int) map[string]interface{} {
+		output := make(map[string]interface{}, count)
+		for i := 0; i < count; i++ {
+			output[fmt.Sprintf("key%d", i)] = "value"
+		}
+		return output
+	}
+
+	cases := []struct {
+		name          string
+		input         map[string]interface{}
+		shouldError   bool
+		offendingKeys []string
+	}{
+		{
+			name:        "no tags",
+			input:       map[string]interface{}{},
+			shouldError: false,
+		},
+		{
+			name:        "50 tags",
+			input:       tagsOfCount(50),
+			shouldError: false,
+		},
+		{
+			name:        "51 tags",
+			input:       tagsOfCount(51),
+			shouldError: true,
+		},
+		{
+			name: "key of 512 characters",
+			input: map[string]interface{}{
+				strings.Repeat("a", 512): "value",
+			},
+			shouldError: false,
+		},
+		{
+			name: "key of 513 characters",
+			input: map[string]interface{}{
+				strings.Repeat("a", 513): "value",
+				"hello":                  "world",
+			},
+			shouldError:   true,
+			offendingKeys: []string{strings.Repeat("a", 513)},
+		},
+		{
+			name: "value of 256 characters",
+			input: map[string]interface{}{
+				"hello": strings.Repeat("a", 256),
+			},
+			shouldError: false,
+		},
+		{
+			name: "values of 257 characters",
+			input: map[string]interface{}{
+				"hello":   strings.Repeat("a", 257),
+				"goodbye": strings.Repeat("a", 257),
+				"other":   "value",
+			},
+			shouldError:   true,
+			offendingKeys: []string{"goodbye", "hello"},
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetTags(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+
+		if len(tc.offendingKeys) > 0 && !strings.Contains(err.Error(), fmt.Sprintf("%q", tc.offendingKeys)) {
+			t.Fatalf("expected the error for %q to list the keys %q but got: %+v", tc.name, tc.offendingKeys, err)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetVMsProtectedFromScaleInCount(t *testing.T) {
+	instance := func(protectionPolicy *virtualmachinescalesetvms.VirtualMachineScaleSetVMProtectionPolicy) virtualmachinescalesetvms.VirtualMachineScaleSetVM {
+		return virtualmachinescalesetvms.VirtualMachineScaleSetVM{
+			Properties: &virtualmachinescalesetvms.VirtualMachineScaleSetVMProperties{
+				ProtectionPolicy: protectionPolicy,
+			},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		input    []virtualmachinescalesetvms.VirtualMachineScaleSetVM
+		expected int
+	}{
+		{
+			name:     "no instances",
+			input:    []virtualmachinescalesetvms.VirtualMachineScaleSetVM{},
+			expected: 0,
+		},
+		{
+			name: "no protected instances",
+			input: []virtualmachinescalesetvms.VirtualMachineScaleSetVM{
+				{},
+				instance(nil),
+				instance(&virtualmachinescalesetvms.VirtualMachineScaleSetVMProtectionPolicy{
+					ProtectFromScaleIn: pointer.To(false),
+				}),
+			},
+			expected: 0,
+		},
+		{
+			name: "protected instances",
+			input: []virtualmachinescalesetvms.VirtualMachineScaleSetVM{
+				instance(&virtualmachinescalesetvms.VirtualMachineScaleSetVMProtectionPolicy{
+					ProtectFromScaleIn: pointer.To(true),
+				}),
+				instance(&virtualmachinescalesetvms.VirtualMachineScaleSetVMProtectionPolicy{
+					ProtectFromScaleSetActions: pointer.To(true),
+				}),
+				instance(&virtualmachinescalesetvms.VirtualMachineScaleSetVMProtectionPolicy{
+					ProtectFromScaleIn:         pointer.To(true),
+					ProtectFromScaleSetActions: pointer.To(true),
+				}),
+			},
+			expected: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := virtualMachineScaleSetVMsProtectedFromScaleInCount(tc.input); actual != tc.expected {
+			t.Fatalf("expected %d protected instances for %q but got %d", tc.expected, tc.name, actual)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetAutomaticRepairsPolicy(t *testing.T) {
+	automaticRepairsPolicy := func(enabled bool) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"enabled":      enabled,
+				"grace_period": "PT30M",
+			},
+		}
+	}
+	healthProbeId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/loadBalancers/lb1/probes/probe1"
+
+	cases := []struct {
+		name               string
+		input              []interface{}
+		healthProbeId      string
+		hasHealthExtension bool
+		shouldError        bool
+	}{
+		{
+			name:        "not configured",
+			input:       []interface{}{},
+			shouldError: false,
+		},
+		{
+			name:        "disabled without health monitoring",
+			input:       automaticRepairsPolicy(false),
+			shouldError: false,
+		},
+		{
+			name:        "enabled without health monitoring",
+			input:       automaticRepairsPolicy(true),
+			shouldError: true,
+		},
+		{
+			name:          "enabled with a health probe",
+			input:         automaticRepairsPolicy(true),
+			healthProbeId: healthProbeId,
+			shouldError:   false,
+		},
+		{
+			name:               "enabled with a health extension",
+			input:              automaticRepairsPolicy(true),
+			hasHealthExtension: true,
+			shouldError:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetAutomaticRepairsPolicy(tc.input, tc.healthProbeId, tc.hasHealthExtension)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
+
+func TestCreateVirtualMachineScaleSetRetryingOnProximityPlacementGroupNotFound(t *testing.T) {
+	proximityPlacementGroupId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/proximityPlacementGroups/ppg1"
+	notFound := fmt.Errorf("unexpected status 404 (404 Not Found) with error: NotFound: The Resource 'Microsoft.Compute/proximityPlacementGroups/ppg1' under resource group 'group1' was not found.")
+	otherNotFound := fmt.Errorf("unexpected status 404 (404 Not Found) with error: NotFound: The Resource 'Microsoft.Network/virtualNetworks/network1' under resource group 'group1' was not found.")
+
+	responses := func(errs ...error) (func(ctx context.Context) error, *int) {
+		calls := 0
+		return func(ctx context.Context) error {
+			var err error
+			if calls < len(errs) {
+				err = errs[calls]
+			}
+			calls++
+			return err
+		}, &calls
+	}
+
+	cases := []struct {
+		name                      string
+		proximityPlacementGroupId string
+		errors                    []error
+		expectedCalls             int
+		shouldError               bool
+	}{
+		{
+			name:                      "404 then success",
+			proximityPlacementGroupId: proximityPlacementGroupId,
+			errors:                    []error{notFound, nil},
+			expectedCalls:             2,
+			shouldError:               false,
+		},
+		{
+			name:                      "404 until the retries are exhausted",
+			proximityPlacementGroupId: proximityPlacementGroupId,
+			errors:                    []error{notFound, notFound, notFound, notFound},
+			expectedCalls:             3,
+			shouldError:               true,
+		},
+		{
+			name:                      "404 for another resource",
+			proximityPlacementGroupId: proximityPlacementGroupId,
+			errors:                    []error{otherNotFound, nil},
+			expectedCalls:             1,
+			shouldError:               true,
+		},
+		{
+			name:                      "404 without a proximity placement group",
+			proximityPlacementGroupId: "",
+			errors:                    []error{notFound, nil},
+			expectedCalls:             1,
+			shouldError:               true,
+		},
+	}
+
+	for _, tc := range cases {
+		create, calls := responses(tc.errors...)
+		err := createVirtualMachineScaleSetRetryingOnProximityPlacementGroupNotFound(context.TODO(), tc.proximityPlacementGroupId, 2, time.Millisecond, create)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if *calls != tc.expectedCalls {
+			t.Fatalf("expected %d attempts for %q but got %d", tc.expectedCalls, tc.name, *calls)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetExtensionProvisionOrder(t *testing.T) {
+	extension := func(name string, provisionAfter ...interface{}) interface{} {
+		return map[string]interface{}{
+			"name":                       name,
+			"provision_after_extensions": provisionAfter,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		expected    []string
+		shouldError bool
+	}{
+		{
+			name:     "no extensions",
+			input:    []interface{}{},
+			expected: []string{},
+		},
+		{
+			name:     "no dependencies",
+			input:    []interface{}{extension("Monitoring"), extension("CustomScript")},
+			expected: []string{"CustomScript", "Monitoring"},
+		},
+		{
+			name: "dependency graph",
+			input: []interface{}{
+				extension("HealthExtension", "CustomScript", "Monitoring"),
+				extension("Monitoring", "KeyVault"),
+				extension("CustomScript", "KeyVault"),
+				extension("KeyVault"),
+				extension("Dependency", "monitoring"),
+			},
+			expected: []string{"KeyVault", "CustomScript", "Monitoring", "Dependency", "HealthExtension"},
+		},
+		{
+			name:     "dependency on an undefined extension",
+			input:    []interface{}{extension("Monitoring", "Other")},
+			expected: []string{"Monitoring"},
+		},
+		{
+			name:        "circular dependency",
+			input:       []interface{}{extension("First", "Second"), extension("Second", "First"), extension("Third")},
+			shouldError: true,
+		},
+		{
+			name:        "dependency on itself",
+			input:       []interface{}{extension("First", "First")},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := virtualMachineScaleSetExtensionProvisionOrder(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError {
+			continue
+		}
+
+		if strings.Join(actual, ",") != strings.Join(tc.expected, ",") {
+			t.Fatalf("expected the provisioning order for %q to be %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+
+	// only the extensions forming the circular dependency should be named, not those provisioned after it
+	_, err := virtualMachineScaleSetExtensionProvisionOrder([]interface{}{
+		extension("First", "Second"),
+		extension("Second", "First"),
+		extension("Dependent", "First"),
+	})
+	if err == nil || !strings.Contains(err.Error(), `"First", "Second" form`) {
+		t.Fatalf("expected the circular dependency error to name `First` and `Second` but got: %+v", err)
+	}
+}
+
+func TestValidateVirtualMachineScaleSetExtensionProvisionAfterExtensions(t *testing.T) {
+	extension := func(name string, provisionAfter ...interface{}) interface{} {
+		return map[string]interface{}{
+			"name":                       name,
+			"provision_after_extensions": provisionAfter,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		shouldError bool
+	}{
+		{
+			name:  "no dependencies",
+			input: []interface{}{extension("Monitoring"), extension("CustomScript")},
+		},
+		{
+			name:  "dependency on a defined extension",
+			input: []interface{}{extension("Monitoring", "customscript"), extension("CustomScript")},
+		},
+		{
+			name:        "dangling reference",
+			input:       []interface{}{extension("Monitoring", "CustomScript", "Other"), extension("CustomScript")},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetExtensionProvisionAfterExtensions(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError && (!strings.Contains(err.Error(), `"Monitoring"`) || !strings.Contains(err.Error(), `"Other"`)) {
+			t.Fatalf("expected the error for %q to name the extensions but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetKeyVaultExtensionsIdentity(t *testing.T) {
+	extension := func(name string, keyVault bool) interface{} {
+		protectedSettingsFromKeyVault := make([]interface{}, 0)
+		if keyVault {
+			protectedSettingsFromKeyVault = append(protectedSettingsFromKeyVault, map[string]interface{}{
+				"secret_url":      "https://vault1.vault.azure.net/secrets/secret1/00000000000000000000000000000000",
+				"source_vault_id": "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.KeyVault/vaults/vault1",
+			})
+		}
+		return map[string]interface{}{
+			"name":                              name,
+			"protected_settings_from_key_vault": protectedSettingsFromKeyVault,
+		}
+	}
+	identity := []interface{}{
+		map[string]interface{}{
+			"type":         "UserAssigned",
+			"identity_ids": []interface{}{"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity1"},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		extensions  []interface{}
+		identity    []interface{}
+		shouldError bool
+	}{
+		{
+			name:        "key vault without an identity",
+			extensions:  []interface{}{extension("CustomScript", true)},
+			identity:    []interface{}{},
+			shouldError: true,
+		},
+		{
+			name:        "key vault with an identity",
+			extensions:  []interface{}{extension("CustomScript", true)},
+			identity:    identity,
+			shouldError: false,
+		},
+		{
+			name:        "no key vault without an identity",
+			extensions:  []interface{}{extension("CustomScript", false)},
+			identity:    []interface{}{},
+			shouldError: false,
+		},
+		{
+			name:        "no extensions",
+			extensions:  []interface{}{},
+			identity:    []interface{}{},
+			shouldError: false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetKeyVaultExtensionsIdentity(tc.extensions, tc.identity)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetApplicationSecurityGroupLocations(t *testing.T) {
+	networkInterface := func(applicationSecurityGroupIds ...interface{}) interface{} {
+		return map[string]interface{}{
+			"name": "example",
+			"ip_configuration": []interface{}{
+				map[string]interface{}{
+					"name":                           "internal",
+					"application_security_group_ids": pluginsdk.NewSet(pluginsdk.HashString, applicationSecurityGroupIds),
+				},
+			},
+		}
+	}
+	locations := map[string]string{
+		"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope":   "West Europe",
+		"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/northeurope":  "northeurope",
+		"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope-2": "westeurope",
+	}
+	calls := 0
+	applicationSecurityGroupLocation := func(id applicationsecuritygroups.ApplicationSecurityGroupId) (string, error) {
+		calls++
+		return locations[id.ID()], nil
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		shouldError bool
+	}{
+		{
+			name:  "no application security groups",
+			input: []interface{}{networkInterface()},
+		},
+		{
+			name: "application security groups in the same region",
+			input: []interface{}{
+				networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope"),
+				networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope-2"),
+			},
+		},
+		{
+			name: "application security group in another region",
+			input: []interface{}{
+				networkInterface(
+					"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope",
+					"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/northeurope",
+				),
+			},
+			shouldError: true,
+		},
+		{
+			name:        "invalid application security group id",
+			input:       []interface{}{networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1")},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetApplicationSecurityGroupLocations(tc.input, "westeurope", applicationSecurityGroupLocation)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+
+	err := validateVirtualMachineScaleSetApplicationSecurityGroupLocations([]interface{}{
+		networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/northeurope"),
+		networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope"),
+	}, "westeurope", applicationSecurityGroupLocation)
+	if err == nil || !strings.Contains(err.Error(), `"northeurope"`) {
+		t.Fatalf("expected the error to name the Application Security Group `northeurope` but got: %+v", err)
+	}
+
+	calls = 0
+	if err := validateVirtualMachineScaleSetApplicationSecurityGroupLocations([]interface{}{
+		networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/westeurope"),
+		networkInterface("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/GROUP1/providers/Microsoft.Network/applicationSecurityGroups/westeurope"),
+	}, "westeurope", applicationSecurityGroupLocation); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the Application Security Group to be retrieved once but it was retrieved %d times", calls)
+	}
+}
+
+func TestVirtualMachineScaleSetSecretCertificateStore(t *testing.T) {
+	certificateSchema := func(input *pluginsdk.Schema) map[string]*pluginsdk.Schema {
+		secret := input.Elem.(*pluginsdk.Resource)
+		return secret.Schema["certificate"].Elem.(*pluginsdk.Resource).Schema
+	}
+
+	windows := certificateSchema(windowsSecretSchema())
+	store, ok := windows["store"]
+	if !ok || !store.Required {
+		t.Fatalf("expected `store` to be required for Windows certificates")
+	}
+	if _, errs := store.ValidateFunc("", "store"); len(errs) == 0 {
+		t.Fatalf("expected an error for a Windows certificate without a `store` but didn't get one")
+	}
+	if _, errs := store.ValidateFunc("My", "store"); len(errs) > 0 {
+		t.Fatalf("expected no error for a Windows certificate with a `store` but got: %+v", errs)
+	}
+
+	// Linux certificates are placed on the file system rather than in a Certificate Store, so `store` isn't supported
+	if _, ok := certificateSchema(linuxSecretSchema())["store"]; ok {
+		t.Fatalf("expected `store` not to be supported for Linux certificates")
+	}
+}
+
+func TestVirtualMachineScaleSetGalleryApplication(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"version_id":                "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/galleries/gallery1/applications/app1/versions/1.0.0",
+			"automatic_upgrade_enabled": true,
+			"configuration_blob_uri":    "https://example.blob.core.windows.net/configurations/settings.config",
+			"order":                     1,
+			"tag":                       "example",
+			"treat_failure_as_deployment_failure_enabled": true,
+		},
+	}
+
+	expanded := expandVirtualMachineScaleSetGalleryApplication(input)
+	if expanded == nil || len(*expanded) != 1 {
+		t.Fatalf("expected 1 Gallery Application but got %+v", expanded)
+	}
+	if app := (*expanded)[0]; !pointer.From(app.EnableAutomaticUpgrade) || !pointer.From(app.TreatFailureAsDeploymentFailure) {
+		t.Fatalf("expected `EnableAutomaticUpgrade` and `TreatFailureAsDeploymentFailure` to be set but got %+v", app)
+	}
+
+	flattened := flattenVirtualMachineScaleSetGalleryApplication(expanded)
+	if len(flattened) != 1 {
+		t.Fatalf("expected 1 Gallery Application but got %+v", flattened)
+	}
+	for key, expected := range input[0].(map[string]interface{}) {
+		if actual := flattened[0].(map[string]interface{})[key]; actual != expected {
+			t.Fatalf("expected %q to be %v but got %v", key, expected, actual)
+		}
+	}
+
+	// these aren't returned by the API when they've not been set, for example when using the deprecated `gallery_applications` block
+	flattened = flattenVirtualMachineScaleSetGalleryApplication(&[]virtualmachinescalesets.VMGalleryApplication{
+		{
+			PackageReferenceId: "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/galleries/gallery1/applications/app1/versions/1.0.0",
+		},
+	})
+	if app := flattened[0].(map[string]interface{}); app["automatic_upgrade_enabled"] != false || app["treat_failure_as_deployment_failure_enabled"] != false {
+		t.Fatalf("expected the fields to default to `false` but got %+v", app)
+	}
+}
+
+func TestVirtualMachineScaleSetGalleryApplication_tagFidelity(t *testing.T) {
+	// the tag is passed through to the application as-is, so it must round-trip without being trimmed or normalized
+	tag := "  key=value; other = \"quoted\"\t{json: [1, 2]} ünïcødé  "
+
+	expanded := expandVirtualMachineScaleSetGalleryApplication([]interface{}{
+		map[string]interface{}{
+			"version_id":                "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/galleries/gallery1/applications/app1/versions/1.0.0",
+			"automatic_upgrade_enabled": false,
+			"configuration_blob_uri":    "",
+			"order":                     0,
+			"tag":                       tag,
+			"treat_failure_as_deployment_failure_enabled": false,
+		},
+	})
+	if actual := pointer.From((*expanded)[0].Tags); actual != tag {
+		t.Fatalf("expected the expanded tag to be %q but got %q", tag, actual)
+	}
+	if actual := flattenVirtualMachineScaleSetGalleryApplication(expanded)[0].(map[string]interface{})["tag"]; actual != tag {
+		t.Fatalf("expected the flattened tag to be %q but got %q", tag, actual)
+	}
+
+	expanded = expandVirtualMachineScaleSetGalleryApplications([]interface{}{
+		map[string]interface{}{
+			"package_reference_id":             "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/galleries/gallery1/applications/app1/versions/1.0.0",
+			"configuration_reference_blob_uri": "",
+			"order":                            0,
+			"tag":                              tag,
+		},
+	})
+	if actual := flattenVirtualMachineScaleSetGalleryApplications(expanded)[0].(map[string]interface{})["tag"]; actual != tag {
+		t.Fatalf("expected the flattened tag for the deprecated block to be %q but got %q", tag, actual)
+	}
+}
+
+func TestFlattenVirtualMachineScaleSetIPConfiguration_normalizesIDs(t *testing.T) {
+	input := virtualmachinescalesets.VirtualMachineScaleSetIPConfiguration{
+		Name: "internal",
+		Properties: &virtualmachinescalesets.VirtualMachineScaleSetIPConfigurationProperties{
+			ApplicationGatewayBackendAddressPools: &[]virtualmachinescalesets.SubResource{
+				{Id: pointer.To("/subscriptions/12345678-1234-9876-4563-123456789012/resourcegroups/group1/providers/microsoft.network/applicationgateways/gateway1/backendaddresspools/pool1")},
+			},
+			ApplicationSecurityGroups: &[]virtualmachinescalesets.SubResource{
+				{Id: pointer.To("/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/ApplicationSecurityGroups/asg1")},
+			},
+			LoadBalancerBackendAddressPools: &[]virtualmachinescalesets.SubResource{
+				{Id: pointer.To("/subscriptions/12345678-1234-9876-4563-123456789012/resourcegroups/group1/providers/Microsoft.Network/loadBalancers/lb1/BackendAddressPools/pool1")},
+				{Id: pointer.To("not-a-resource-id")},
+			},
+		},
+	}
+
+	expected := map[string][]interface{}{
+		"application_gateway_backend_address_pool_ids": {
+			"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationGateways/gateway1/backendAddressPools/pool1",
+		},
+		"application_security_group_ids": {
+			"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/applicationSecurityGroups/asg1",
+		},
+		"load_balancer_backend_address_pool_ids": {
+			"/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/loadBalancers/lb1/backendAddressPools/pool1",
+			"not-a-resource-id",
+		},
+	}
+
+	actual := flattenVirtualMachineScaleSetIPConfiguration(input)
+	for key, ids := range expected {
+		// these are written into a TypeSet using `HashString`, so the values must match the configuration exactly
+		expectedSet := pluginsdk.NewSet(pluginsdk.HashString, ids)
+		actualSet := pluginsdk.NewSet(pluginsdk.HashString, actual[key].([]interface{}))
+		if !expectedSet.Equal(actualSet) {
+			t.Fatalf("expected %q to be %+v but got %+v", key, ids, actual[key])
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetCapacityReservationPriority(t *testing.T) {
+	capacityReservationGroupId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Compute/capacityReservationGroups/group1"
+
+	cases := []struct {
+		name                       string
+		capacityReservationGroupId string
+		priority                   string
+		shouldError                bool
+	}{
+		{
+			name:     "spot without a capacity reservation",
+			priority: "Spot",
+		},
+		{
+			name:                       "regular with a capacity reservation",
+			capacityReservationGroupId: capacityReservationGroupId,
+			priority:                   "Regular",
+		},
+		{
+			name:                       "spot with a capacity reservation",
+			capacityReservationGroupId: capacityReservationGroupId,
+			priority:                   "Spot",
+			shouldError:                true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetCapacityReservationPriority(tc.capacityReservationGroupId, tc.priority)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError && (!strings.Contains(err.Error(), "`capacity_reservation_group_id`") || !strings.Contains(err.Error(), "`priority`")) {
+			t.Fatalf("expected the error for %q to name both fields but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetAcceleratedNetworkingSupported(t *testing.T) {
+	networkInterfaces := func(acceleratedNetworking ...bool) []interface{} {
+		output := make([]interface{}, 0)
+		for i, enabled := range acceleratedNetworking {
+			output = append(output, map[string]interface{}{
+				"name":                          fmt.Sprintf("nic%d", i),
+				"enable_accelerated_networking": enabled,
+			})
+		}
+		return output
+	}
+	sku := func(acceleratedNetworking string, maxNetworkInterfaces string) *client.SkuCapabilities {
+		return &client.SkuCapabilities{
+			Name: "Standard_D2s_v3",
+			Capabilities: map[string]string{
+				"AcceleratedNetworkingEnabled": acceleratedNetworking,
+				"MaxNetworkInterfaces":         maxNetworkInterfaces,
+			},
+		}
+	}
+
+	cases := []struct {
+		name              string
+		sku               *client.SkuCapabilities
+		networkInterfaces []interface{}
+		shouldError       bool
+	}{
+		{
+			name:              "unknown sku",
+			networkInterfaces: networkInterfaces(true, true, true),
+		},
+		{
+			name:              "within the limit",
+			sku:               sku("True", "2"),
+			networkInterfaces: networkInterfaces(true, true),
+		},
+		{
+			name:              "exceeding the limit without accelerated networking",
+			sku:               sku("True", "2"),
+			networkInterfaces: networkInterfaces(true, false, false),
+		},
+		{
+			name:              "exceeding the limit",
+			sku:               sku("True", "2"),
+			networkInterfaces: networkInterfaces(true, true, true),
+			shouldError:       true,
+		},
+		{
+			name:              "accelerated networking unsupported",
+			sku:               sku("False", "2"),
+			networkInterfaces: networkInterfaces(true),
+			shouldError:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetAcceleratedNetworkingSupported(tc.sku, "westeurope", "Standard_D2s_v3", tc.networkInterfaces)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError && !strings.Contains(err.Error(), `"Standard_D2s_v3"`) {
+			t.Fatalf("expected the error for %q to name the SKU but got: %+v", tc.name, err)
+		}
+	}
+
+	err := validateVirtualMachineScaleSetAcceleratedNetworkingSupported(sku("True", "2"), "westeurope", "Standard_D2s_v3", networkInterfaces(true, true, true))
+	if err == nil || !strings.Contains(err.Error(), "maximum of 2") {
+		t.Fatalf("expected the error to include the limit but got: %+v", err)
+	}
+}
+
+func TestValidateVirtualMachineScaleSetSinglePlacementGroupCapacity(t *testing.T) {
+	cases := []struct {
+		capacity             int
+		singlePlacementGroup bool
+		shouldError          bool
+	}{
+		{
+			capacity:             100,
+			singlePlacementGroup: true,
+		},
+		{
+			capacity:             101,
+			singlePlacementGroup: true,
+			shouldError:          true,
+		},
+		{
+			capacity:             101,
+			singlePlacementGroup: false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetSinglePlacementGroupCapacity(tc.capacity, tc.singlePlacementGroup)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %d instances with `single_placement_group` %t: %t but got: %+v", tc.capacity, tc.singlePlacementGroup, tc.shouldError, err)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetExtensions_hasHealthExtension(t *testing.T) {
+	extension := func(publisher, extensionType string) interface{} {
+		return map[string]interface{}{
+			"name":                              "extension",
+			"publisher":                         publisher,
+			"type":                              extensionType,
+			"type_handler_version":              "1.0",
+			"auto_upgrade_minor_version":        true,
+			"automatic_upgrade_enabled":         false,
+			"force_update_tag":                  "",
+			"provision_after_extensions":        []interface{}{},
+			"settings":                          "",
+			"protected_settings":                "",
+			"protected_settings_from_key_vault": []interface{}{},
+		}
+	}
+	healthProbeId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/group1/providers/Microsoft.Network/loadBalancers/lb1/probes/probe1"
+
+	cases := []struct {
+		name          string
+		input         []interface{}
+		healthProbeId string
+		expected      bool
+	}{
+		{
+			name:     "application health linux",
+			input:    []interface{}{extension("Microsoft.ManagedServices", "ApplicationHealthLinux")},
+			expected: true,
+		},
+		{
+			name:     "lowercase application health windows",
+			input:    []interface{}{extension("Microsoft.ManagedServices", "applicationhealthwindows")},
+			expected: true,
+		},
+		{
+			name:     "custom publisher",
+			input:    []interface{}{extension("Contoso.Monitoring", "APPLICATIONHEALTHLINUX")},
+			expected: true,
+		},
+		{
+			name:     "other extension",
+			input:    []interface{}{extension("Microsoft.Azure.Extensions", "CustomScript")},
+			expected: false,
+		},
+		{
+			name:          "health probe without extensions",
+			input:         []interface{}{},
+			healthProbeId: healthProbeId,
+			expected:      true,
+		},
+		{
+			name:          "health probe with another extension",
+			input:         []interface{}{extension("Microsoft.Azure.Extensions", "CustomScript")},
+			healthProbeId: healthProbeId,
+			expected:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		_, actual, err := expandVirtualMachineScaleSetExtensions(tc.input, tc.healthProbeId)
+		if err != nil {
+			t.Fatalf("expanding the extensions for %q: %+v", tc.name, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("expected `hasHealthExtension` to be %t for %q but got %t", tc.expected, tc.name, actual)
+		}
+	}
+}
+
+func TestLinuxVirtualMachineScaleSetAdminPasswordSchema_validation(t *testing.T) {
+	// existing configurations may use passwords which the validation would reject, so this is only validated from 4.0
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "false")
+	if resourceLinuxVirtualMachineScaleSetSchema()["admin_password"].ValidateFunc != nil {
+		t.Fatalf("expected `admin_password` not to be validated prior to 4.0")
+	}
+
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+	validateFunc := resourceLinuxVirtualMachineScaleSetSchema()["admin_password"].ValidateFunc
+	if validateFunc == nil {
+		t.Fatalf("expected `admin_password` to be validated in 4.0")
+	}
+	if _, errors := validateFunc("Password1", "admin_password"); len(errors) == 0 {
+		t.Fatalf("expected a disallowed `admin_password` to be rejected in 4.0")
+	}
+}
+
+func TestValidateVirtualMachineScaleSetSingleIPConfigurationPrimary(t *testing.T) {
+	ipConfiguration := func(name string, primary bool) interface{} {
+		return map[string]interface{}{
+			"name":    name,
+			"primary": primary,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []interface{}
+		shouldError bool
+	}{
+		{
+			name:  "no ip configurations",
+			input: []interface{}{},
+		},
+		{
+			name:  "single primary ip configuration",
+			input: []interface{}{ipConfiguration("internal", true)},
+		},
+		{
+			name:        "single non-primary ip configuration",
+			input:       []interface{}{ipConfiguration("internal", false)},
+			shouldError: true,
+		},
+		{
+			name:  "multiple ip configurations",
+			input: []interface{}{ipConfiguration("internal", true), ipConfiguration("secondary", false)},
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetSingleIPConfigurationPrimary("nic1", tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError && (!strings.Contains(err.Error(), `"internal"`) || !strings.Contains(err.Error(), `"nic1"`)) {
+			t.Fatalf("expected the error for %q to name the IP Configuration and Network Interface but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetCredentials(t *testing.T) {
+	cases := []struct {
+		name        string
+		osType      virtualmachinescalesets.OperatingSystemTypes
+		input       virtualMachineScaleSetCredentials
+		expected    []string
+		shouldError bool
+	}{
+		{
+			name:   "windows complete",
+			osType: virtualmachinescalesets.OperatingSystemTypesWindows,
+			input: virtualMachineScaleSetCredentials{
+				AdminUsername: "adminuser",
+				AdminPassword: "P@ssword1234!",
+			},
+		},
+		{
+			name:        "windows without a password",
+			osType:      virtualmachinescalesets.OperatingSystemTypesWindows,
+			input:       virtualMachineScaleSetCredentials{AdminUsername: "adminuser"},
+			expected:    []string{"`admin_password`"},
+			shouldError: true,
+		},
+		{
+			name:        "windows without any credentials",
+			osType:      virtualmachinescalesets.OperatingSystemTypesWindows,
+			input:       virtualMachineScaleSetCredentials{},
+			expected:    []string{"`admin_username`", "`admin_password`"},
+			shouldError: true,
+		},
+		{
+			name:   "linux with ssh keys",
+			osType: virtualmachinescalesets.OperatingSystemTypesLinux,
+			input: virtualMachineScaleSetCredentials{
+				AdminUsername:                 "adminuser",
+				DisablePasswordAuthentication: true,
+				SSHKeyCount:                   1,
+			},
+		},
+		{
+			name:   "linux with a password",
+			osType: virtualmachinescalesets.OperatingSystemTypesLinux,
+			input: virtualMachineScaleSetCredentials{
+				AdminUsername:                 "adminuser",
+				AdminPassword:                 "P@ssword1234!",
+				DisablePasswordAuthentication: false,
+			},
+		},
+		{
+			name:   "linux without ssh keys",
+			osType: virtualmachinescalesets.OperatingSystemTypesLinux,
+			input: virtualMachineScaleSetCredentials{
+				AdminUsername:                 "adminuser",
+				DisablePasswordAuthentication: true,
+			},
+			expected:    []string{"`admin_ssh_key`"},
+			shouldError: true,
+		},
+		{
+			name:   "linux password authentication without a password",
+			osType: virtualmachinescalesets.OperatingSystemTypesLinux,
+			input: virtualMachineScaleSetCredentials{
+				AdminUsername:                 "adminuser",
+				DisablePasswordAuthentication: false,
+				SSHKeyCount:                   1,
+			},
+			expected:    []string{"`admin_password`"},
+			shouldError: true,
+		},
+		{
+			name:   "linux without a username or ssh keys",
+			osType: virtualmachinescalesets.OperatingSystemTypesLinux,
+			input: virtualMachineScaleSetCredentials{
+				DisablePasswordAuthentication: true,
+			},
+			expected:    []string{"`admin_username`", "`admin_ssh_key`"},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetCredentials(tc.osType, tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		for _, field := range tc.expected {
+			if !strings.Contains(err.Error(), field) {
+				t.Fatalf("expected the error for %q to report %s but got: %+v", tc.name, field, err)
+			}
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetPublicIPAddressDnsSettings(t *testing.T) {
+	cases := []struct {
+		name          string
+		label         string
+		scope         string
+		expectedScope *virtualmachinescalesets.DomainNameLabelScopeTypes
+		expectNil     bool
+		shouldError   bool
+	}{
+		{
+			name:      "no domain name label",
+			expectNil: true,
+		},
+		{
+			name:  "domain name label without a scope",
+			label: "acctest",
+		},
+		{
+			name:          "domain name label with a scope",
+			label:         "acctest",
+			scope:         string(virtualmachinescalesets.DomainNameLabelScopeTypesTenantReuse),
+			expectedScope: pointer.To(virtualmachinescalesets.DomainNameLabelScopeTypesTenantReuse),
+		},
+		{
+			name:        "scope without a domain name label",
+			scope:       string(virtualmachinescalesets.DomainNameLabelScopeTypesSubscriptionReuse),
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := expandVirtualMachineScaleSetPublicIPAddressDnsSettings(map[string]interface{}{
+			"name":                    "pip",
+			"domain_name_label":       tc.label,
+			"domain_name_label_scope": tc.scope,
+		})
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError {
+			continue
+		}
+		if tc.expectNil {
+			if actual != nil {
+				t.Fatalf("expected no DNS Settings for %q but got: %+v", tc.name, actual)
+			}
+			continue
+		}
+		if actual.DomainNameLabel != tc.label {
+			t.Fatalf("expected the domain name label for %q to be %q but got %q", tc.name, tc.label, actual.DomainNameLabel)
+		}
+		if pointer.From(actual.DomainNameLabelScope) != pointer.From(tc.expectedScope) {
+			t.Fatalf("expected the domain name label scope for %q to be %q but got %q", tc.name, pointer.From(tc.expectedScope), pointer.From(actual.DomainNameLabelScope))
+		}
+
+		flattened := flattenVirtualMachineScaleSetPublicIPAddress(virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfiguration{
+			Name: "pip",
+			Properties: &virtualmachinescalesets.VirtualMachineScaleSetPublicIPAddressConfigurationProperties{
+				DnsSettings: actual,
+			},
+		})
+		if flattened["domain_name_label_scope"].(string) != tc.scope {
+			t.Fatalf("expected the flattened domain name label scope for %q to be %q but got %q", tc.name, tc.scope, flattened["domain_name_label_scope"])
+		}
+	}
+}
+
+func TestValidateZonesForLocation(t *testing.T) {
+	sku := func(name string, zones ...string) client.SkuCapabilities {
+		return client.SkuCapabilities{
+			Name:  name,
+			Zones: zones,
+		}
+	}
+	zonal := []client.SkuCapabilities{
+		sku("Standard_D2s_v3", "1", "2"),
+		sku("Standard_F2", "3"),
+	}
+	nonZonal := []client.SkuCapabilities{
+		sku("Standard_D2s_v3"),
+	}
+
+	cases := []struct {
+		name        string
+		skus        []client.SkuCapabilities
+		location    string
+		zones       []string
+		shouldError bool
+	}{
+		{
+			name:     "unknown skus",
+			location: "westeurope",
+			zones:    []string{"4"},
+		},
+		{
+			name:     "no zones",
+			skus:     zonal,
+			location: "westeurope",
+		},
+		{
+			name:     "supported zones",
+			skus:     zonal,
+			location: "westeurope",
+			zones:    []string{"1", "3"},
+		},
+		{
+			name:        "unsupported zone",
+			skus:        zonal,
+			location:    "westeurope",
+			zones:       []string{"1", "4"},
+			shouldError: true,
+		},
+		{
+			name:        "location without zones",
+			skus:        nonZonal,
+			location:    "westcentralus",
+			zones:       []string{"1"},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateZonesForLocation(tc.skus, tc.location, tc.zones)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+
+	err := validateZonesForLocation(zonal, "westeurope", []string{"4"})
+	if err == nil || !strings.Contains(err.Error(), `"1, 2, 3"`) {
+		t.Fatalf("expected the error to list the supported zones but got: %+v", err)
+	}
+}
+
+func TestVirtualMachineScaleSetBootDiagnostics(t *testing.T) {
+	cases := []struct {
+		name            string
+		input           []interface{}
+		existing        []interface{}
+		apiUri          string
+		expectedEnabled bool
+		expectedUri     string
+		expectedState   []interface{}
+	}{
+		{
+			name:            "omitted",
+			input:           []interface{}{},
+			expectedEnabled: false,
+			expectedState:   []interface{}{},
+		},
+		{
+			name:            "managed with an empty block",
+			input:           []interface{}{nil},
+			expectedEnabled: true,
+			expectedState:   []interface{}{map[string]interface{}{"storage_account_uri": ""}},
+		},
+		{
+			name:            "managed with an empty uri",
+			input:           []interface{}{map[string]interface{}{"storage_account_uri": ""}},
+			expectedEnabled: true,
+			expectedState:   []interface{}{map[string]interface{}{"storage_account_uri": ""}},
+		},
+		{
+			name:            "storage account",
+			input:           []interface{}{map[string]interface{}{"storage_account_uri": "https://example.blob.core.windows.net"}},
+			existing:        []interface{}{map[string]interface{}{"storage_account_uri": "https://example.blob.core.windows.net"}},
+			apiUri:          "https://example.blob.core.windows.net/",
+			expectedEnabled: true,
+			expectedUri:     "https://example.blob.core.windows.net",
+			expectedState:   []interface{}{map[string]interface{}{"storage_account_uri": "https://example.blob.core.windows.net"}},
+		},
+		{
+			name:            "storage account changed outside of terraform",
+			input:           []interface{}{map[string]interface{}{"storage_account_uri": "https://example.blob.core.windows.net"}},
+			existing:        []interface{}{map[string]interface{}{"storage_account_uri": "https://example.blob.core.windows.net"}},
+			apiUri:          "https://other.blob.core.windows.net/",
+			expectedEnabled: true,
+			expectedUri:     "https://example.blob.core.windows.net",
+			expectedState:   []interface{}{map[string]interface{}{"storage_account_uri": "https://other.blob.core.windows.net/"}},
+		},
+	}
+
+	for _, tc := range cases {
+		expanded := ExpandVirtualMachineScaleSetBootDiagnostics(tc.input)
+		if pointer.From(expanded.BootDiagnostics.Enabled) != tc.expectedEnabled {
+			t.Fatalf("expected Boot Diagnostics to be enabled for %q: %t", tc.name, tc.expectedEnabled)
+		}
+		if pointer.From(expanded.BootDiagnostics.StorageUri) != tc.expectedUri {
+			t.Fatalf("expected the storage uri for %q to be %q but got %q", tc.name, tc.expectedUri, pointer.From(expanded.BootDiagnostics.StorageUri))
+		}
+
+		response := &virtualmachinescalesets.DiagnosticsProfile{
+			BootDiagnostics: &virtualmachinescalesets.BootDiagnostics{
+				Enabled: expanded.BootDiagnostics.Enabled,
+			},
+		}
+		if tc.apiUri != "" {
+			response.BootDiagnostics.StorageUri = pointer.To(tc.apiUri)
+		}
+		flattened := FlattenVirtualMachineScaleSetBootDiagnostics(response, tc.existing)
+		if !reflect.DeepEqual(flattened, tc.expectedState) {
+			t.Fatalf("expected the flattened `boot_diagnostics` for %q to be %+v but got %+v", tc.name, tc.expectedState, flattened)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetBootDiagnosticsSchema_storageAccountUri(t *testing.T) {
+	validateFunc := VirtualMachineScaleSetBootDiagnosticsSchema().Elem.(*pluginsdk.Resource).Schema["storage_account_uri"].ValidateFunc
+
+	cases := map[string]bool{
+		"":                                      false,
+		"https://example.blob.core.windows.net": false,
+		"http://example.blob.core.windows.net":  true,
+		"example.blob.core.windows.net":         true,
+	}
+
+	for input, shouldError := range cases {
+		_, errors := validateFunc(input, "storage_account_uri")
+		if shouldError != (len(errors) > 0) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", input, shouldError, errors)
+		}
+	}
+}
+
+func TestValidateVirtualMachineScaleSetIPConfigurationAllocation(t *testing.T) {
+	// existing configurations may omit the `subnet_id`, so this is only validated from 4.0
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "false")
+	if err := validateVirtualMachineScaleSetIPConfigurationAllocation(map[string]interface{}{
+		"name":      "internal",
+		"version":   string(virtualmachinescalesets.IPVersionIPvFour),
+		"subnet_id": "",
+	}); err != nil {
+		t.Fatalf("expected no error prior to 4.0 but got: %+v", err)
+	}
+
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+	cases := []struct {
+		name        string
+		version     virtualmachinescalesets.IPVersion
+		subnetId    string
+		shouldError bool
+	}{
+		{
+			name:     "IPv4 with a subnet",
+			version:  virtualmachinescalesets.IPVersionIPvFour,
+			subnetId: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet",
+		},
+		{
+			name:        "IPv4 without a subnet",
+			version:     virtualmachinescalesets.IPVersionIPvFour,
+			shouldError: true,
+		},
+		{
+			name:    "IPv6 without a subnet",
+			version: virtualmachinescalesets.IPVersionIPvSix,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateVirtualMachineScaleSetIPConfigurationAllocation(map[string]interface{}{
+			"name":      "internal",
+			"version":   string(tc.version),
+			"subnet_id": tc.subnetId,
+		})
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetScaleInPolicy(t *testing.T) {
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "false")
+
+	cases := []struct {
+		name     string
+		input    map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "no rules",
+			input: map[string]interface{}{
+				"rule":                   "",
+				"rules":                  []interface{}{},
+				"force_deletion_enabled": false,
+			},
+			expected: []string{"Default"},
+		},
+		{
+			name: "legacy rule",
+			input: map[string]interface{}{
+				"rule":                   "NewestVM",
+				"rules":                  []interface{}{},
+				"force_deletion_enabled": false,
+			},
+			expected: []string{"NewestVM"},
+		},
+		{
+			name: "ordered rules",
+			input: map[string]interface{}{
+				"rule":                   "",
+				"rules":                  []interface{}{"OldestVM", "Default"},
+				"force_deletion_enabled": true,
+			},
+			expected: []string{"OldestVM", "Default"},
+		},
+	}
+
+	for _, tc := range cases {
+		expanded := ExpandVirtualMachineScaleSetScaleInPolicy([]interface{}{tc.input})
+		actual := make([]string, 0)
+		for _, rule := range pointer.From(expanded.Rules) {
+			actual = append(actual, string(rule))
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("expected the rules for %q to be %+v but got %+v", tc.name, tc.expected, actual)
+		}
+		if pointer.From(expanded.ForceDeletion) != tc.input["force_deletion_enabled"].(bool) {
+			t.Fatalf("expected `force_deletion_enabled` for %q to be %t", tc.name, tc.input["force_deletion_enabled"].(bool))
+		}
+
+		// `rules` is only flattened when it's configured, so that changing the deprecated `rule` converges
+		flattened := FlattenVirtualMachineScaleSetScaleInPolicy(expanded, []interface{}{tc.input})[0].(map[string]interface{})
+		flattenedRules := make([]string, 0)
+		for _, rule := range flattened["rules"].([]interface{}) {
+			flattenedRules = append(flattenedRules, rule.(string))
+		}
+		expectedRules := make([]string, 0)
+		if len(tc.input["rules"].([]interface{})) > 0 {
+			expectedRules = tc.expected
+		}
+		if !reflect.DeepEqual(flattenedRules, expectedRules) {
+			t.Fatalf("expected the flattened rules for %q to be %+v but got %+v", tc.name, expectedRules, flattenedRules)
+		}
+		if flattened["rule"].(string) != tc.expected[0] {
+			t.Fatalf("expected the flattened rule for %q to be %q but got %q", tc.name, tc.expected[0], flattened["rule"])
+		}
+	}
+}
+
+func TestVirtualMachineScaleSetScaleInPolicy_fourPointOh(t *testing.T) {
+	t.Setenv("ARM_FOURPOINTZERO_BETA", "true")
+
+	elem := VirtualMachineScaleSetScaleInPolicySchema().Elem.(*pluginsdk.Resource)
+	if _, ok := elem.Schema["rule"]; ok {
+		t.Fatalf("expected the deprecated `rule` to be removed in 4.0")
+	}
+	if conflicts := elem.Schema["rules"].ConflictsWith; len(conflicts) > 0 {
+		t.Fatalf("expected `rules` not to conflict with the removed `rule` in 4.0 but got %+v", conflicts)
+	}
+
+	input := map[string]interface{}{
+		"rules":                  []interface{}{"OldestVM", "Default"},
+		"force_deletion_enabled": false,
+	}
+	expanded := ExpandVirtualMachineScaleSetScaleInPolicy([]interface{}{input})
+	flattened := FlattenVirtualMachineScaleSetScaleInPolicy(expanded, []interface{}{input})[0].(map[string]interface{})
+	if _, ok := flattened["rule"]; ok {
+		t.Fatalf("expected the removed `rule` not to be flattened in 4.0")
+	}
+	if !reflect.DeepEqual(flattened["rules"], []interface{}{"OldestVM", "Default"}) {
+		t.Fatalf("expected the flattened rules to be %+v but got %+v", input["rules"], flattened["rules"])
+	}
+}
+
+func TestValidateMarketplaceAgreementAccepted(t *testing.T) {
+	cases := []struct {
+		name        string
+		accepted    *bool
+		shouldError bool
+	}{
+		{
+			name: "no agreement",
+		},
+		{
+			name:     "accepted",
+			accepted: pointer.To(true),
+		},
+		{
+			name:        "not accepted",
+			accepted:    pointer.To(false),
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateMarketplaceAgreementAccepted(tc.accepted, "cisco", "cisco-meraki-vmx", "cisco-meraki-vmx")
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.shouldError && !strings.Contains(err.Error(), `resource "azurerm_marketplace_agreement"`) {
+			t.Fatalf("expected the error for %q to include the `azurerm_marketplace_agreement` configuration but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestEnsureMarketplaceAgreementAccepted_enhancedValidationDisabled(t *testing.T) {
+	// the Marketplace Agreement isn't retrieved when Enhanced Validation is disabled, so no client is required
+	t.Setenv("ARM_PROVIDER_ENHANCED_VALIDATION", "false")
+	if err := EnsureMarketplaceAgreementAccepted(context.Background(), nil, "publisher", "offer", "plan"); err != nil {
+		t.Fatalf("expected no error when Enhanced Validation is disabled but got: %+v", err)
+	}
+}
+
+func TestVirtualMachineScaleSetPublicIPAddressSku(t *testing.T) {
+	networkInterfaces := func(backendAddressPoolId string) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"ip_configuration": []interface{}{
+					map[string]interface{}{
+						"load_balancer_backend_address_pool_ids": pluginsdk.NewSet(pluginsdk.HashString, []interface{}{backendAddressPoolId}),
+						"public_ip_address": []interface{}{
+							map[string]interface{}{
+								"ip_tag": []interface{}{
+									map[string]interface{}{
+										"type": "RoutingPreference",
+										"tag":  "Internet",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// the Load Balancer isn't retrieved when Enhanced Validation is disabled, so no client is required
+	t.Setenv("ARM_PROVIDER_ENHANCED_VALIDATION", "false")
+	sku, err := virtualMachineScaleSetPublicIPAddressSku(context.Background(), nil, networkInterfaces("invalid"))
+	if err != nil {
+		t.Fatalf("expected no error when Enhanced Validation is disabled but got: %+v", err)
+	}
+	if sku != nil {
+		t.Fatalf("expected no SKU when Enhanced Validation is disabled but got: %+v", sku)
+	}
+
+	t.Setenv("ARM_PROVIDER_ENHANCED_VALIDATION", "true")
+	if _, err := virtualMachineScaleSetPublicIPAddressSku(context.Background(), nil, networkInterfaces("invalid")); err == nil {
+		t.Fatalf("expected an error for an invalid Backend Address Pool ID when Enhanced Validation is enabled")
+	}
+}
+
+func TestVirtualMachineScaleSetPublicIPAddressSkuForLoadBalancers(t *testing.T) {
+	loadBalancer := func(name string, skuName loadbalancers.LoadBalancerSkuName) loadbalancers.LoadBalancer {
+		return loadbalancers.LoadBalancer{
+			Id: pointer.To(loadbalancers.NewProviderLoadBalancerID("12345678-1234-9876-4563-123456789012", "group1", name).ID()),
+			Sku: &loadbalancers.LoadBalancerSku{
+				Name: pointer.To(skuName),
+			},
+		}
+	}
+
+	cases := []struct {
+		name        string
+		input       []loadbalancers.LoadBalancer
+		expected    *virtualmachinescalesets.PublicIPAddressSkuName
+		shouldError bool
+	}{
+		{
+			name:  "no load balancers",
+			input: []loadbalancers.LoadBalancer{},
+		},
+		{
+			name:  "unknown sku",
+			input: []loadbalancers.LoadBalancer{{Id: pointer.To("lb1")}},
+		},
+		{
+			name:     "basic",
+			input:    []loadbalancers.LoadBalancer{loadBalancer("lb1", loadbalancers.LoadBalancerSkuNameBasic)},
+			expected: pointer.To(virtualmachinescalesets.PublicIPAddressSkuNameBasic),
+		},
+		{
+			name: "standard and gateway",
+			input: []loadbalancers.LoadBalancer{
+				loadBalancer("lb1", loadbalancers.LoadBalancerSkuNameStandard),
+				loadBalancer("lb2", loadbalancers.LoadBalancerSkuNameGateway),
+			},
+			expected: pointer.To(virtualmachinescalesets.PublicIPAddressSkuNameStandard),
+		},
+		{
+			name: "standard and basic",
+			input: []loadbalancers.LoadBalancer{
+				loadBalancer("lb1", loadbalancers.LoadBalancerSkuNameStandard),
+				loadBalancer("lb2", loadbalancers.LoadBalancerSkuNameBasic),
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := virtualMachineScaleSetPublicIPAddressSkuForLoadBalancers(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if tc.expected == nil {
+			if actual != nil {
+				t.Fatalf("expected no SKU for %q but got: %+v", tc.name, actual)
+			}
+			continue
+		}
+		if actual == nil || pointer.From(actual.Name) != *tc.expected {
+			t.Fatalf("expected the SKU %q for %q but got: %+v", *tc.expected, tc.name, actual)
+		}
+	}
+}
+
+func TestExpandVirtualMachineScaleSetPublicIPAddress_ipTagSku(t *testing.T) {
+	publicIPAddress := func(ipTags ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                    "pip",
+			"domain_name_label":       "",
+			"domain_name_label_scope": "",
+			"idle_timeout_in_minutes": 4,
+			"public_ip_prefix_id":     "",
+			"version":                 string(virtualmachinescalesets.IPVersionIPvFour),
+			"delete_option":           "",
+			"ip_tag":                  ipTags,
+		}
+	}
+	ipTag := map[string]interface{}{
+		"type": "RoutingPreference",
+		"tag":  "Internet",
+	}
+
+	cases := []struct {
+		name        string
+		input       map[string]interface{}
+		sku         *virtualmachinescalesets.PublicIPAddressSku
+		shouldError bool
+	}{
+		{
+			name:  "ip tag with an unknown SKU",
+			input: publicIPAddress(ipTag),
+		},
+		{
+			name:  "ip tag with a Standard SKU",
+			input: publicIPAddress(ipTag),
+			sku:   &virtualmachinescalesets.PublicIPAddressSku{Name: pointer.To(virtualmachinescalesets.PublicIPAddressSkuNameStandard)},
+		},
+		{
+			name:  "no ip tag with a Basic SKU",
+			input: publicIPAddress(),
+			sku:   &virtualmachinescalesets.PublicIPAddressSku{Name: pointer.To(virtualmachinescalesets.PublicIPAddressSkuNameBasic)},
+		},
+		{
+			name:        "ip tag with a Basic SKU",
+			input:       publicIPAddress(ipTag),
+			sku:         &virtualmachinescalesets.PublicIPAddressSku{Name: pointer.To(virtualmachinescalesets.PublicIPAddressSkuNameBasic)},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		if _, err := expandVirtualMachineScaleSetPublicIPAddress(tc.input, tc.sku); tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q when creating: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+		if _, err := expandVirtualMachineScaleSetPublicIPAddressUpdate(tc.input, tc.sku); tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q when updating: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
@@ -7,15 +7,22 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetrollingupgrades"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesets"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetvms"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/services/compute/client"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/pluginsdk"
 )
 
+// virtualMachineScaleSetExtensionRemovalTimeout bounds how long we wait for removed Extensions to disappear from the
+// instances, since this is a best-effort wait to avoid a subsequent read briefly showing the removed Extension
+const virtualMachineScaleSetExtensionRemovalTimeout = 15 * time.Minute
+
 type virtualMachineScaleSetUpdateMetaData struct {
 	// is "automaticOSUpgrade" enable in the upgradeProfile block
 	AutomaticOSUpgradeIsEnabled bool
@@ -29,6 +36,12 @@ type virtualMachineScaleSetUpdateMetaData struct {
 	// do we need to roll the instances in this scale set?
 	UpdateInstances bool
 
+	// the names of the Extensions which have been removed, which we wait to be removed from the instances
+	RemovedExtensionNames []string
+
+	// the `provisioning_timeout` of each of the Extensions, which bounds how long we wait for them to be provisioned
+	ExtensionProvisioningTimeouts map[string]time.Duration
+
 	Client   *client.Client
 	Existing virtualmachinescalesets.VirtualMachineScaleSet
 	ID       *virtualmachinescalesets.VirtualMachineScaleSetId
@@ -49,6 +62,13 @@ func (metadata virtualMachineScaleSetUpdateMetaData) performUpdate(ctx context.C
 		return err
 	}
 
+	// tracks whether the instances have been upgraded to the latest model, either by us or by the platform
+	instancesUpgraded := false
+	if props := metadata.Existing.Properties; props != nil && props.UpgradePolicy != nil {
+		existingUpgradeMode := pointer.From(props.UpgradePolicy.Mode)
+		instancesUpgraded = existingUpgradeMode == virtualmachinescalesets.UpgradeModeAutomatic || existingUpgradeMode == virtualmachinescalesets.UpgradeModeRolling
+	}
+
 	// if we update the SKU, we also need to subsequently roll the instances using the `UpdateInstances` API
 	if metadata.UpdateInstances {
 		userWantsToRollInstances := metadata.CanRollInstancesWhenRequired
@@ -67,6 +87,7 @@ func (metadata virtualMachineScaleSetUpdateMetaData) performUpdate(ctx context.C
 				if err := metadata.upgradeInstancesForManualUpgradePolicy(ctx); err != nil {
 					return err
 				}
+				instancesUpgraded = true
 			}
 		}
 	}
@@ -86,6 +107,12 @@ func (metadata virtualMachineScaleSetUpdateMetaData) performUpdate(ctx context.C
 		}
 	}
 
+	// the instances only drop removed Extensions once they've been upgraded to the latest model, otherwise the
+	// Extensions remain on the instances (and in the Instance View) until they're next upgraded
+	if len(metadata.RemovedExtensionNames) > 0 && instancesUpgraded {
+		metadata.waitForExtensionsToBeRemoved(ctx)
+	}
+
 	return nil
 }
 
@@ -94,7 +121,13 @@ func (metadata virtualMachineScaleSetUpdateMetaData) updateVmss(ctx context.Cont
 	id := metadata.ID
 
 	log.Printf("[DEBUG] Updating %s %s", metadata.OSType, id)
-	if err := client.UpdateThenPoll(ctx, *id, update, virtualmachinescalesets.DefaultUpdateOperationOptions()); err != nil {
+	err := pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(ctx, metadata.ExtensionProvisioningTimeouts, virtualMachineScaleSetExtensionProvisioningTimeoutPollInterval, func(ctx context.Context) error {
+		return client.UpdateThenPoll(ctx, *id, update, virtualmachinescalesets.DefaultUpdateOperationOptions())
+	}, virtualMachineScaleSetProvisionedExtensionsFunc(client, *id))
+	if err != nil {
+		if err := metadata.scaleOutAllocationFailedError(update, err); err != nil {
+			return err
+		}
 		return fmt.Errorf("updating %s %s: %+v", metadata.OSType, id, err)
 	}
 	log.Printf("[DEBUG] Updated %s %s", metadata.OSType, id)
@@ -169,6 +202,123 @@ func (metadata virtualMachineScaleSetUpdateMetaData) upgradeInstancesForManualUp
 	return nil
 }
 
+// waitForExtensionsToBeRemoved is best-effort, since the Scale Set has already been updated - as such a failure or
+// timeout is logged rather than failing the apply
+func (metadata virtualMachineScaleSetUpdateMetaData) waitForExtensionsToBeRemoved(ctx context.Context) {
+	client := metadata.Client.VirtualMachineScaleSetsClient
+	id := metadata.ID
+
+	log.Printf("[DEBUG] Waiting for the Extensions %q to be removed from the instances of %s %s..", strings.Join(metadata.RemovedExtensionNames, ", "), metadata.OSType, id)
+	instanceViewExtensionNames := func(ctx context.Context) ([]string, error) {
+		resp, err := client.GetInstanceView(ctx, *id)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving the Instance View for %s %s: %+v", metadata.OSType, id, err)
+		}
+
+		names := make([]string, 0)
+		if model := resp.Model; model != nil && model.Extensions != nil {
+			for _, extension := range *model.Extensions {
+				if extension.Name != nil {
+					names = append(names, *extension.Name)
+				}
+			}
+		}
+		return names, nil
+	}
+
+	if err := waitForVirtualMachineScaleSetExtensionsToBeRemoved(ctx, metadata.RemovedExtensionNames, virtualMachineScaleSetExtensionRemovalTimeout, 15*time.Second, instanceViewExtensionNames); err != nil {
+		log.Printf("[WARN] waiting for the Extensions %q to be removed from %s %s: %+v", strings.Join(metadata.RemovedExtensionNames, ", "), metadata.OSType, id, err)
+		return
+	}
+	log.Printf("[DEBUG] The Extensions have been removed from the instances of %s %s.", metadata.OSType, id)
+}
+
+// waitForVirtualMachineScaleSetExtensionsToBeRemoved polls the names of the Extensions present on the instances until none
+// of the removed Extensions remain, or the timeout (bounded by the context's deadline) elapses
+func waitForVirtualMachineScaleSetExtensionsToBeRemoved(ctx context.Context, removed []string, timeout time.Duration, pollInterval time.Duration, extensionNames func(ctx context.Context) ([]string, error)) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	stateConf := &pluginsdk.StateChangeConf{
+		Pending: []string{"Present"},
+		Target:  []string{"Removed"},
+		Refresh: func() (interface{}, string, error) {
+			names, err := extensionNames(ctx)
+			if err != nil {
+				return nil, "", err
+			}
+
+			for _, name := range names {
+				for _, removedName := range removed {
+					if strings.EqualFold(name, removedName) {
+						log.Printf("[DEBUG] Extension %q is still present on the instances", name)
+						return names, "Present", nil
+					}
+				}
+			}
+
+			return names, "Removed", nil
+		},
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+	}
+
+	if _, err := stateConf.WaitForStateContext(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// virtualMachineScaleSetRemovedExtensionNames returns the names of the Extensions which exist in before but not in after - an
+// Extension which is updated is present in both (with the same name) and so isn't considered removed
+func virtualMachineScaleSetRemovedExtensionNames(before []interface{}, after []interface{}) []string {
+	names := make(map[string]struct{}, len(after))
+	for _, v := range after {
+		names[strings.ToLower(v.(map[string]interface{})["name"].(string))] = struct{}{}
+	}
+
+	removed := make([]string, 0)
+	for _, v := range before {
+		name := v.(map[string]interface{})["name"].(string)
+		if _, ok := names[strings.ToLower(name)]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return removed
+}
+
+// VirtualMachineScaleSetChangedExtensions compares the expanded Extensions in after against those in before (matched by name)
+// and returns only the Extensions which have been added or whose configuration has changed - Extensions which have been
+// removed are returned by virtualMachineScaleSetRemovedExtensionNames. Since `settings` and `protected_settings` are compared
+// once they've been unmarshaled, reformatting the JSON without changing its content isn't considered a change.
+func VirtualMachineScaleSetChangedExtensions(before []interface{}, after []interface{}) ([]virtualmachinescalesets.VirtualMachineScaleSetExtension, error) {
+	existing := make(map[string]*virtualmachinescalesets.VirtualMachineScaleSetExtensionProperties, len(before))
+	for _, v := range before {
+		extension, err := expandVirtualMachineScaleSetExtension(v.(map[string]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("expanding the existing Extension %q: %+v", v.(map[string]interface{})["name"].(string), err)
+		}
+		existing[strings.ToLower(pointer.From(extension.Name))] = extension.Properties
+	}
+
+	changed := make([]virtualmachinescalesets.VirtualMachineScaleSetExtension, 0)
+	for _, v := range after {
+		extension, err := expandVirtualMachineScaleSetExtension(v.(map[string]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("expanding the Extension %q: %+v", v.(map[string]interface{})["name"].(string), err)
+		}
+
+		if props, ok := existing[strings.ToLower(pointer.From(extension.Name))]; ok && reflect.DeepEqual(props, extension.Properties) {
+			continue
+		}
+		changed = append(changed, *extension)
+	}
+
+	return changed, nil
+}
+
 func isUsingLatestImage(update virtualmachinescalesets.VirtualMachineScaleSetUpdate) bool {
 	if update.Properties.VirtualMachineProfile.StorageProfile == nil ||
 		update.Properties.VirtualMachineProfile.StorageProfile.ImageReference == nil ||
@@ -180,3 +330,92 @@ func isUsingLatestImage(update virtualmachinescalesets.VirtualMachineScaleSetUpd
 	}
 	return false
 }
+
+// virtualMachineScaleSetAllocationFailureCodes are the error codes returned by the API when the region (or zone)
+// doesn't have enough capacity available to allocate the requested instances.
+var virtualMachineScaleSetAllocationFailureCodes = []string{
+	"AllocationFailed",
+	"OverconstrainedAllocationRequest",
+	"OverconstrainedZonalAllocationRequest",
+	"ZonalAllocationFailed",
+}
+
+// scaleOutAllocationFailedError returns a more actionable error than the raw API error when increasing the capacity of
+// the Scale Set failed due to insufficient regional/zonal capacity - nil is returned for any other error.
+func (metadata virtualMachineScaleSetUpdateMetaData) scaleOutAllocationFailedError(update virtualmachinescalesets.VirtualMachineScaleSetUpdate, err error) error {
+	if update.Sku == nil || update.Sku.Capacity == nil {
+		return nil
+	}
+
+	existingCapacity := int64(0)
+	if metadata.Existing.Sku != nil {
+		existingCapacity = pointer.From(metadata.Existing.Sku.Capacity)
+	}
+	if *update.Sku.Capacity <= existingCapacity {
+		return nil
+	}
+
+	allocationFailed := false
+	for _, code := range virtualMachineScaleSetAllocationFailureCodes {
+		if strings.Contains(err.Error(), code) {
+			allocationFailed = true
+			break
+		}
+	}
+	if !allocationFailed {
+		return nil
+	}
+
+	skuName := pointer.From(update.Sku.Name)
+	if skuName == "" && metadata.Existing.Sku != nil {
+		skuName = pointer.From(metadata.Existing.Sku.Name)
+	}
+
+	return fmt.Errorf("scaling out %s %s from %d to %d instances: there isn't currently enough capacity available for the SKU %q - consider spreading the instances across additional `zones`, using a different `sku` or retrying later: %+v", metadata.OSType, metadata.ID, existingCapacity, *update.Sku.Capacity, skuName, err)
+}
+
+// capacityAfterFailedUpdate returns the actual capacity of the Scale Set when an update changing the capacity failed,
+// since some of the instances may have been provisioned (or removed) before the failure - this allows the state to reflect
+// reality so that a subsequent apply reconciles the capacity. The `sku.capacity` returned by the API is the requested
+// capacity rather than the actual capacity, so this counts the instances which were provisioned successfully instead.
+// nil is returned when the capacity can't be determined.
+func (metadata virtualMachineScaleSetUpdateMetaData) capacityAfterFailedUpdate(ctx context.Context, update virtualmachinescalesets.VirtualMachineScaleSetUpdate) *int64 {
+	return virtualMachineScaleSetCapacityAfterFailedUpdate(ctx, update, func(ctx context.Context) (*int64, error) {
+		virtualMachineScaleSetId := virtualmachinescalesetvms.NewVirtualMachineScaleSetID(metadata.ID.SubscriptionId, metadata.ID.ResourceGroupName, metadata.ID.VirtualMachineScaleSetName)
+		result, err := metadata.Client.VirtualMachineScaleSetVMsClient.ListComplete(ctx, virtualMachineScaleSetId, virtualmachinescalesetvms.DefaultListOperationOptions())
+		if err != nil {
+			return nil, fmt.Errorf("listing the instances of %s %s: %+v", metadata.OSType, metadata.ID, err)
+		}
+
+		return pointer.To(virtualMachineScaleSetSucceededInstanceCount(result.Items)), nil
+	})
+}
+
+// virtualMachineScaleSetSucceededInstanceCount returns the number of instances which have been provisioned successfully
+func virtualMachineScaleSetSucceededInstanceCount(input []virtualmachinescalesetvms.VirtualMachineScaleSetVM) int64 {
+	count := int64(0)
+	for _, item := range input {
+		if props := item.Properties; props != nil && strings.EqualFold(pointer.From(props.ProvisioningState), "Succeeded") {
+			count++
+		}
+	}
+	return count
+}
+
+func virtualMachineScaleSetCapacityAfterFailedUpdate(ctx context.Context, update virtualmachinescalesets.VirtualMachineScaleSetUpdate, getCapacity func(ctx context.Context) (*int64, error)) *int64 {
+	if update.Sku == nil || update.Sku.Capacity == nil {
+		return nil
+	}
+
+	capacity, err := getCapacity(ctx)
+	if err != nil {
+		log.Printf("[WARN] determining the capacity after the update failed: %+v", err)
+		return nil
+	}
+
+	if capacity != nil && *capacity != *update.Sku.Capacity {
+		log.Printf("[DEBUG] the capacity is %d rather than the requested %d after the update failed", *capacity, *update.Sku.Capacity)
+	}
+
+	return capacity
+}
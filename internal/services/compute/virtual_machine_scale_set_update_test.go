@@ -0,0 +1,309 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesets"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesetvms"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
+)
+
+func TestVirtualMachineScaleSetUpdateScaleOutAllocationFailedError(t *testing.T) {
+	allocationFailed := pollers.PollingFailedError{
+		Message: "the Azure API returned the following error:\n\nStatus: \"Failed\"\nCode: \"ZonalAllocationFailed\"\nMessage: \"Allocation failed. We do not have sufficient capacity for the requested VM size in this zone.\"",
+	}
+	otherFailure := pollers.PollingFailedError{
+		Message: "the Azure API returned the following error:\n\nStatus: \"Failed\"\nCode: \"InternalServerError\"\nMessage: \"An internal error occurred.\"",
+	}
+
+	metadata := virtualMachineScaleSetUpdateMetaData{
+		Existing: virtualmachinescalesets.VirtualMachineScaleSet{
+			Sku: &virtualmachinescalesets.Sku{
+				Name:     pointer.To("Standard_F2"),
+				Capacity: pointer.To(int64(2)),
+			},
+		},
+		ID:     pointer.To(virtualmachinescalesets.NewVirtualMachineScaleSetID("12345678-1234-9876-4563-123456789012", "group1", "vmss1")),
+		OSType: virtualmachinescalesets.OperatingSystemTypesLinux,
+	}
+	update := func(capacity *int64) virtualmachinescalesets.VirtualMachineScaleSetUpdate {
+		return virtualmachinescalesets.VirtualMachineScaleSetUpdate{
+			Sku: &virtualmachinescalesets.Sku{
+				Capacity: capacity,
+			},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		update   virtualmachinescalesets.VirtualMachineScaleSetUpdate
+		err      error
+		expected bool
+	}{
+		{
+			name:     "scale out with an allocation failure",
+			update:   update(pointer.To(int64(100))),
+			err:      allocationFailed,
+			expected: true,
+		},
+		{
+			name:     "scale out with another failure",
+			update:   update(pointer.To(int64(100))),
+			err:      otherFailure,
+			expected: false,
+		},
+		{
+			name:     "scale in with an allocation failure",
+			update:   update(pointer.To(int64(1))),
+			err:      allocationFailed,
+			expected: false,
+		},
+		{
+			name:     "capacity unchanged with an allocation failure",
+			update:   virtualmachinescalesets.VirtualMachineScaleSetUpdate{},
+			err:      fmt.Errorf("wrapped: %+v", allocationFailed),
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		err := metadata.scaleOutAllocationFailedError(tc.update, tc.err)
+		if !tc.expected {
+			if err != nil {
+				t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Fatalf("expected an error for %q but didn't get one", tc.name)
+		}
+		if !strings.Contains(err.Error(), `from 2 to 100 instances`) || !strings.Contains(err.Error(), `"Standard_F2"`) || !strings.Contains(err.Error(), "ZonalAllocationFailed") {
+			t.Fatalf("expected the error for %q to include the capacity, SKU and the API error but got: %+v", tc.name, err)
+		}
+	}
+}
+
+func TestWaitForVirtualMachineScaleSetExtensionsToBeRemoved(t *testing.T) {
+	responses := [][]string{
+		{"HealthExtension", "CustomScript"},
+		{"healthextension", "CustomScript"},
+		{"CustomScript"},
+	}
+	calls := 0
+	extensionNames := func(ctx context.Context) ([]string, error) {
+		names := responses[len(responses)-1]
+		if calls < len(responses) {
+			names = responses[calls]
+		}
+		calls++
+		return names, nil
+	}
+
+	if err := waitForVirtualMachineScaleSetExtensionsToBeRemoved(context.TODO(), []string{"HealthExtension"}, time.Minute, time.Millisecond, extensionNames); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+	if calls != len(responses) {
+		t.Fatalf("expected the extensions to be retrieved %d times but got %d", len(responses), calls)
+	}
+
+	alwaysPresent := func(ctx context.Context) ([]string, error) {
+		return []string{"HealthExtension"}, nil
+	}
+	if err := waitForVirtualMachineScaleSetExtensionsToBeRemoved(context.TODO(), []string{"HealthExtension"}, 50*time.Millisecond, time.Millisecond, alwaysPresent); err == nil {
+		t.Fatalf("expected an error when the extension isn't removed within the timeout but didn't get one")
+	}
+
+	failing := func(ctx context.Context) ([]string, error) {
+		return nil, fmt.Errorf("internal server error")
+	}
+	if err := waitForVirtualMachineScaleSetExtensionsToBeRemoved(context.TODO(), []string{"HealthExtension"}, time.Minute, time.Millisecond, failing); err == nil {
+		t.Fatalf("expected an error when retrieving the extensions fails but didn't get one")
+	}
+}
+
+func TestVirtualMachineScaleSetRemovedExtensionNames(t *testing.T) {
+	extension := func(name string) interface{} {
+		return map[string]interface{}{
+			"name": name,
+		}
+	}
+
+	removed := virtualMachineScaleSetRemovedExtensionNames(
+		[]interface{}{extension("HealthExtension"), extension("CustomScript"), extension("Monitoring")},
+		[]interface{}{extension("customscript"), extension("Other")},
+	)
+	if len(removed) != 2 || removed[0] != "HealthExtension" || removed[1] != "Monitoring" {
+		t.Fatalf("expected `HealthExtension` and `Monitoring` to be removed but got %+v", removed)
+	}
+}
+
+func TestVirtualMachineScaleSetChangedExtensions(t *testing.T) {
+	extension := func(name string, settings string) interface{} {
+		return map[string]interface{}{
+			"name":                              name,
+			"publisher":                         "Microsoft.Azure.Extensions",
+			"type":                              "CustomScript",
+			"type_handler_version":              "2.0",
+			"auto_upgrade_minor_version":        true,
+			"automatic_upgrade_enabled":         false,
+			"force_update_tag":                  "",
+			"provision_after_extensions":        []interface{}{},
+			"settings":                          settings,
+			"protected_settings":                "",
+			"protected_settings_from_key_vault": []interface{}{},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		before   []interface{}
+		after    []interface{}
+		expected []string
+	}{
+		{
+			name:     "unchanged",
+			before:   []interface{}{extension("first", `{"a":"b"}`), extension("second", "")},
+			after:    []interface{}{extension("first", `{"a":"b"}`), extension("second", "")},
+			expected: []string{},
+		},
+		{
+			name:     "settings reformatted",
+			before:   []interface{}{extension("first", `{"a":"b","c":1}`)},
+			after:    []interface{}{extension("first", `{ "c": 1, "a": "b" }`)},
+			expected: []string{},
+		},
+		{
+			name:     "only settings changed",
+			before:   []interface{}{extension("first", `{"a":"b"}`), extension("second", `{"a":"b"}`)},
+			after:    []interface{}{extension("first", `{"a":"b"}`), extension("second", `{"a":"c"}`)},
+			expected: []string{"second"},
+		},
+		{
+			name:     "extension added",
+			before:   []interface{}{extension("first", "")},
+			after:    []interface{}{extension("first", ""), extension("second", "")},
+			expected: []string{"second"},
+		},
+		{
+			name:     "extension removed",
+			before:   []interface{}{extension("first", ""), extension("second", "")},
+			after:    []interface{}{extension("first", "")},
+			expected: []string{},
+		},
+		{
+			name:     "extension replaced",
+			before:   []interface{}{extension("first", "")},
+			after:    []interface{}{extension("second", "")},
+			expected: []string{"second"},
+		},
+	}
+
+	for _, tc := range cases {
+		changed, err := VirtualMachineScaleSetChangedExtensions(tc.before, tc.after)
+		if err != nil {
+			t.Fatalf("expected no error for %q but got: %+v", tc.name, err)
+		}
+
+		names := make([]string, 0)
+		for _, extension := range changed {
+			names = append(names, pointer.From(extension.Name))
+		}
+		if strings.Join(names, ",") != strings.Join(tc.expected, ",") {
+			t.Fatalf("expected the changed Extensions for %q to be %+v but got %+v", tc.name, tc.expected, names)
+		}
+	}
+
+	if _, err := VirtualMachineScaleSetChangedExtensions(nil, []interface{}{extension("first", "{")}); err == nil {
+		t.Fatalf("expected an error for invalid `settings` but didn't get one")
+	}
+}
+
+func TestWaitForVirtualMachineScaleSetInstancesToSettle(t *testing.T) {
+	responses := []int64{5, 4, 3}
+	calls := 0
+	instanceCount := func(ctx context.Context) (int64, error) {
+		count := responses[len(responses)-1]
+		if calls < len(responses) {
+			count = responses[calls]
+		}
+		calls++
+		return count, nil
+	}
+
+	if err := waitForVirtualMachineScaleSetInstancesToSettle(context.TODO(), 3, time.Minute, time.Millisecond, instanceCount); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+	if calls != len(responses) {
+		t.Fatalf("expected the instances to be listed %d times but got %d", len(responses), calls)
+	}
+
+	alwaysOverprovisioned := func(ctx context.Context) (int64, error) {
+		return 5, nil
+	}
+	if err := waitForVirtualMachineScaleSetInstancesToSettle(context.TODO(), 3, 50*time.Millisecond, time.Millisecond, alwaysOverprovisioned); err == nil {
+		t.Fatalf("expected an error when the instances don't settle within the timeout but didn't get one")
+	}
+
+	failing := func(ctx context.Context) (int64, error) {
+		return 0, fmt.Errorf("internal server error")
+	}
+	if err := waitForVirtualMachineScaleSetInstancesToSettle(context.TODO(), 3, time.Minute, time.Millisecond, failing); err == nil {
+		t.Fatalf("expected an error when listing the instances fails but didn't get one")
+	}
+}
+
+func TestVirtualMachineScaleSetCapacityAfterFailedUpdate(t *testing.T) {
+	update := virtualmachinescalesets.VirtualMachineScaleSetUpdate{
+		Sku: &virtualmachinescalesets.Sku{
+			Capacity: pointer.To(int64(5)),
+		},
+	}
+
+	// only 3 of the requested 5 instances were provisioned before the update failed
+	instance := func(provisioningState string) virtualmachinescalesetvms.VirtualMachineScaleSetVM {
+		return virtualmachinescalesetvms.VirtualMachineScaleSetVM{
+			Properties: &virtualmachinescalesetvms.VirtualMachineScaleSetVMProperties{
+				ProvisioningState: pointer.To(provisioningState),
+			},
+		}
+	}
+	instances := []virtualmachinescalesetvms.VirtualMachineScaleSetVM{
+		instance("Succeeded"),
+		instance("succeeded"),
+		instance("Succeeded"),
+		instance("Failed"),
+		instance("Creating"),
+		{},
+	}
+	partiallyScaled := func(ctx context.Context) (*int64, error) {
+		return pointer.To(virtualMachineScaleSetSucceededInstanceCount(instances)), nil
+	}
+	if actual := virtualMachineScaleSetCapacityAfterFailedUpdate(context.TODO(), update, partiallyScaled); pointer.From(actual) != 3 {
+		t.Fatalf("expected the capacity to be 3 but got %+v", actual)
+	}
+
+	calls := 0
+	counted := func(ctx context.Context) (*int64, error) {
+		calls++
+		return pointer.To(int64(3)), nil
+	}
+	if actual := virtualMachineScaleSetCapacityAfterFailedUpdate(context.TODO(), virtualmachinescalesets.VirtualMachineScaleSetUpdate{}, counted); actual != nil || calls != 0 {
+		t.Fatalf("expected the capacity not to be retrieved when it wasn't being updated but got %+v after %d calls", actual, calls)
+	}
+
+	failing := func(ctx context.Context) (*int64, error) {
+		return nil, fmt.Errorf("internal server error")
+	}
+	if actual := virtualMachineScaleSetCapacityAfterFailedUpdate(context.TODO(), update, failing); actual != nil {
+		t.Fatalf("expected no capacity when retrieving the Scale Set fails but got %+v", actual)
+	}
+}
@@ -4,6 +4,7 @@
 package compute
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -20,6 +21,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-01/images"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-01/proximityplacementgroups"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2024-03-01/virtualmachinescalesets"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-provider-azurerm/helpers/azure"
 	"github.com/hashicorp/terraform-provider-azurerm/helpers/tf"
@@ -36,10 +38,10 @@ import (
 
 func resourceWindowsVirtualMachineScaleSet() *pluginsdk.Resource {
 	return &pluginsdk.Resource{
-		Create: resourceWindowsVirtualMachineScaleSetCreate,
-		Read:   resourceWindowsVirtualMachineScaleSetRead,
-		Update: resourceWindowsVirtualMachineScaleSetUpdate,
-		Delete: resourceWindowsVirtualMachineScaleSetDelete,
+		CreateContext: resourceWindowsVirtualMachineScaleSetCreate,
+		Read:          resourceWindowsVirtualMachineScaleSetRead,
+		UpdateContext: resourceWindowsVirtualMachineScaleSetUpdate,
+		Delete:        resourceWindowsVirtualMachineScaleSetDelete,
 
 		Importer: pluginsdk.ImporterValidatingResourceIdThen(func(id string) error {
 			_, err := commonids.ParseVirtualMachineScaleSetID(id)
@@ -57,28 +59,56 @@ func resourceWindowsVirtualMachineScaleSet() *pluginsdk.Resource {
 		// https://github.com/Azure/azure-rest-api-specs/pull/7246
 
 		Schema: resourceWindowsVirtualMachineScaleSetSchema(),
+
+		// `custom_data` can be updated in-place but cannot be removed, since the API only accepts a Base64 encoded value,
+		// a Capacity Reservation Group can't be used with Spot instances, the credentials required by the API must be present,
+		// the Backend Address Pools can span at most two Load Balancers,
+		// an Ephemeral OS Disk must fit within the cache or resource disk of the SKU, `ultra_ssd_enabled` can only be changed in-place
+		// when there are no instances and from 4.0, the Extensions must be configured consistently with the rest of the Scale Set and
+		// the only IP Configuration within a Network Interface must be marked as `primary`
+		CustomizeDiff: pluginsdk.CustomDiffWithAll(
+			pluginsdk.ForceNewIfChange("custom_data", func(ctx context.Context, old, new, meta interface{}) bool {
+				if !features.FourPointOhBeta() {
+					return false
+				}
+				return old.(string) != "" && new.(string) == ""
+			}),
+			virtualMachineScaleSetCapacityReservationPriorityCustomizeDiff,
+			virtualMachineScaleSetCredentialsCustomizeDiff(virtualmachinescalesets.OperatingSystemTypesWindows),
+			virtualMachineScaleSetEphemeralOSDiskSizeCustomizeDiff,
+			virtualMachineScaleSetExtensionsCustomizeDiff,
+			virtualMachineScaleSetLoadBalancersCustomizeDiff,
+			virtualMachineScaleSetIPConfigurationPrimaryCustomizeDiff,
+			virtualMachineScaleSetUltraSSDCustomizeDiff,
+		),
 	}
 }
 
-func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta interface{}) error {
+func resourceWindowsVirtualMachineScaleSetCreate(_ context.Context, d *pluginsdk.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*clients.Client).Compute.VirtualMachineScaleSetsClient
 	subscriptionId := meta.(*clients.Client).Account.SubscriptionId
 	ctx, cancel := timeouts.ForCreate(meta.(*clients.Client).StopContext, d)
 	defer cancel()
 
+	// advisory checks are returned as warnings alongside the result of the Create/Update
+	var diags diag.Diagnostics
+
 	id := virtualmachinescalesets.NewVirtualMachineScaleSetID(subscriptionId, d.Get("resource_group_name").(string), d.Get("name").(string))
 	exists, err := client.Get(ctx, id, virtualmachinescalesets.DefaultGetOperationOptions())
 	if err != nil {
 		if !response.WasNotFound(exists.HttpResponse) {
-			return fmt.Errorf("checking for existing Windows %s: %+v", id, err)
+			return diag.Errorf("checking for existing Windows %s: %+v", id, err)
 		}
 	}
 
 	if !response.WasNotFound(exists.HttpResponse) {
-		return tf.ImportAsExistsError("azurerm_windows_virtual_machine_scale_set", id.ID())
+		return diag.FromErr(tf.ImportAsExistsError("azurerm_windows_virtual_machine_scale_set", id.ID()))
 	}
 
 	t := d.Get("tags").(map[string]interface{})
+	if err := validateVirtualMachineScaleSetTags(t); err != nil {
+		return diag.Errorf("validating `tags`: %+v", err)
+	}
 
 	additionalCapabilitiesRaw := d.Get("additional_capabilities").([]interface{})
 	additionalCapabilities := ExpandVirtualMachineScaleSetAdditionalCapabilities(additionalCapabilitiesRaw)
@@ -87,30 +117,59 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	additionalUnattendContent := expandAdditionalUnattendContentVMSS(additionalUnattendContentRaw)
 
 	bootDiagnosticsRaw := d.Get("boot_diagnostics").([]interface{})
-	bootDiagnostics := expandBootDiagnosticsVMSS(bootDiagnosticsRaw)
+	bootDiagnostics := ExpandVirtualMachineScaleSetBootDiagnostics(bootDiagnosticsRaw)
 
 	dataDisksRaw := d.Get("data_disk").([]interface{})
 	ultraSSDEnabled := d.Get("additional_capabilities.0.ultra_ssd_enabled").(bool)
-	dataDisks, err := ExpandVirtualMachineScaleSetDataDisk(dataDisksRaw, ultraSSDEnabled)
+	dataDisks, err := ExpandVirtualMachineScaleSetDataDisk(dataDisksRaw, ultraSSDEnabled, zones.ExpandUntyped(d.Get("zones").(*schema.Set).List()))
 	if err != nil {
-		return fmt.Errorf("expanding `data_disk`: %+v", err)
+		return diag.Errorf("expanding `data_disk`: %+v", err)
 	}
 
 	identityExpanded, err := identity.ExpandSystemAndUserAssignedMap(d.Get("identity").([]interface{}))
 	if err != nil {
-		return fmt.Errorf("expanding `identity`: %+v", err)
+		return diag.Errorf("expanding `identity`: %+v", err)
 	}
 
 	networkInterfacesRaw := d.Get("network_interface").([]interface{})
-	networkInterfaces, err := ExpandVirtualMachineScaleSetNetworkInterface(networkInterfacesRaw)
+	publicIPAddressSku, err := virtualMachineScaleSetPublicIPAddressSku(ctx, meta.(*clients.Client).Network.LoadBalancers, networkInterfacesRaw)
+	if err != nil {
+		return diag.Errorf("determining the SKU of the Public IP Addresses for `network_interface`: %+v", err)
+	}
+	networkInterfaces, err := ExpandVirtualMachineScaleSetNetworkInterface(networkInterfacesRaw, publicIPAddressSku)
 	if err != nil {
-		return fmt.Errorf("expanding `network_interface`: %+v", err)
+		return diag.Errorf("expanding `network_interface`: %+v", err)
+	}
+	diags = append(diags, virtualMachineScaleSetIPv6LoadBalancerWarnings(networkInterfacesRaw)...)
+	if err := validateVirtualMachineScaleSetNetworkInterfaceFpga(networkInterfacesRaw, d.Get("sku").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	if features.EnhancedValidationEnabled() {
+		if err := checkVirtualMachineScaleSetApplicationSecurityGroupLocations(ctx, meta.(*clients.Client).Network.ApplicationSecurityGroups, networkInterfacesRaw, d.Get("location").(string)); err != nil {
+			return diag.Errorf("validating `network_interface`: %+v", err)
+		}
+		if err := checkVirtualMachineScaleSetAcceleratedNetworkingSupported(ctx, meta.(*clients.Client).Compute, d.Get("location").(string), d.Get("sku").(string), networkInterfacesRaw); err != nil {
+			return diag.Errorf("validating `network_interface`: %+v", err)
+		}
 	}
 
 	osDiskRaw := d.Get("os_disk").([]interface{})
 	osDisk, err := ExpandVirtualMachineScaleSetOSDisk(osDiskRaw, virtualmachinescalesets.OperatingSystemTypesWindows)
 	if err != nil {
-		return fmt.Errorf("expanding `os_disk`: %+v", err)
+		return diag.Errorf("expanding `os_disk`: %+v", err)
+	}
+	if err := checkVirtualMachineScaleSetOSDiskPlacementSupported(ctx, meta.(*clients.Client).Compute, d.Get("location").(string), d.Get("sku").(string), osDisk); err != nil {
+		return diag.FromErr(err)
+	}
+	if secureVMDiskEncryptionSetId := osDiskRaw[0].(map[string]interface{})["secure_vm_disk_encryption_set_id"].(string); secureVMDiskEncryptionSetId != "" {
+		if err := validateDiskEncryptionSetLocation(ctx, meta.(*clients.Client).Compute.DiskEncryptionSetsClient, secureVMDiskEncryptionSetId, d.Get("location").(string)); err != nil {
+			return diag.Errorf("validating `os_disk.0.secure_vm_disk_encryption_set_id`: %+v", err)
+		}
+	}
+	if features.EnhancedValidationEnabled() {
+		if err := checkVirtualMachineScaleSetDiskEncryptionSetTypes(ctx, meta.(*clients.Client).Compute.DiskEncryptionSetsClient, osDiskRaw, dataDisksRaw); err != nil {
+			return diag.FromErr(err)
+		}
 	}
 	securityEncryptionType := osDiskRaw[0].(map[string]interface{})["security_encryption_type"].(string)
 
@@ -120,10 +179,21 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	sourceImageReferenceRaw := d.Get("source_image_reference").([]interface{})
 	sourceImageId := d.Get("source_image_id").(string)
 	sourceImageReference := expandSourceImageReferenceVMSS(sourceImageReferenceRaw, sourceImageId)
+	if err := validatePlanMatchesSourceImageReference(planRaw, sourceImageReferenceRaw); err != nil {
+		return diag.FromErr(err)
+	}
+	if plan != nil {
+		if err := EnsureMarketplaceAgreementAccepted(ctx, meta.(*clients.Client).Compute, pointer.From(plan.Publisher), pointer.From(plan.Product), pointer.From(plan.Name)); err != nil {
+			return diag.Errorf("validating `plan`: %+v", err)
+		}
+	}
 
 	overProvision := d.Get("overprovision").(bool)
 	provisionVMAgent := d.Get("provision_vm_agent").(bool)
 	zones := zones.ExpandUntyped(d.Get("zones").(*schema.Set).List())
+	if err := ValidateZonesForLocation(ctx, meta.(*clients.Client).Compute, d.Get("location").(string), zones); err != nil {
+		return diag.Errorf("validating `zones`: %+v", err)
+	}
 	healthProbeId := d.Get("health_probe_id").(string)
 	upgradeMode := virtualmachinescalesets.UpgradeMode(d.Get("upgrade_mode").(string))
 	automaticOSUpgradePolicyRaw := d.Get("automatic_os_upgrade_policy").([]interface{})
@@ -131,21 +201,21 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	rollingUpgradePolicyRaw := d.Get("rolling_upgrade_policy").([]interface{})
 	rollingUpgradePolicy, err := ExpandVirtualMachineScaleSetRollingUpgradePolicy(rollingUpgradePolicyRaw, len(zones) > 0, overProvision)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	canHaveAutomaticOsUpgradePolicy := upgradeMode == virtualmachinescalesets.UpgradeModeAutomatic || upgradeMode == virtualmachinescalesets.UpgradeModeRolling
 	if !canHaveAutomaticOsUpgradePolicy && len(automaticOSUpgradePolicyRaw) > 0 {
-		return fmt.Errorf("an `automatic_os_upgrade_policy` block cannot be specified when `upgrade_mode` is not set to `Automatic` or `Rolling`")
+		return diag.Errorf("an `automatic_os_upgrade_policy` block cannot be specified when `upgrade_mode` is not set to `Automatic` or `Rolling`")
 	}
 
 	shouldHaveRollingUpgradePolicy := upgradeMode == virtualmachinescalesets.UpgradeModeAutomatic || upgradeMode == virtualmachinescalesets.UpgradeModeRolling
 	if !shouldHaveRollingUpgradePolicy && len(rollingUpgradePolicyRaw) > 0 {
-		return fmt.Errorf("a `rolling_upgrade_policy` block cannot be specified when `upgrade_mode` is set to %q", string(upgradeMode))
+		return diag.Errorf("a `rolling_upgrade_policy` block cannot be specified when `upgrade_mode` is set to %q", string(upgradeMode))
 	}
 	shouldHaveRollingUpgradePolicy = upgradeMode == virtualmachinescalesets.UpgradeModeRolling
 	if shouldHaveRollingUpgradePolicy && len(rollingUpgradePolicyRaw) == 0 {
-		return fmt.Errorf("a `rolling_upgrade_policy` block must be specified when `upgrade_mode` is set to %q", string(upgradeMode))
+		return diag.Errorf("a `rolling_upgrade_policy` block must be specified when `upgrade_mode` is set to %q", string(upgradeMode))
 	}
 
 	winRmListenersRaw := d.Get("winrm_listener").(*pluginsdk.Set).List()
@@ -160,7 +230,7 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	} else {
 		_, errs := computeValidate.WindowsComputerNamePrefix(d.Get("name"), "computer_name_prefix")
 		if len(errs) > 0 {
-			return fmt.Errorf("unable to assume default computer name prefix %s. Please adjust the %q, or specify an explicit %q", errs[0], "name", "computer_name_prefix")
+			return diag.Errorf("unable to assume default computer name prefix %s. Please adjust the %q, or specify an explicit %q", errs[0], "name", "computer_name_prefix")
 		}
 		computerNamePrefix = id.VirtualMachineScaleSetName
 	}
@@ -196,9 +266,10 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 		DiagnosticsProfile: bootDiagnostics,
 		NetworkProfile:     networkProfile,
 		StorageProfile: &virtualmachinescalesets.VirtualMachineScaleSetStorageProfile{
-			ImageReference: sourceImageReference,
-			OsDisk:         osDisk,
-			DataDisks:      dataDisks,
+			ImageReference:     sourceImageReference,
+			OsDisk:             osDisk,
+			DataDisks:          dataDisks,
+			DiskControllerType: ExpandVirtualMachineScaleSetDiskControllerType(d.Get("disk_controller_type").(string)),
 		},
 	}
 
@@ -216,9 +287,13 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 		}
 	}
 
+	if err := validateVirtualMachineScaleSetSinglePlacementGroupCapacity(d.Get("instances").(int), d.Get("single_placement_group").(bool)); err != nil {
+		return diag.FromErr(err)
+	}
+
 	if v, ok := d.GetOk("capacity_reservation_group_id"); ok {
 		if d.Get("single_placement_group").(bool) {
-			return fmt.Errorf("`single_placement_group` must be set to `false` when `capacity_reservation_group_id` is specified")
+			return diag.Errorf("`single_placement_group` must be set to `false` when `capacity_reservation_group_id` is specified")
 		}
 		virtualMachineProfile.CapacityReservation = &virtualmachinescalesets.CapacityReservationProfile{
 			CapacityReservationGroup: &virtualmachinescalesets.SubResource{
@@ -229,15 +304,19 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 
 	hasHealthExtension := false
 	if vmExtensionsRaw, ok := d.GetOk("extension"); ok {
-		virtualMachineProfile.ExtensionProfile, hasHealthExtension, err = expandVirtualMachineScaleSetExtensions(vmExtensionsRaw.(*pluginsdk.Set).List())
+		virtualMachineProfile.ExtensionProfile, hasHealthExtension, err = expandVirtualMachineScaleSetExtensions(vmExtensionsRaw.(*pluginsdk.Set).List(), healthProbeId)
 		if err != nil {
-			return err
+			return diag.FromErr(err)
 		}
+		diags = append(diags, virtualMachineScaleSetExtensionSettingsWarnings(vmExtensionsRaw.(*pluginsdk.Set).List())...)
+		diags = append(diags, virtualMachineScaleSetExtensionAutomaticUpgradeVersionWarnings(vmExtensionsRaw.(*pluginsdk.Set).List())...)
+		diags = append(diags, virtualMachineScaleSetHealthExtensionOrderingWarnings(vmExtensionsRaw.(*pluginsdk.Set).List())...)
+		diags = append(diags, virtualMachineScaleSetZeroInstancesExtensionsWarnings(d.Get("instances").(int), vmExtensionsRaw.(*pluginsdk.Set).List())...)
 	}
 
 	if v, ok := d.Get("extension_operations_enabled").(bool); ok {
 		if v && !provisionVMAgent {
-			return fmt.Errorf("`extension_operations_enabled` cannot be set to `true` when `provision_vm_agent` is set to `false`")
+			return diag.Errorf("`extension_operations_enabled` cannot be set to `true` when `provision_vm_agent` is set to `false`")
 		}
 
 		if !features.FourPointOhBeta() {
@@ -259,7 +338,7 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	// otherwise the service return the error:
 	// Rolling Upgrade mode is not supported for this Virtual Machine Scale Set because a health probe or health extension was not provided.
 	if upgradeMode == virtualmachinescalesets.UpgradeModeRolling && (healthProbeId == "" && !hasHealthExtension) {
-		return fmt.Errorf("`health_probe_id` must be set or a health extension must be specified when `upgrade_mode` is set to %q", string(upgradeMode))
+		return diag.Errorf("`health_probe_id` must be set or a health extension must be specified when `upgrade_mode` is set to %q", string(upgradeMode))
 	}
 
 	enableAutomaticUpdates := d.Get("enable_automatic_updates").(bool)
@@ -267,7 +346,7 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 
 	if v, ok := d.Get("max_bid_price").(float64); ok && v > 0 {
 		if priority != virtualmachinescalesets.VirtualMachinePriorityTypesSpot {
-			return fmt.Errorf("`max_bid_price` can only be configured when `priority` is set to `Spot`")
+			return diag.Errorf("`max_bid_price` can only be configured when `priority` is set to `Spot`")
 		}
 
 		virtualMachineProfile.BillingProfile = &virtualmachinescalesets.BillingProfile{
@@ -282,26 +361,24 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	if encryptionAtHostEnabled, ok := d.GetOk("encryption_at_host_enabled"); ok {
 		if encryptionAtHostEnabled.(bool) {
 			if virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState == virtualmachinescalesets.SecurityEncryptionTypes(securityEncryptionType) {
-				return fmt.Errorf("`encryption_at_host_enabled` cannot be set to `true` when `os_disk.0.security_encryption_type` is set to `DiskWithVMGuestState`")
+				return diag.Errorf("`encryption_at_host_enabled` cannot be set to `true` when `os_disk.0.security_encryption_type` is set to `DiskWithVMGuestState`")
 			}
-		}
 
-		if virtualMachineProfile.SecurityProfile == nil {
-			virtualMachineProfile.SecurityProfile = &virtualmachinescalesets.SecurityProfile{}
+			if err := checkVirtualMachineScaleSetEncryptionAtHostSupported(ctx, meta.(*clients.Client).Compute, d.Get("location").(string), d.Get("sku").(string)); err != nil {
+				return diag.FromErr(err)
+			}
 		}
-		virtualMachineProfile.SecurityProfile.EncryptionAtHost = pointer.To(encryptionAtHostEnabled.(bool))
+
+		virtualMachineProfile.SecurityProfile = ExpandVirtualMachineScaleSetEncryptionAtHost(virtualMachineProfile.SecurityProfile, encryptionAtHostEnabled.(bool))
 	}
 
 	secureBootEnabled := d.Get("secure_boot_enabled").(bool)
 	vtpmEnabled := d.Get("vtpm_enabled").(bool)
-	if securityEncryptionType != "" {
-		if virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState == virtualmachinescalesets.SecurityEncryptionTypes(securityEncryptionType) && !secureBootEnabled {
-			return fmt.Errorf("`secure_boot_enabled` must be set to `true` when `os_disk.0.security_encryption_type` is set to `DiskWithVMGuestState`")
-		}
-		if !vtpmEnabled {
-			return fmt.Errorf("`vtpm_enabled` must be set to `true` when `os_disk.0.security_encryption_type` is specified")
-		}
+	if err := ValidateConfidentialVMDiskEncryption(securityEncryptionType, vtpmEnabled, secureBootEnabled); err != nil {
+		return diag.FromErr(err)
+	}
 
+	if securityEncryptionType != "" {
 		if virtualMachineProfile.SecurityProfile == nil {
 			virtualMachineProfile.SecurityProfile = &virtualmachinescalesets.SecurityProfile{}
 		}
@@ -339,11 +416,11 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 
 	if evictionPolicyRaw, ok := d.GetOk("eviction_policy"); ok {
 		if *virtualMachineProfile.Priority != virtualmachinescalesets.VirtualMachinePriorityTypesSpot {
-			return fmt.Errorf("an `eviction_policy` can only be specified when `priority` is set to `Spot`")
+			return diag.Errorf("an `eviction_policy` can only be specified when `priority` is set to `Spot`")
 		}
 		virtualMachineProfile.EvictionPolicy = pointer.To(virtualmachinescalesets.VirtualMachineEvictionPolicyTypes(evictionPolicyRaw.(string)))
 	} else if priority == virtualmachinescalesets.VirtualMachinePriorityTypesSpot {
-		return fmt.Errorf("an `eviction_policy` must be specified when `priority` is set to `Spot`")
+		return diag.Errorf("an `eviction_policy` must be specified when `priority` is set to `Spot`")
 	}
 
 	if len(additionalUnattendContentRaw) > 0 {
@@ -373,6 +450,9 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 	}
 
 	automaticRepairsPolicyRaw := d.Get("automatic_instance_repair").([]interface{})
+	if err := validateVirtualMachineScaleSetAutomaticRepairsPolicy(automaticRepairsPolicyRaw, healthProbeId, hasHealthExtension); err != nil {
+		return diag.FromErr(err)
+	}
 	automaticRepairsPolicy := ExpandVirtualMachineScaleSetAutomaticRepairsPolicy(automaticRepairsPolicyRaw)
 
 	props := virtualmachinescalesets.VirtualMachineScaleSet{
@@ -449,31 +529,46 @@ func resourceWindowsVirtualMachineScaleSetCreate(d *pluginsdk.ResourceData, meta
 
 	if v, ok := d.GetOk("zone_balance"); ok && v.(bool) {
 		if props.Zones == nil || len(*props.Zones) == 0 {
-			return fmt.Errorf("`zone_balance` can only be set to `true` when zones are specified")
+			return diag.Errorf("`zone_balance` can only be set to `true` when zones are specified")
 		}
 
 		props.Properties.ZoneBalance = pointer.To(v.(bool))
 	}
+	diags = append(diags, virtualMachineScaleSetZoneBalanceCapacityWarnings(d.Get("instances").(int), zones, d.Get("zone_balance").(bool))...)
 
 	log.Printf("[DEBUG] Creating Windows %s.", id)
-	if err := client.CreateOrUpdateThenPoll(ctx, id, props, virtualmachinescalesets.DefaultCreateOrUpdateOperationOptions()); err != nil {
-		return fmt.Errorf("creating Windows %s: %+v", id, err)
+	// the API doesn't support a timeout for each Extension, so their `provisioning_timeout` bounds how long we wait for them instead
+	extensionProvisioningTimeouts := virtualMachineScaleSetExtensionProvisioningTimeouts(d.Get("extension").(*pluginsdk.Set).List())
+	err = pollWithinVirtualMachineScaleSetExtensionProvisioningTimeouts(ctx, extensionProvisioningTimeouts, virtualMachineScaleSetExtensionProvisioningTimeoutPollInterval, func(ctx context.Context) error {
+		return createVirtualMachineScaleSetRetryingOnProximityPlacementGroupNotFound(ctx, d.Get("proximity_placement_group_id").(string), virtualMachineScaleSetProximityPlacementGroupNotFoundRetries, virtualMachineScaleSetProximityPlacementGroupNotFoundRetryInterval, func(ctx context.Context) error {
+			return client.CreateOrUpdateThenPoll(ctx, id, props, virtualmachinescalesets.DefaultCreateOrUpdateOperationOptions())
+		})
+	}, virtualMachineScaleSetProvisionedExtensionsFunc(client, id))
+	if err != nil {
+		return diag.Errorf("creating Windows %s: %+v", id, err)
 	}
 	log.Printf("[DEBUG] Windows %s was created", id)
 
 	d.SetId(id.ID())
 
-	return resourceWindowsVirtualMachineScaleSetRead(d, meta)
+	if instances := d.Get("instances").(int); overProvision && instances > 0 {
+		waitForVirtualMachineScaleSetOverprovisionedInstancesToBeRemoved(ctx, meta.(*clients.Client).Compute, id, int64(instances))
+	}
+
+	return append(diags, diag.FromErr(resourceWindowsVirtualMachineScaleSetRead(d, meta))...)
 }
 
-func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta interface{}) error {
+func resourceWindowsVirtualMachineScaleSetUpdate(_ context.Context, d *pluginsdk.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*clients.Client).Compute.VirtualMachineScaleSetsClient
 	ctx, cancel := timeouts.ForUpdate(meta.(*clients.Client).StopContext, d)
 	defer cancel()
 
+	// advisory checks are returned as warnings alongside the result of the Create/Update
+	var diags diag.Diagnostics
+
 	id, err := virtualmachinescalesets.ParseVirtualMachineScaleSetID(d.Id())
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	updateInstances := false
@@ -482,19 +577,19 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 	options.Expand = pointer.To(virtualmachinescalesets.ExpandTypesForGetVMScaleSetsUserData)
 	existing, err := client.Get(ctx, *id, options)
 	if err != nil {
-		return fmt.Errorf("retrieving Windows %s: %+v", id, err)
+		return diag.Errorf("retrieving Windows %s: %+v", id, err)
 	}
 	if existing.Model == nil {
-		return fmt.Errorf("retrieving Windows %s: `model` was nil", id)
+		return diag.Errorf("retrieving Windows %s: `model` was nil", id)
 	}
 	if existing.Model.Properties == nil {
-		return fmt.Errorf("retrieving Windows %s: `properties` was nil", id)
+		return diag.Errorf("retrieving Windows %s: `properties` was nil", id)
 	}
 	if existing.Model.Properties.VirtualMachineProfile == nil {
-		return fmt.Errorf("retrieving Windows %s: `properties.virtualMachineProfile` was nil", id)
+		return diag.Errorf("retrieving Windows %s: `properties.virtualMachineProfile` was nil", id)
 	}
 	if existing.Model.Properties.VirtualMachineProfile.StorageProfile == nil {
-		return fmt.Errorf("retrieving Windows %s: `properties.virtualMachineProfile,storageProfile` was nil", id)
+		return diag.Errorf("retrieving Windows %s: `properties.virtualMachineProfile,storageProfile` was nil", id)
 	}
 
 	updateProps := virtualmachinescalesets.VirtualMachineScaleSetUpdateProperties{
@@ -546,7 +641,7 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 			zones := zones.ExpandUntyped(d.Get("zones").(*schema.Set).List())
 			rollingUpgradePolicy, err := ExpandVirtualMachineScaleSetRollingUpgradePolicy(rollingRaw, len(zones) > 0, d.Get("overprovision").(bool))
 			if err != nil {
-				return err
+				return diag.FromErr(err)
 			}
 			upgradePolicy.RollingUpgradePolicy = rollingUpgradePolicy
 		}
@@ -557,7 +652,7 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 	priority := virtualmachinescalesets.VirtualMachinePriorityTypes(d.Get("priority").(string))
 	if d.HasChange("max_bid_price") {
 		if priority != virtualmachinescalesets.VirtualMachinePriorityTypesSpot {
-			return fmt.Errorf("`max_bid_price` can only be configured when `priority` is set to `Spot`")
+			return diag.Errorf("`max_bid_price` can only be configured when `priority` is set to `Spot`")
 		}
 
 		updateProps.VirtualMachineProfile.BillingProfile = &virtualmachinescalesets.BillingProfile{
@@ -565,10 +660,16 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		}
 	}
 
+	if d.HasChanges("instances", "single_placement_group") {
+		if err := validateVirtualMachineScaleSetSinglePlacementGroupCapacity(d.Get("instances").(int), d.Get("single_placement_group").(bool)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	if d.HasChange("single_placement_group") {
 		singlePlacementGroup := d.Get("single_placement_group").(bool)
 		if singlePlacementGroup {
-			return fmt.Errorf("%q can not be set to %q once it has been set to %q", "single_placement_group", "true", "false")
+			return diag.Errorf("%q can not be set to %q once it has been set to %q", "single_placement_group", "true", "false")
 		}
 		updateProps.SinglePlacementGroup = pointer.To(singlePlacementGroup)
 	}
@@ -585,7 +686,7 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 
 			if d.HasChange("enable_automatic_updates") {
 				if upgradeMode == virtualmachinescalesets.UpgradeModeAutomatic {
-					return fmt.Errorf("`enable_automatic_updates` cannot be changed for when `upgrade_mode` is `Automatic`")
+					return diag.Errorf("`enable_automatic_updates` cannot be changed for when `upgrade_mode` is `Automatic`")
 				}
 
 				windowsConfig.EnableAutomaticUpdates = pointer.To(d.Get("enable_automatic_updates").(bool))
@@ -604,6 +705,7 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 
 		if d.HasChange("custom_data") {
 			updateInstances = true
+			diags = append(diags, virtualMachineScaleSetWarning("`custom_data` is only processed when an instance is provisioned", fmt.Sprintf("existing instances within %s must be reimaged to pick up the change to `custom_data` - `user_data` can be used for data which needs to be updated in-place", id)))
 
 			// customData can only be sent if it's a base64 encoded string,
 			// so it's not possible to remove this without tainting the resource
@@ -620,18 +722,22 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		updateProps.VirtualMachineProfile.OsProfile = &osProfile
 	}
 
-	if d.HasChange("data_disk") || d.HasChange("os_disk") || d.HasChange("source_image_id") || d.HasChange("source_image_reference") {
+	if d.HasChange("data_disk") || d.HasChange("disk_controller_type") || d.HasChange("os_disk") || d.HasChange("source_image_id") || d.HasChange("source_image_reference") {
 		updateInstances = true
 
 		if updateProps.VirtualMachineProfile.StorageProfile == nil {
 			updateProps.VirtualMachineProfile.StorageProfile = &virtualmachinescalesets.VirtualMachineScaleSetUpdateStorageProfile{}
 		}
 
+		if d.HasChange("disk_controller_type") {
+			updateProps.VirtualMachineProfile.StorageProfile.DiskControllerType = ExpandVirtualMachineScaleSetDiskControllerType(d.Get("disk_controller_type").(string))
+		}
+
 		if d.HasChange("data_disk") {
 			ultraSSDEnabled := d.Get("additional_capabilities.0.ultra_ssd_enabled").(bool)
-			dataDisks, err := ExpandVirtualMachineScaleSetDataDisk(d.Get("data_disk").([]interface{}), ultraSSDEnabled)
+			dataDisks, err := ExpandVirtualMachineScaleSetDataDisk(d.Get("data_disk").([]interface{}), ultraSSDEnabled, zones.ExpandUntyped(d.Get("zones").(*schema.Set).List()))
 			if err != nil {
-				return fmt.Errorf("expanding `data_disk`: %+v", err)
+				return diag.Errorf("expanding `data_disk`: %+v", err)
 			}
 			updateProps.VirtualMachineProfile.StorageProfile.DataDisks = dataDisks
 		}
@@ -639,12 +745,18 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		if d.HasChange("os_disk") {
 			osDiskRaw := d.Get("os_disk").([]interface{})
 			updateProps.VirtualMachineProfile.StorageProfile.OsDisk = ExpandVirtualMachineScaleSetOSDiskUpdate(osDiskRaw)
+
+			oldSize, newSize := d.GetChange("os_disk.0.disk_size_gb")
+			diags = append(diags, virtualMachineScaleSetOSDiskSizeIncreaseWarnings(oldSize.(int), newSize.(int))...)
 		}
 
 		if d.HasChange("source_image_id") || d.HasChange("source_image_reference") {
 			sourceImageReferenceRaw := d.Get("source_image_reference").([]interface{})
 			sourceImageId := d.Get("source_image_id").(string)
 			sourceImageReference := expandSourceImageReferenceVMSS(sourceImageReferenceRaw, sourceImageId)
+			if err := validatePlanMatchesSourceImageReference(d.Get("plan").([]interface{}), sourceImageReferenceRaw); err != nil {
+				return diag.FromErr(err)
+			}
 
 			// Must include all storage profile properties when updating disk image.  See: https://github.com/hashicorp/terraform-provider-azurerm/issues/8273
 			updateProps.VirtualMachineProfile.StorageProfile.DataDisks = existing.Model.Properties.VirtualMachineProfile.StorageProfile.DataDisks
@@ -660,12 +772,33 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		}
 	}
 
+	if d.HasChange("network_interface") || d.HasChange("sku") {
+		if err := validateVirtualMachineScaleSetNetworkInterfaceFpga(d.Get("network_interface").([]interface{}), d.Get("sku").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	if d.HasChange("network_interface") && features.EnhancedValidationEnabled() {
+		if err := checkVirtualMachineScaleSetApplicationSecurityGroupLocations(ctx, meta.(*clients.Client).Network.ApplicationSecurityGroups, d.Get("network_interface").([]interface{}), d.Get("location").(string)); err != nil {
+			return diag.Errorf("validating `network_interface`: %+v", err)
+		}
+	}
+	if (d.HasChange("network_interface") || d.HasChange("sku")) && features.EnhancedValidationEnabled() {
+		if err := checkVirtualMachineScaleSetAcceleratedNetworkingSupported(ctx, meta.(*clients.Client).Compute, d.Get("location").(string), d.Get("sku").(string), d.Get("network_interface").([]interface{})); err != nil {
+			return diag.Errorf("validating `network_interface`: %+v", err)
+		}
+	}
+
 	if d.HasChange("network_interface") || d.HasChange("health_probe_id") {
 		networkInterfacesRaw := d.Get("network_interface").([]interface{})
-		networkInterfaces, err := ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(networkInterfacesRaw)
+		publicIPAddressSku, err := virtualMachineScaleSetPublicIPAddressSku(ctx, meta.(*clients.Client).Network.LoadBalancers, networkInterfacesRaw)
 		if err != nil {
-			return fmt.Errorf("expanding `network_interface`: %+v", err)
+			return diag.Errorf("determining the SKU of the Public IP Addresses for `network_interface`: %+v", err)
 		}
+		networkInterfaces, err := ExpandVirtualMachineScaleSetNetworkInterfaceUpdate(networkInterfacesRaw, publicIPAddressSku)
+		if err != nil {
+			return diag.Errorf("expanding `network_interface`: %+v", err)
+		}
+		diags = append(diags, virtualMachineScaleSetIPv6LoadBalancerWarnings(networkInterfacesRaw)...)
 
 		updateProps.VirtualMachineProfile.NetworkProfile = &virtualmachinescalesets.VirtualMachineScaleSetUpdateNetworkProfile{
 			NetworkInterfaceConfigurations: networkInterfaces,
@@ -683,7 +816,7 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		updateInstances = true
 
 		bootDiagnosticsRaw := d.Get("boot_diagnostics").([]interface{})
-		updateProps.VirtualMachineProfile.DiagnosticsProfile = expandBootDiagnosticsVMSS(bootDiagnosticsRaw)
+		updateProps.VirtualMachineProfile.DiagnosticsProfile = ExpandVirtualMachineScaleSetBootDiagnostics(bootDiagnosticsRaw)
 	}
 
 	if d.HasChange("do_not_run_extensions_on_overprovisioned_machines") {
@@ -730,14 +863,15 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 			osDiskRaw := d.Get("os_disk").([]interface{})
 			securityEncryptionType := osDiskRaw[0].(map[string]interface{})["security_encryption_type"].(string)
 			if virtualmachinescalesets.SecurityEncryptionTypesDiskWithVMGuestState == virtualmachinescalesets.SecurityEncryptionTypes(securityEncryptionType) {
-				return fmt.Errorf("`encryption_at_host_enabled` cannot be set to `true` when `os_disk.0.security_encryption_type` is set to `DiskWithVMGuestState`")
+				return diag.Errorf("`encryption_at_host_enabled` cannot be set to `true` when `os_disk.0.security_encryption_type` is set to `DiskWithVMGuestState`")
 			}
-		}
 
-		if updateProps.VirtualMachineProfile.SecurityProfile == nil {
-			updateProps.VirtualMachineProfile.SecurityProfile = &virtualmachinescalesets.SecurityProfile{}
+			if err := checkVirtualMachineScaleSetEncryptionAtHostSupported(ctx, meta.(*clients.Client).Compute, d.Get("location").(string), d.Get("sku").(string)); err != nil {
+				return diag.FromErr(err)
+			}
 		}
-		updateProps.VirtualMachineProfile.SecurityProfile.EncryptionAtHost = pointer.To(d.Get("encryption_at_host_enabled").(bool))
+
+		updateProps.VirtualMachineProfile.SecurityProfile = ExpandVirtualMachineScaleSetEncryptionAtHost(updateProps.VirtualMachineProfile.SecurityProfile, d.Get("encryption_at_host_enabled").(bool))
 	}
 
 	if d.HasChange("license_type") {
@@ -760,7 +894,7 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 	if d.HasChange("identity") {
 		identityExpanded, err := identity.ExpandSystemAndUserAssignedMap(d.Get("identity").([]interface{}))
 		if err != nil {
-			return fmt.Errorf("expanding `identity`: %+v", err)
+			return diag.Errorf("expanding `identity`: %+v", err)
 		}
 
 		update.Identity = identityExpanded
@@ -771,6 +905,15 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		update.Plan = expandPlanVMSS(planRaw)
 	}
 
+	// this is checked against the current capacity of the Scale Set, so must happen before the `sku` is updated below
+	if d.HasChange("additional_capabilities.0.ultra_ssd_enabled") {
+		additionalCapabilities, err := ExpandVirtualMachineScaleSetAdditionalCapabilitiesUpdate(d.Get("additional_capabilities").([]interface{}), existing.Model.Sku, virtualmachinescalesets.OrchestrationModeUniform)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		updateProps.AdditionalCapabilities = additionalCapabilities
+	}
+
 	if d.HasChange("sku") || d.HasChange("instances") {
 		// in-case ignore_changes is being used, since both fields are required
 		// look up the current values and override them as needed
@@ -789,15 +932,48 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 		update.Sku = sku
 	}
 
+	removedExtensionNames := make([]string, 0)
 	if d.HasChanges("extension", "extensions_time_budget") {
-		updateInstances = true
-
-		extensionProfile, _, err := expandVirtualMachineScaleSetExtensions(d.Get("extension").(*pluginsdk.Set).List())
+		extensionProfile, _, err := expandVirtualMachineScaleSetExtensions(d.Get("extension").(*pluginsdk.Set).List(), "")
 		if err != nil {
-			return err
+			return diag.FromErr(err)
 		}
+		diags = append(diags, virtualMachineScaleSetExtensionSettingsWarnings(d.Get("extension").(*pluginsdk.Set).List())...)
+		diags = append(diags, virtualMachineScaleSetExtensionAutomaticUpgradeVersionWarnings(d.Get("extension").(*pluginsdk.Set).List())...)
+		diags = append(diags, virtualMachineScaleSetHealthExtensionOrderingWarnings(d.Get("extension").(*pluginsdk.Set).List())...)
 		updateProps.VirtualMachineProfile.ExtensionProfile = extensionProfile
 		updateProps.VirtualMachineProfile.ExtensionProfile.ExtensionsTimeBudget = pointer.To(d.Get("extensions_time_budget").(string))
+
+		oldExtensions, newExtensions := d.GetChange("extension")
+		removedExtensionNames = virtualMachineScaleSetRemovedExtensionNames(oldExtensions.(*pluginsdk.Set).List(), newExtensions.(*pluginsdk.Set).List())
+
+		// the API requires the full Extension Profile, however the instances only need to be rolled when an Extension
+		// has actually been added, changed or removed - rather than when e.g. only the `extensions_time_budget` changed
+		changedExtensions, err := VirtualMachineScaleSetChangedExtensions(oldExtensions.(*pluginsdk.Set).List(), newExtensions.(*pluginsdk.Set).List())
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if len(changedExtensions) > 0 || len(removedExtensionNames) > 0 {
+			updateInstances = true
+		}
+	}
+
+	if d.HasChanges("automatic_instance_repair", "extension", "health_probe_id") {
+		_, hasHealthExtension, err := expandVirtualMachineScaleSetExtensions(d.Get("extension").(*pluginsdk.Set).List(), d.Get("health_probe_id").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if err := validateVirtualMachineScaleSetAutomaticRepairsPolicy(d.Get("automatic_instance_repair").([]interface{}), d.Get("health_probe_id").(string), hasHealthExtension); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChanges("instances", "zones", "zone_balance") {
+		diags = append(diags, virtualMachineScaleSetZoneBalanceCapacityWarnings(d.Get("instances").(int), zones.ExpandUntyped(d.Get("zones").(*schema.Set).List()), d.Get("zone_balance").(bool))...)
+	}
+
+	if d.HasChanges("instances", "extension") {
+		diags = append(diags, virtualMachineScaleSetZeroInstancesExtensionsWarnings(d.Get("instances").(int), d.Get("extension").(*pluginsdk.Set).List())...)
 	}
 
 	if d.HasChange("user_data") {
@@ -806,27 +982,40 @@ func resourceWindowsVirtualMachineScaleSetUpdate(d *pluginsdk.ResourceData, meta
 	}
 
 	if d.HasChange("tags") {
+		if err := validateVirtualMachineScaleSetTags(d.Get("tags").(map[string]interface{})); err != nil {
+			return diag.Errorf("validating `tags`: %+v", err)
+		}
 		update.Tags = tags.Expand(d.Get("tags").(map[string]interface{}))
 	}
 
 	update.Properties = &updateProps
 
 	metaData := virtualMachineScaleSetUpdateMetaData{
-		AutomaticOSUpgradeIsEnabled:  automaticOSUpgradeIsEnabled,
-		CanReimageOnManualUpgrade:    meta.(*clients.Client).Features.VirtualMachineScaleSet.ReimageOnManualUpgrade,
-		CanRollInstancesWhenRequired: meta.(*clients.Client).Features.VirtualMachineScaleSet.RollInstancesWhenRequired,
-		UpdateInstances:              updateInstances,
-		Client:                       meta.(*clients.Client).Compute,
-		Existing:                     *existing.Model,
-		ID:                           id,
-		OSType:                       virtualmachinescalesets.OperatingSystemTypesWindows,
+		AutomaticOSUpgradeIsEnabled:   automaticOSUpgradeIsEnabled,
+		CanReimageOnManualUpgrade:     meta.(*clients.Client).Features.VirtualMachineScaleSet.ReimageOnManualUpgrade,
+		CanRollInstancesWhenRequired:  meta.(*clients.Client).Features.VirtualMachineScaleSet.RollInstancesWhenRequired,
+		UpdateInstances:               updateInstances,
+		RemovedExtensionNames:         removedExtensionNames,
+		ExtensionProvisioningTimeouts: virtualMachineScaleSetExtensionProvisioningTimeouts(d.Get("extension").(*pluginsdk.Set).List()),
+		Client:                        meta.(*clients.Client).Compute,
+		Existing:                      *existing.Model,
+		ID:                            id,
+		OSType:                        virtualmachinescalesets.OperatingSystemTypesWindows,
 	}
 
 	if err := metaData.performUpdate(ctx, update); err != nil {
-		return err
+		// otherwise the requested (but unachieved) capacity would be persisted into the state
+		if capacity := metaData.capacityAfterFailedUpdate(ctx, update); capacity != nil {
+			d.Set("instances", int(*capacity))
+		}
+		return diag.FromErr(err)
+	}
+
+	if instances := d.Get("instances").(int); d.HasChange("instances") && d.Get("overprovision").(bool) && instances > 0 {
+		waitForVirtualMachineScaleSetOverprovisionedInstancesToBeRemoved(ctx, meta.(*clients.Client).Compute, *id, int64(instances))
 	}
 
-	return resourceWindowsVirtualMachineScaleSetRead(d, meta)
+	return append(diags, diag.FromErr(resourceWindowsVirtualMachineScaleSetRead(d, meta))...)
 }
 
 func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta interface{}) error {
@@ -906,7 +1095,7 @@ func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta i
 			d.Set("single_placement_group", props.SinglePlacementGroup)
 			d.Set("unique_id", props.UniqueId)
 			d.Set("zone_balance", props.ZoneBalance)
-			d.Set("scale_in", FlattenVirtualMachineScaleSetScaleInPolicy(props.ScaleInPolicy))
+			d.Set("scale_in", FlattenVirtualMachineScaleSetScaleInPolicy(props.ScaleInPolicy, d.Get("scale_in").([]interface{})))
 
 			if !features.FourPointOhBeta() {
 				rule := string(virtualmachinescalesets.VirtualMachineScaleSetScaleInRulesDefault)
@@ -940,7 +1129,7 @@ func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta i
 			}
 
 			if profile := props.VirtualMachineProfile; profile != nil {
-				if err := d.Set("boot_diagnostics", flattenBootDiagnosticsVMSS(profile.DiagnosticsProfile)); err != nil {
+				if err := d.Set("boot_diagnostics", FlattenVirtualMachineScaleSetBootDiagnostics(profile.DiagnosticsProfile, d.Get("boot_diagnostics").([]interface{}))); err != nil {
 					return fmt.Errorf("setting `boot_diagnostics`: %+v", err)
 				}
 
@@ -977,11 +1166,13 @@ func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta i
 				d.Set("priority", priority)
 
 				if storageProfile := profile.StorageProfile; storageProfile != nil {
+					d.Set("disk_controller_type", FlattenVirtualMachineScaleSetDiskControllerType(storageProfile.DiskControllerType))
+
 					if err := d.Set("os_disk", FlattenVirtualMachineScaleSetOSDisk(storageProfile.OsDisk)); err != nil {
 						return fmt.Errorf("setting `os_disk`: %+v", err)
 					}
 
-					if err := d.Set("data_disk", FlattenVirtualMachineScaleSetDataDisk(storageProfile.DataDisks)); err != nil {
+					if err := d.Set("data_disk", FlattenVirtualMachineScaleSetDataDisk(storageProfile.DataDisks, d.Get("data_disk").([]interface{}))); err != nil {
 						return fmt.Errorf("setting `data_disk`: %+v", err)
 					}
 
@@ -1000,6 +1191,12 @@ func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta i
 					if err := d.Set("source_image_reference", flattenSourceImageReferenceVMSS(storageProfile.ImageReference, storageImageId != "")); err != nil {
 						return fmt.Errorf("setting `source_image_reference`: %+v", err)
 					}
+
+					exactVersion := ""
+					if storageProfile.ImageReference != nil {
+						exactVersion = pointer.From(storageProfile.ImageReference.ExactVersion)
+					}
+					d.Set("source_image_reference_exact_version", exactVersion)
 				}
 
 				extensionOperationsEnabled := true
@@ -1076,20 +1273,26 @@ func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta i
 				}
 				d.Set("extension", extensionProfile)
 
+				extensions := make([]interface{}, 0, len(extensionProfile))
+				for _, extension := range extensionProfile {
+					extensions = append(extensions, extension)
+				}
+				extensionProvisionOrder, err := virtualMachineScaleSetExtensionProvisionOrder(extensions)
+				if err != nil {
+					return fmt.Errorf("resolving `extension_provision_order`: %+v", err)
+				}
+				d.Set("extension_provision_order", extensionProvisionOrder)
+
 				extensionsTimeBudget := "PT1H30M"
 				if profile.ExtensionProfile != nil && profile.ExtensionProfile.ExtensionsTimeBudget != nil {
 					extensionsTimeBudget = *profile.ExtensionProfile.ExtensionsTimeBudget
 				}
 				d.Set("extensions_time_budget", extensionsTimeBudget)
 
-				encryptionAtHostEnabled := false
 				vtpmEnabled := false
 				secureBootEnabled := false
 
 				if securityProfile := profile.SecurityProfile; securityProfile != nil {
-					if securityProfile.EncryptionAtHost != nil {
-						encryptionAtHostEnabled = *securityProfile.EncryptionAtHost
-					}
 					if uefi := profile.SecurityProfile.UefiSettings; uefi != nil {
 						if uefi.VTpmEnabled != nil {
 							vtpmEnabled = *uefi.VTpmEnabled
@@ -1100,7 +1303,7 @@ func resourceWindowsVirtualMachineScaleSetRead(d *pluginsdk.ResourceData, meta i
 					}
 				}
 
-				d.Set("encryption_at_host_enabled", encryptionAtHostEnabled)
+				d.Set("encryption_at_host_enabled", FlattenVirtualMachineScaleSetEncryptionAtHost(profile.SecurityProfile))
 				d.Set("vtpm_enabled", vtpmEnabled)
 				d.Set("secure_boot_enabled", secureBootEnabled)
 				d.Set("user_data", profile.UserData)
@@ -1222,11 +1425,12 @@ func resourceWindowsVirtualMachineScaleSetSchema() map[string]*pluginsdk.Schema
 
 		"automatic_instance_repair": VirtualMachineScaleSetAutomaticRepairsPolicySchema(),
 
-		"boot_diagnostics": bootDiagnosticsSchema(),
+		"boot_diagnostics": VirtualMachineScaleSetBootDiagnosticsSchema(),
 
 		"capacity_reservation_group_id": {
-			Type:         pluginsdk.TypeString,
-			Optional:     true,
+			Type:     pluginsdk.TypeString,
+			Optional: true,
+			// the Capacity Reservation Group can't be changed (or removed) via the Update API, so the Scale Set must be recreated
 			ForceNew:     true,
 			ValidateFunc: capacityreservationgroups.ValidateCapacityReservationGroupID,
 			ConflictsWith: []string{
@@ -1249,6 +1453,8 @@ func resourceWindowsVirtualMachineScaleSetSchema() map[string]*pluginsdk.Schema
 
 		"data_disk": VirtualMachineScaleSetDataDiskSchema(),
 
+		"disk_controller_type": VirtualMachineScaleSetDiskControllerTypeSchema(),
+
 		"do_not_run_extensions_on_overprovisioned_machines": {
 			Type:     pluginsdk.TypeBool,
 			Optional: true,
@@ -1295,11 +1501,19 @@ func resourceWindowsVirtualMachineScaleSetSchema() map[string]*pluginsdk.Schema
 
 		"extension": VirtualMachineScaleSetExtensionsSchema(),
 
+		"extension_provision_order": {
+			Type:     pluginsdk.TypeList,
+			Computed: true,
+			Elem: &pluginsdk.Schema{
+				Type: pluginsdk.TypeString,
+			},
+		},
+
 		"extensions_time_budget": {
 			Type:         pluginsdk.TypeString,
 			Optional:     true,
 			Default:      "PT1H30M",
-			ValidateFunc: validate.ISO8601DurationBetween("PT15M", "PT2H"),
+			ValidateFunc: validate.ISO8601DurationBetweenWithMessage("PT15M", "PT2H"),
 		},
 
 		"gallery_application": VirtualMachineScaleSetGalleryApplicationSchema(),
@@ -1318,6 +1532,12 @@ func resourceWindowsVirtualMachineScaleSetSchema() map[string]*pluginsdk.Schema
 			// tracked by https://github.com/Azure/azure-rest-api-specs/issues/19424
 			DiffSuppressFunc: suppress.CaseDifference,
 			ValidateFunc:     computeValidate.HostGroupID,
+			ConflictsWith: func() []string {
+				if !features.FourPointOhBeta() {
+					return []string{}
+				}
+				return []string{"proximity_placement_group_id"}
+			}(),
 		},
 
 		"identity": commonschema.SystemAssignedUserAssignedIdentityOptional(),
@@ -1386,9 +1606,12 @@ func resourceWindowsVirtualMachineScaleSetSchema() map[string]*pluginsdk.Schema
 			ValidateFunc: proximityplacementgroups.ValidateProximityPlacementGroupID,
 			// the Compute API is broken and returns the Resource Group name in UPPERCASE :shrug:, github issue: https://github.com/Azure/azure-rest-api-specs/issues/10016
 			DiffSuppressFunc: suppress.CaseDifference,
-			ConflictsWith: []string{
-				"capacity_reservation_group_id",
-			},
+			ConflictsWith: func() []string {
+				if !features.FourPointOhBeta() {
+					return []string{"capacity_reservation_group_id"}
+				}
+				return []string{"capacity_reservation_group_id", "host_group_id"}
+			}(),
 		},
 
 		"rolling_upgrade_policy": VirtualMachineScaleSetRollingUpgradePolicySchema(),
@@ -1427,6 +1650,12 @@ func resourceWindowsVirtualMachineScaleSetSchema() map[string]*pluginsdk.Schema
 
 		"source_image_reference": sourceImageReferenceSchema(false),
 
+		// the concrete version of the image which was resolved by the API, which differs from the `version` when it's `latest`
+		"source_image_reference_exact_version": {
+			Type:     pluginsdk.TypeString,
+			Computed: true,
+		},
+
 		"tags": commonschema.Tags(),
 
 		"timezone": {
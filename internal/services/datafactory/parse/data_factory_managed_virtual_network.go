@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package parse
+
+// NOTE: this file is generated via 'go:generate' - manual changes will be overwritten
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/resourceids"
+)
+
+type DataFactoryManagedVirtualNetworkId struct {
+	SubscriptionId            string
+	ResourceGroup             string
+	FactoryName               string
+	ManagedVirtualNetworkName string
+}
+
+func NewDataFactoryManagedVirtualNetworkID(subscriptionId, resourceGroup, factoryName, managedVirtualNetworkName string) DataFactoryManagedVirtualNetworkId {
+	return DataFactoryManagedVirtualNetworkId{
+		SubscriptionId:            subscriptionId,
+		ResourceGroup:             resourceGroup,
+		FactoryName:               factoryName,
+		ManagedVirtualNetworkName: managedVirtualNetworkName,
+	}
+}
+
+func (id DataFactoryManagedVirtualNetworkId) String() string {
+	segments := []string{
+		fmt.Sprintf("Managed Virtual Network Name %q", id.ManagedVirtualNetworkName),
+		fmt.Sprintf("Factory Name %q", id.FactoryName),
+		fmt.Sprintf("Resource Group %q", id.ResourceGroup),
+	}
+	segmentsStr := strings.Join(segments, " / ")
+	return fmt.Sprintf("%s: (%s)", "Data Factory Managed Virtual Network", segmentsStr)
+}
+
+func (id DataFactoryManagedVirtualNetworkId) ID() string {
+	fmtString := "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.DataFactory/factories/%s/managedVirtualNetworks/%s"
+	return fmt.Sprintf(fmtString, id.SubscriptionId, id.ResourceGroup, id.FactoryName, id.ManagedVirtualNetworkName)
+}
+
+// DataFactoryManagedVirtualNetworkID parses a DataFactoryManagedVirtualNetwork ID into an DataFactoryManagedVirtualNetworkId struct
+func DataFactoryManagedVirtualNetworkID(input string) (*DataFactoryManagedVirtualNetworkId, error) {
+	id, err := resourceids.ParseAzureResourceID(input)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q as an DataFactoryManagedVirtualNetwork ID: %+v", input, err)
+	}
+
+	resourceId := DataFactoryManagedVirtualNetworkId{
+		SubscriptionId: id.SubscriptionID,
+		ResourceGroup:  id.ResourceGroup,
+	}
+
+	if resourceId.SubscriptionId == "" {
+		return nil, fmt.Errorf("ID was missing the 'subscriptions' element")
+	}
+
+	if resourceId.ResourceGroup == "" {
+		return nil, fmt.Errorf("ID was missing the 'resourceGroups' element")
+	}
+
+	if resourceId.FactoryName, err = id.PopSegment("factories"); err != nil {
+		return nil, err
+	}
+	if resourceId.ManagedVirtualNetworkName, err = id.PopSegment("managedVirtualNetworks"); err != nil {
+		return nil, err
+	}
+
+	if err := id.ValidateNoEmptySegments(input); err != nil {
+		return nil, err
+	}
+
+	return &resourceId, nil
+}
@@ -152,6 +152,10 @@ func resourceKustoDatabaseScriptCreateUpdate(d *pluginsdk.ResourceData, meta int
 		parameters.Properties.ScriptContent = utils.String(scriptContent.(string))
 	}
 
+	if err := validateKustoDatabaseScriptProperties(*parameters.Properties); err != nil {
+		return err
+	}
+
 	if err := client.CreateOrUpdateThenPoll(ctx, id, parameters); err != nil {
 		return fmt.Errorf("creating %q: %+v", id, err)
 	}
@@ -213,3 +217,21 @@ func resourceKustoDatabaseScriptDelete(d *pluginsdk.ResourceData, meta interface
 
 	return nil
 }
+
+// validateKustoDatabaseScriptProperties ensures that the script is sourced from either `script_content` or `url`, since these are
+// mutually exclusive in the API - the schema covers this at plan time, however this also catches values which are unknown until apply
+func validateKustoDatabaseScriptProperties(input scripts.ScriptProperties) error {
+	hasScriptContent := input.ScriptContent != nil && *input.ScriptContent != ""
+	hasScriptUrl := input.ScriptUrl != nil && *input.ScriptUrl != ""
+	hasScriptUrlSasToken := input.ScriptUrlSasToken != nil && *input.ScriptUrlSasToken != ""
+
+	if hasScriptContent && hasScriptUrl {
+		return fmt.Errorf("only one of `script_content` or `url` can be specified")
+	}
+
+	if hasScriptUrlSasToken && !hasScriptUrl {
+		return fmt.Errorf("`url` must be specified when `sas_token` is specified")
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kusto
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/kusto/2023-08-15/scripts"
+)
+
+func TestValidateKustoDatabaseScriptProperties(t *testing.T) {
+	cases := []struct {
+		name        string
+		input       scripts.ScriptProperties
+		shouldError bool
+	}{
+		{
+			name: "script content",
+			input: scripts.ScriptProperties{
+				ScriptContent: pointer.To(".create table MyTable (Level:string)"),
+			},
+			shouldError: false,
+		},
+		{
+			name: "url and sas token",
+			input: scripts.ScriptProperties{
+				ScriptUrl:         pointer.To("https://example.blob.core.windows.net/scripts/script.kql"),
+				ScriptUrlSasToken: pointer.To("?sv=2022-11-02&sig=abc123"),
+			},
+			shouldError: false,
+		},
+		{
+			name: "script content and url",
+			input: scripts.ScriptProperties{
+				ScriptContent: pointer.To(".create table MyTable (Level:string)"),
+				ScriptUrl:     pointer.To("https://example.blob.core.windows.net/scripts/script.kql"),
+			},
+			shouldError: true,
+		},
+		{
+			name: "script content, url and sas token",
+			input: scripts.ScriptProperties{
+				ScriptContent:     pointer.To(".create table MyTable (Level:string)"),
+				ScriptUrl:         pointer.To("https://example.blob.core.windows.net/scripts/script.kql"),
+				ScriptUrlSasToken: pointer.To("?sv=2022-11-02&sig=abc123"),
+			},
+			shouldError: true,
+		},
+		{
+			name: "sas token without url",
+			input: scripts.ScriptProperties{
+				ScriptUrlSasToken: pointer.To("?sv=2022-11-02&sig=abc123"),
+			},
+			shouldError: true,
+		},
+		{
+			name: "script content and sas token without url",
+			input: scripts.ScriptProperties{
+				ScriptContent:     pointer.To(".create table MyTable (Level:string)"),
+				ScriptUrlSasToken: pointer.To("?sv=2022-11-02&sig=abc123"),
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateKustoDatabaseScriptProperties(tc.input)
+		if tc.shouldError != (err != nil) {
+			t.Fatalf("expected an error for %q: %t but got: %+v", tc.name, tc.shouldError, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/expressroutecircuitconnections"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/expressroutecircuitpeerings"
+)
+
+type ListByCircuitCompleteResult struct {
+	LatestHttpResponse *http.Response
+	Items              []expressroutecircuitconnections.ExpressRouteCircuitConnection
+}
+
+// ListByCircuitComplete retrieves all the Express Route Circuit Connections across all of the Peerings within the specified Express Route Circuit
+func (c *Client) ListByCircuitComplete(ctx context.Context, id expressroutecircuitpeerings.ExpressRouteCircuitId) (ListByCircuitCompleteResult, error) {
+	return c.ListByCircuitCompleteMatchingPredicate(ctx, id, expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate{})
+}
+
+// ListByCircuitCompleteMatchingPredicate retrieves all the Express Route Circuit Connections across all of the Peerings within
+// the specified Express Route Circuit and then applies the predicate
+func (c *Client) ListByCircuitCompleteMatchingPredicate(ctx context.Context, id expressroutecircuitpeerings.ExpressRouteCircuitId, predicate expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate) (ListByCircuitCompleteResult, error) {
+	return listByCircuitCompleteMatchingPredicate(ctx, c.ExpressRouteCircuitPeerings.ListComplete, c.ExpressRouteCircuitConnections.ListCompleteMatchingPredicate, id, predicate)
+}
+
+func listByCircuitCompleteMatchingPredicate(ctx context.Context, listPeerings func(ctx context.Context, id expressroutecircuitpeerings.ExpressRouteCircuitId) (expressroutecircuitpeerings.ListCompleteResult, error), listConnections func(ctx context.Context, id commonids.ExpressRouteCircuitPeeringId, predicate expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate) (expressroutecircuitconnections.ListCompleteResult, error), id expressroutecircuitpeerings.ExpressRouteCircuitId, predicate expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate) (result ListByCircuitCompleteResult, err error) {
+	items := make([]expressroutecircuitconnections.ExpressRouteCircuitConnection, 0)
+
+	peerings, err := listPeerings(ctx, id)
+	result.LatestHttpResponse = peerings.LatestHttpResponse
+	if err != nil {
+		err = fmt.Errorf("listing Peerings for %s: %+v", id, err)
+		return
+	}
+
+	for _, peering := range peerings.Items {
+		peeringName := pointer.From(peering.Name)
+		if peeringName == "" {
+			continue
+		}
+
+		peeringId := commonids.NewExpressRouteCircuitPeeringID(id.SubscriptionId, id.ResourceGroupName, id.ExpressRouteCircuitName, peeringName)
+		connections, err := listConnections(ctx, peeringId, predicate)
+		result.LatestHttpResponse = connections.LatestHttpResponse
+		if err != nil {
+			return result, fmt.Errorf("listing Connections for %s: %+v", peeringId, err)
+		}
+
+		items = append(items, connections.Items...)
+	}
+
+	result.Items = items
+	return
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/expressroutecircuitconnections"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/network/2023-11-01/expressroutecircuitpeerings"
+)
+
+func TestListByCircuitCompleteMatchingPredicate(t *testing.T) {
+	id := expressroutecircuitpeerings.NewExpressRouteCircuitID("00000000-0000-0000-0000-000000000000", "resGroup1", "circuit1")
+
+	listPeerings := func(ctx context.Context, id expressroutecircuitpeerings.ExpressRouteCircuitId) (expressroutecircuitpeerings.ListCompleteResult, error) {
+		return expressroutecircuitpeerings.ListCompleteResult{
+			Items: []expressroutecircuitpeerings.ExpressRouteCircuitPeering{
+				{Name: pointer.To("AzurePrivatePeering")},
+				{Name: pointer.To("MicrosoftPeering")},
+				{},
+			},
+		}, nil
+	}
+
+	connectionsByPeering := map[string][]expressroutecircuitconnections.ExpressRouteCircuitConnection{
+		"AzurePrivatePeering": {
+			{Name: pointer.To("first")},
+			{Name: pointer.To("second")},
+		},
+		"MicrosoftPeering": {
+			{Name: pointer.To("third")},
+		},
+	}
+	listConnections := func(ctx context.Context, id commonids.ExpressRouteCircuitPeeringId, predicate expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate) (expressroutecircuitconnections.ListCompleteResult, error) {
+		connections, ok := connectionsByPeering[id.PeeringName]
+		if !ok {
+			return expressroutecircuitconnections.ListCompleteResult{}, fmt.Errorf("unexpected peering %q", id.PeeringName)
+		}
+
+		items := make([]expressroutecircuitconnections.ExpressRouteCircuitConnection, 0)
+		for _, connection := range connections {
+			if predicate.Matches(connection) {
+				items = append(items, connection)
+			}
+		}
+		return expressroutecircuitconnections.ListCompleteResult{Items: items}, nil
+	}
+
+	result, err := listByCircuitCompleteMatchingPredicate(context.TODO(), listPeerings, listConnections, id, expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(result.Items) != 3 {
+		t.Fatalf("expected the Connections across both Peerings to be returned but got %d", len(result.Items))
+	}
+
+	result, err = listByCircuitCompleteMatchingPredicate(context.TODO(), listPeerings, listConnections, id, expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate{Name: pointer.To("third")})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(result.Items) != 1 || pointer.From(result.Items[0].Name) != "third" {
+		t.Fatalf("expected only `third` to match but got %+v", result.Items)
+	}
+
+	failingConnections := func(ctx context.Context, id commonids.ExpressRouteCircuitPeeringId, predicate expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate) (expressroutecircuitconnections.ListCompleteResult, error) {
+		return expressroutecircuitconnections.ListCompleteResult{}, fmt.Errorf("internal server error")
+	}
+	if _, err := listByCircuitCompleteMatchingPredicate(context.TODO(), listPeerings, failingConnections, id, expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate{}); err == nil {
+		t.Fatalf("expected an error when listing the Connections fails but didn't get one")
+	}
+
+	failingPeerings := func(ctx context.Context, id expressroutecircuitpeerings.ExpressRouteCircuitId) (expressroutecircuitpeerings.ListCompleteResult, error) {
+		return expressroutecircuitpeerings.ListCompleteResult{}, fmt.Errorf("internal server error")
+	}
+	if _, err := listByCircuitCompleteMatchingPredicate(context.TODO(), failingPeerings, listConnections, id, expressroutecircuitconnections.ExpressRouteCircuitConnectionOperationPredicate{}); err == nil {
+		t.Fatalf("expected an error when listing the Peerings fails but didn't get one")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package parse
+
+// NOTE: this file is generated via 'go:generate' - manual changes will be overwritten
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/resourceids"
+)
+
+type ExpressRoutePortLinkId struct {
+	SubscriptionId       string
+	ResourceGroup        string
+	ExpressRoutePortName string
+	LinkName             string
+}
+
+func NewExpressRoutePortLinkID(subscriptionId, resourceGroup, expressRoutePortName, linkName string) ExpressRoutePortLinkId {
+	return ExpressRoutePortLinkId{
+		SubscriptionId:       subscriptionId,
+		ResourceGroup:        resourceGroup,
+		ExpressRoutePortName: expressRoutePortName,
+		LinkName:             linkName,
+	}
+}
+
+func (id ExpressRoutePortLinkId) String() string {
+	segments := []string{
+		fmt.Sprintf("Link Name %q", id.LinkName),
+		fmt.Sprintf("Express Route Port Name %q", id.ExpressRoutePortName),
+		fmt.Sprintf("Resource Group %q", id.ResourceGroup),
+	}
+	segmentsStr := strings.Join(segments, " / ")
+	return fmt.Sprintf("%s: (%s)", "Express Route Port Link", segmentsStr)
+}
+
+func (id ExpressRoutePortLinkId) ID() string {
+	fmtString := "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/expressRoutePorts/%s/links/%s"
+	return fmt.Sprintf(fmtString, id.SubscriptionId, id.ResourceGroup, id.ExpressRoutePortName, id.LinkName)
+}
+
+// ExpressRoutePortLinkID parses a ExpressRoutePortLink ID into an ExpressRoutePortLinkId struct
+func ExpressRoutePortLinkID(input string) (*ExpressRoutePortLinkId, error) {
+	id, err := resourceids.ParseAzureResourceID(input)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q as an ExpressRoutePortLink ID: %+v", input, err)
+	}
+
+	resourceId := ExpressRoutePortLinkId{
+		SubscriptionId: id.SubscriptionID,
+		ResourceGroup:  id.ResourceGroup,
+	}
+
+	if resourceId.SubscriptionId == "" {
+		return nil, fmt.Errorf("ID was missing the 'subscriptions' element")
+	}
+
+	if resourceId.ResourceGroup == "" {
+		return nil, fmt.Errorf("ID was missing the 'resourceGroups' element")
+	}
+
+	if resourceId.ExpressRoutePortName, err = id.PopSegment("expressRoutePorts"); err != nil {
+		return nil, err
+	}
+	if resourceId.LinkName, err = id.PopSegment("links"); err != nil {
+		return nil, err
+	}
+
+	if err := id.ValidateNoEmptySegments(input); err != nil {
+		return nil, err
+	}
+
+	return &resourceId, nil
+}
+
+// ExpressRoutePortLinkIDInsensitively parses an ExpressRoutePortLink ID into an ExpressRoutePortLinkId struct, insensitively
+// This should only be used to parse an ID for rewriting, the ExpressRoutePortLinkID
+// method should be used instead for validation etc.
+//
+// Whilst this may seem strange, this enables Terraform have consistent casing
+// which works around issues in Core, whilst handling broken API responses.
+func ExpressRoutePortLinkIDInsensitively(input string) (*ExpressRoutePortLinkId, error) {
+	id, err := resourceids.ParseAzureResourceID(input)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceId := ExpressRoutePortLinkId{
+		SubscriptionId: id.SubscriptionID,
+		ResourceGroup:  id.ResourceGroup,
+	}
+
+	if resourceId.SubscriptionId == "" {
+		return nil, fmt.Errorf("ID was missing the 'subscriptions' element")
+	}
+
+	if resourceId.ResourceGroup == "" {
+		return nil, fmt.Errorf("ID was missing the 'resourceGroups' element")
+	}
+
+	// find the correct casing for the 'expressRoutePorts' segment
+	expressRoutePortsKey := "expressRoutePorts"
+	for key := range id.Path {
+		if strings.EqualFold(key, expressRoutePortsKey) {
+			expressRoutePortsKey = key
+			break
+		}
+	}
+	if resourceId.ExpressRoutePortName, err = id.PopSegment(expressRoutePortsKey); err != nil {
+		return nil, err
+	}
+
+	// find the correct casing for the 'links' segment
+	linksKey := "links"
+	for key := range id.Path {
+		if strings.EqualFold(key, linksKey) {
+			linksKey = key
+			break
+		}
+	}
+	if resourceId.LinkName, err = id.PopSegment(linksKey); err != nil {
+		return nil, err
+	}
+
+	if err := id.ValidateNoEmptySegments(input); err != nil {
+		return nil, err
+	}
+
+	return &resourceId, nil
+}
@@ -0,0 +1,148 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/storage/2023-01-01/blobinventorypolicies"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/sdk"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/services/storage/parse"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/tf/pluginsdk"
+)
+
+type storageBlobInventoryPoliciesDataSource struct{}
+
+var _ sdk.DataSource = storageBlobInventoryPoliciesDataSource{}
+
+type storageBlobInventoryPoliciesDataSourceModel struct {
+	StorageAccountId string                     `tfschema:"storage_account_id"`
+	Policies         []blobInventoryPolicyModel `tfschema:"policies"`
+}
+
+type blobInventoryPolicyModel struct {
+	Id      string `tfschema:"id"`
+	Name    string `tfschema:"name"`
+	Enabled bool   `tfschema:"enabled"`
+}
+
+func (r storageBlobInventoryPoliciesDataSource) Arguments() map[string]*pluginsdk.Schema {
+	return map[string]*pluginsdk.Schema{
+		"storage_account_id": {
+			Type:         pluginsdk.TypeString,
+			Required:     true,
+			ValidateFunc: commonids.ValidateStorageAccountID,
+		},
+	}
+}
+
+func (r storageBlobInventoryPoliciesDataSource) Attributes() map[string]*pluginsdk.Schema {
+	return map[string]*pluginsdk.Schema{
+		"policies": {
+			Type:     pluginsdk.TypeList,
+			Computed: true,
+			Elem: &pluginsdk.Resource{
+				Schema: map[string]*pluginsdk.Schema{
+					"id": {
+						Type:     pluginsdk.TypeString,
+						Computed: true,
+					},
+					"name": {
+						Type:     pluginsdk.TypeString,
+						Computed: true,
+					},
+					"enabled": {
+						Type:     pluginsdk.TypeBool,
+						Computed: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r storageBlobInventoryPoliciesDataSource) ResourceType() string {
+	return "azurerm_storage_blob_inventory_policies"
+}
+
+func (r storageBlobInventoryPoliciesDataSource) ModelObject() interface{} {
+	return &storageBlobInventoryPoliciesDataSourceModel{}
+}
+
+func (r storageBlobInventoryPoliciesDataSource) Read() sdk.ResourceFunc {
+	return sdk.ResourceFunc{
+		Timeout: 5 * time.Minute,
+
+		Func: func(ctx context.Context, metadata sdk.ResourceMetaData) error {
+			client := metadata.Client.Storage.ResourceManager.BlobInventoryPolicies
+
+			var state storageBlobInventoryPoliciesDataSourceModel
+			if err := metadata.Decode(&state); err != nil {
+				return fmt.Errorf("decoding %+v", err)
+			}
+
+			id, err := commonids.ParseStorageAccountID(state.StorageAccountId)
+			if err != nil {
+				return err
+			}
+
+			policies, err := listBlobInventoryPoliciesByStorageAccount(ctx, client, *id)
+			if err != nil {
+				return err
+			}
+
+			state.Policies = flattenStorageBlobInventoryPolicies(*id, policies)
+
+			if err := metadata.Encode(&state); err != nil {
+				return fmt.Errorf("encoding %s: %+v", id, err)
+			}
+
+			metadata.SetID(id)
+
+			return nil
+		},
+	}
+}
+
+// listBlobInventoryPoliciesByStorageAccount returns the Blob Inventory Policies defined within the specified Storage Account
+func listBlobInventoryPoliciesByStorageAccount(ctx context.Context, client *blobinventorypolicies.BlobInventoryPoliciesClient, id commonids.StorageAccountId) ([]blobinventorypolicies.BlobInventoryPolicy, error) {
+	resp, err := client.List(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("listing Blob Inventory Policies for %s: %+v", id, err)
+	}
+
+	if model := resp.Model; model != nil && model.Value != nil {
+		return *model.Value, nil
+	}
+
+	return []blobinventorypolicies.BlobInventoryPolicy{}, nil
+}
+
+func flattenStorageBlobInventoryPolicies(accountId commonids.StorageAccountId, input []blobinventorypolicies.BlobInventoryPolicy) []blobInventoryPolicyModel {
+	output := make([]blobInventoryPolicyModel, 0)
+	for _, item := range input {
+		if item.Name == nil {
+			continue
+		}
+
+		// the ID is built from the Storage Account rather than using the one returned from the API, to ensure it's in the canonical casing
+		id := parse.NewBlobInventoryPolicyID(accountId.SubscriptionId, accountId.ResourceGroupName, accountId.StorageAccountName, *item.Name)
+
+		var enabled bool
+		if props := item.Properties; props != nil {
+			enabled = props.Policy.Enabled
+		}
+
+		output = append(output, blobInventoryPolicyModel{
+			Id:      id.ID(),
+			Name:    id.InventoryPolicyName,
+			Enabled: enabled,
+		})
+	}
+
+	return output
+}
@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package storage_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/acceptance"
+	"github.com/hashicorp/terraform-provider-azurerm/internal/acceptance/check"
+)
+
+type storageBlobInventoryPoliciesDataSource struct{}
+
+func TestAccDataSourceStorageBlobInventoryPolicies_basic(t *testing.T) {
+	data := acceptance.BuildTestData(t, "data.azurerm_storage_blob_inventory_policies", "test")
+	d := storageBlobInventoryPoliciesDataSource{}
+
+	data.DataSourceTest(t, []resource.TestStep{
+		{
+			Config: d.basic(data),
+			Check: resource.ComposeTestCheckFunc(
+				check.That(data.ResourceName).Key("policies.#").HasValue("1"),
+				check.That(data.ResourceName).Key("policies.0.name").HasValue("default"),
+				check.That(data.ResourceName).Key("policies.0.id").HasValue(
+					fmt.Sprintf("/subscriptions/%s/resourceGroups/acctestRG-storage-%d/providers/Microsoft.Storage/storageAccounts/acctestacc%s/inventoryPolicies/default",
+						data.Client().SubscriptionID, data.RandomInteger, data.RandomString),
+				),
+				check.That(data.ResourceName).Key("policies.0.enabled").HasValue("true"),
+			),
+		},
+	})
+}
+
+func (d storageBlobInventoryPoliciesDataSource) basic(data acceptance.TestData) string {
+	return fmt.Sprintf(`
+%s
+
+data "azurerm_storage_blob_inventory_policies" "test" {
+  storage_account_id = azurerm_storage_account.test.id
+  depends_on         = [azurerm_storage_blob_inventory_policy.test]
+}
+`, StorageBlobInventoryPolicyResource{}.basic(data))
+}
@@ -111,7 +111,7 @@ func resourceStorageBlobInventoryPolicy() *pluginsdk.Resource {
 								Schema: map[string]*pluginsdk.Schema{
 									"blob_types": {
 										Type:     pluginsdk.TypeSet,
-										Required: true,
+										Optional: true,
 										Elem: &pluginsdk.Schema{
 											Type: pluginsdk.TypeString,
 											ValidateFunc: validation.StringInSlice([]string{
@@ -171,8 +171,8 @@ func resourceStorageBlobInventoryPolicy() *pluginsdk.Resource {
 			rules := diff.Get("rules").(*pluginsdk.Set).List()
 			for _, rule := range rules {
 				v := rule.(map[string]interface{})
-				if v["scope"] != string(blobinventorypolicies.ObjectTypeBlob) && len(v["filter"].([]interface{})) != 0 {
-					return fmt.Errorf("the `filter` can only be set when the `scope` is `%s`", blobinventorypolicies.ObjectTypeBlob)
+				if err := validateBlobInventoryPolicyRuleFilter(v["scope"].(string), v["filter"].([]interface{})); err != nil {
+					return fmt.Errorf("validating the `filter` for rule %q: %+v", v["name"].(string), err)
 				}
 			}
 
@@ -276,6 +276,35 @@ func resourceStorageBlobInventoryPolicyDelete(d *pluginsdk.ResourceData, meta in
 	return nil
 }
 
+// validateBlobInventoryPolicyRuleFilter ensures that the fields within the `filter` block are supported for the
+// `scope` (object type) of the rule - Container inventories only support prefix matching and deleted containers
+func validateBlobInventoryPolicyRuleFilter(scope string, input []interface{}) error {
+	if len(input) == 0 || input[0] == nil {
+		return nil
+	}
+	v := input[0].(map[string]interface{})
+
+	blobTypes := v["blob_types"].(*pluginsdk.Set).Len()
+	if scope == string(blobinventorypolicies.ObjectTypeBlob) {
+		if blobTypes == 0 {
+			return fmt.Errorf("`blob_types` must be specified when the `scope` is `%s`", blobinventorypolicies.ObjectTypeBlob)
+		}
+		return nil
+	}
+
+	if blobTypes != 0 {
+		return fmt.Errorf("`blob_types` can only be specified when the `scope` is `%s`", blobinventorypolicies.ObjectTypeBlob)
+	}
+	if v["include_blob_versions"].(bool) {
+		return fmt.Errorf("`include_blob_versions` can only be enabled when the `scope` is `%s`", blobinventorypolicies.ObjectTypeBlob)
+	}
+	if v["include_snapshots"].(bool) {
+		return fmt.Errorf("`include_snapshots` can only be enabled when the `scope` is `%s`", blobinventorypolicies.ObjectTypeBlob)
+	}
+
+	return nil
+}
+
 func expandBlobInventoryPolicyRules(input []interface{}) []blobinventorypolicies.BlobInventoryPolicyRule {
 	results := make([]blobinventorypolicies.BlobInventoryPolicyRule, 0)
 	for _, item := range input {
@@ -289,26 +318,32 @@ func expandBlobInventoryPolicyRules(input []interface{}) []blobinventorypolicies
 				Schedule:     blobinventorypolicies.Schedule(v["schedule"].(string)),
 				ObjectType:   blobinventorypolicies.ObjectType(v["scope"].(string)),
 				SchemaFields: *utils.ExpandStringSlice(v["schema_fields"].([]interface{})),
-				Filters:      expandBlobInventoryPolicyFilter(v["filter"].([]interface{})),
+				Filters:      expandBlobInventoryPolicyFilter(blobinventorypolicies.ObjectType(v["scope"].(string)), v["filter"].([]interface{})),
 			},
 		})
 	}
 	return results
 }
 
-func expandBlobInventoryPolicyFilter(input []interface{}) *blobinventorypolicies.BlobInventoryPolicyFilter {
-	if len(input) == 0 {
+func expandBlobInventoryPolicyFilter(objectType blobinventorypolicies.ObjectType, input []interface{}) *blobinventorypolicies.BlobInventoryPolicyFilter {
+	if len(input) == 0 || input[0] == nil {
 		return nil
 	}
 	v := input[0].(map[string]interface{})
-	return &blobinventorypolicies.BlobInventoryPolicyFilter{
-		PrefixMatch:         utils.ExpandStringSlice(v["prefix_match"].(*pluginsdk.Set).List()),
-		ExcludePrefix:       utils.ExpandStringSlice(v["exclude_prefixes"].(*pluginsdk.Set).List()),
-		BlobTypes:           utils.ExpandStringSlice(v["blob_types"].(*pluginsdk.Set).List()),
-		IncludeBlobVersions: utils.Bool(v["include_blob_versions"].(bool)),
-		IncludeDeleted:      utils.Bool(v["include_deleted"].(bool)),
-		IncludeSnapshots:    utils.Bool(v["include_snapshots"].(bool)),
+	filter := &blobinventorypolicies.BlobInventoryPolicyFilter{
+		PrefixMatch:    utils.ExpandStringSlice(v["prefix_match"].(*pluginsdk.Set).List()),
+		ExcludePrefix:  utils.ExpandStringSlice(v["exclude_prefixes"].(*pluginsdk.Set).List()),
+		IncludeDeleted: utils.Bool(v["include_deleted"].(bool)),
+	}
+
+	// the API rejects the blob-level fields when the inventory is for Containers
+	if objectType == blobinventorypolicies.ObjectTypeBlob {
+		filter.BlobTypes = utils.ExpandStringSlice(v["blob_types"].(*pluginsdk.Set).List())
+		filter.IncludeBlobVersions = utils.Bool(v["include_blob_versions"].(bool))
+		filter.IncludeSnapshots = utils.Bool(v["include_snapshots"].(bool))
 	}
+
+	return filter
 }
 
 func flattenBlobInventoryPolicyRules(input []blobinventorypolicies.BlobInventoryPolicyRule) []interface{} {
@@ -6,6 +6,7 @@ package storage_test
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
@@ -61,6 +62,31 @@ func TestAccStorageBlobInventoryPolicy_complete(t *testing.T) {
 	})
 }
 
+func TestAccStorageBlobInventoryPolicy_containerScopeFilter(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_storage_blob_inventory_policy", "test")
+	r := StorageBlobInventoryPolicyResource{}
+	data.ResourceTest(t, r, []acceptance.TestStep{
+		{
+			Config: r.containerScopeFilter(data),
+			Check: acceptance.ComposeTestCheckFunc(
+				check.That(data.ResourceName).ExistsInAzure(r),
+			),
+		},
+		data.ImportStep(),
+	})
+}
+
+func TestAccStorageBlobInventoryPolicy_containerScopeBlobFilterFields(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_storage_blob_inventory_policy", "test")
+	r := StorageBlobInventoryPolicyResource{}
+	data.ResourceTest(t, r, []acceptance.TestStep{
+		{
+			Config:      r.containerScopeBlobFilterFields(data),
+			ExpectError: regexp.MustCompile("`include_snapshots` can only be enabled when the `scope` is `Blob`"),
+		},
+	})
+}
+
 func TestAccStorageBlobInventoryPolicy_update(t *testing.T) {
 	data := acceptance.BuildTestData(t, "azurerm_storage_blob_inventory_policy", "test")
 	r := StorageBlobInventoryPolicyResource{}
@@ -233,6 +259,60 @@ resource "azurerm_storage_blob_inventory_policy" "test" {
 `, template)
 }
 
+func (r StorageBlobInventoryPolicyResource) containerScopeFilter(data acceptance.TestData) string {
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_storage_blob_inventory_policy" "test" {
+  storage_account_id = azurerm_storage_account.test.id
+  rules {
+    name                   = "rule1"
+    storage_container_name = azurerm_storage_container.test.name
+    format                 = "Csv"
+    schedule               = "Daily"
+    scope                  = "Container"
+    schema_fields = [
+      "Name",
+      "Last-Modified",
+      "Deleted",
+      "Version",
+      "DeletedTime",
+      "RemainingRetentionDays",
+    ]
+    filter {
+      include_deleted  = true
+      prefix_match     = ["test"]
+      exclude_prefixes = ["logs"]
+    }
+  }
+}
+`, r.template(data))
+}
+
+func (r StorageBlobInventoryPolicyResource) containerScopeBlobFilterFields(data acceptance.TestData) string {
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_storage_blob_inventory_policy" "test" {
+  storage_account_id = azurerm_storage_account.test.id
+  rules {
+    name                   = "rule1"
+    storage_container_name = azurerm_storage_container.test.name
+    format                 = "Csv"
+    schedule               = "Daily"
+    scope                  = "Container"
+    schema_fields = [
+      "Name",
+      "Last-Modified",
+    ]
+    filter {
+      include_snapshots = true
+    }
+  }
+}
+`, r.template(data))
+}
+
 func (r StorageBlobInventoryPolicyResource) multipleRules(data acceptance.TestData) string {
 	template := r.template(data)
 	return fmt.Sprintf(`
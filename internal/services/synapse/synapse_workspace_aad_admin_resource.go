@@ -4,10 +4,14 @@
 package synapse
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/preview/synapse/mgmt/v2.0/synapse" // nolint: staticcheck
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/clients"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/services/synapse/parse"
 	"github.com/hashicorp/terraform-provider-azurerm/internal/services/synapse/validate"
@@ -85,13 +89,12 @@ func resourceSynapseWorkspaceAADAdminCreateUpdate(d *pluginsdk.ResourceData, met
 		},
 	}
 
-	workspaceAadAdminsCreateOrUpdateFuture, err := client.CreateOrUpdate(ctx, workspaceResourceGroup, workspaceName, *aadAdmin)
+	changed, err := synapseWorkspaceAADAdminCreateOrUpdateIfChanged(ctx, client, workspaceResourceGroup, workspaceName, *aadAdmin)
 	if err != nil {
-		return fmt.Errorf("updating Synapse Workspace %q AAD Admin (Resource Group %q): %+v", workspaceName, workspaceResourceGroup, err)
+		return err
 	}
-
-	if err = workspaceAadAdminsCreateOrUpdateFuture.WaitForCompletionRef(ctx, client.Client); err != nil {
-		return fmt.Errorf("waiting on updating for Synapse Workspace %q AAD Admin (Resource Group %q): %+v", workspaceName, workspaceResourceGroup, err)
+	if !changed {
+		log.Printf("[DEBUG] Synapse Workspace %q AAD Admin (Resource Group %q) already matches the configuration - skipping update", workspaceName, workspaceResourceGroup)
 	}
 
 	id := parse.NewWorkspaceAADAdminID(workspaceId.SubscriptionId, workspaceId.ResourceGroup, workspaceId.Name, "activeDirectory")
@@ -100,6 +103,42 @@ func resourceSynapseWorkspaceAADAdminCreateUpdate(d *pluginsdk.ResourceData, met
 	return resourceSynapseWorkspaceAADAdminRead(d, meta)
 }
 
+// synapseWorkspaceAADAdminCreateOrUpdateIfChanged only updates the AAD Admin for the Workspace when it differs from the existing
+// AAD Admin, to avoid unnecessary updates (and the associated audit events) - returning whether a change was applied
+func synapseWorkspaceAADAdminCreateOrUpdateIfChanged(ctx context.Context, client *synapse.WorkspaceAadAdminsClient, resourceGroup string, workspaceName string, input synapse.WorkspaceAadAdminInfo) (bool, error) {
+	existing, err := client.Get(ctx, resourceGroup, workspaceName)
+	if err != nil {
+		if !utils.ResponseWasNotFound(existing.Response) {
+			return false, fmt.Errorf("retrieving Synapse Workspace %q AAD Admin (Resource Group %q): %+v", workspaceName, resourceGroup, err)
+		}
+	} else if synapseWorkspaceAADAdminMatches(existing.AadAdminProperties, input.AadAdminProperties) {
+		return false, nil
+	}
+
+	future, err := client.CreateOrUpdate(ctx, resourceGroup, workspaceName, input)
+	if err != nil {
+		return false, fmt.Errorf("updating Synapse Workspace %q AAD Admin (Resource Group %q): %+v", workspaceName, resourceGroup, err)
+	}
+
+	if err = future.WaitForCompletionRef(ctx, client.Client); err != nil {
+		return false, fmt.Errorf("waiting on updating for Synapse Workspace %q AAD Admin (Resource Group %q): %+v", workspaceName, resourceGroup, err)
+	}
+
+	return true, nil
+}
+
+// synapseWorkspaceAADAdminMatches returns whether the existing AAD Admin has the same Login, Object ID and Tenant ID as the
+// desired AAD Admin - the IDs are compared case-insensitively since they're UUIDs
+func synapseWorkspaceAADAdminMatches(existing *synapse.AadAdminProperties, desired *synapse.AadAdminProperties) bool {
+	if existing == nil || desired == nil {
+		return false
+	}
+
+	return pointer.From(existing.Login) == pointer.From(desired.Login) &&
+		strings.EqualFold(pointer.From(existing.Sid), pointer.From(desired.Sid)) &&
+		strings.EqualFold(pointer.From(existing.TenantID), pointer.From(desired.TenantID))
+}
+
 func resourceSynapseWorkspaceAADAdminRead(d *pluginsdk.ResourceData, meta interface{}) error {
 	client := meta.(*clients.Client).Synapse.WorkspaceAadAdminsClient
 	ctx, cancel := timeouts.ForRead(meta.(*clients.Client).StopContext, d)
@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package synapse
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/synapse/mgmt/v2.0/synapse" // nolint: staticcheck
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+)
+
+func TestSynapseWorkspaceAADAdminMatches(t *testing.T) {
+	desired := &synapse.AadAdminProperties{
+		Login:    pointer.To("AzureAD Admin"),
+		Sid:      pointer.To("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"),
+		TenantID: pointer.To("11111111-2222-3333-4444-555555555555"),
+	}
+
+	cases := []struct {
+		name     string
+		existing *synapse.AadAdminProperties
+		desired  *synapse.AadAdminProperties
+		expected bool
+	}{
+		{
+			name:     "no existing admin",
+			existing: nil,
+			desired:  desired,
+			expected: false,
+		},
+		{
+			name:     "no desired admin",
+			existing: desired,
+			desired:  nil,
+			expected: false,
+		},
+		{
+			name: "identical",
+			existing: &synapse.AadAdminProperties{
+				Login:    pointer.To("AzureAD Admin"),
+				Sid:      pointer.To("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"),
+				TenantID: pointer.To("11111111-2222-3333-4444-555555555555"),
+			},
+			desired:  desired,
+			expected: true,
+		},
+		{
+			name: "ids differ only in casing",
+			existing: &synapse.AadAdminProperties{
+				Login:    pointer.To("AzureAD Admin"),
+				Sid:      pointer.To("AAAAAAAA-BBBB-CCCC-DDDD-EEEEEEEEEEEE"),
+				TenantID: pointer.To("11111111-2222-3333-4444-555555555555"),
+			},
+			desired:  desired,
+			expected: true,
+		},
+		{
+			name: "login differs only in casing",
+			existing: &synapse.AadAdminProperties{
+				Login:    pointer.To("azuread admin"),
+				Sid:      pointer.To("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"),
+				TenantID: pointer.To("11111111-2222-3333-4444-555555555555"),
+			},
+			desired:  desired,
+			expected: false,
+		},
+		{
+			name: "different object id",
+			existing: &synapse.AadAdminProperties{
+				Login:    pointer.To("AzureAD Admin"),
+				Sid:      pointer.To("ffffffff-bbbb-cccc-dddd-eeeeeeeeeeee"),
+				TenantID: pointer.To("11111111-2222-3333-4444-555555555555"),
+			},
+			desired:  desired,
+			expected: false,
+		},
+		{
+			name: "different tenant id",
+			existing: &synapse.AadAdminProperties{
+				Login:    pointer.To("AzureAD Admin"),
+				Sid:      pointer.To("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"),
+				TenantID: pointer.To("99999999-2222-3333-4444-555555555555"),
+			},
+			desired:  desired,
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := synapseWorkspaceAADAdminMatches(tc.existing, tc.desired); actual != tc.expected {
+				t.Fatalf("expected %t for %q but got %t", tc.expected, tc.name, actual)
+			}
+		})
+	}
+}
@@ -434,13 +434,8 @@ func resourceSynapseWorkspaceCreate(d *pluginsdk.ResourceData, meta interface{})
 	if !features.FourPointOhBeta() {
 		aadAdmin := expandArmWorkspaceAadAdminInfo(d.Get("aad_admin").([]interface{}))
 		if aadAdmin != nil {
-			future, err := aadAdminClient.CreateOrUpdate(ctx, id.ResourceGroup, id.Name, *aadAdmin)
-			if err != nil {
-				return fmt.Errorf("configuring AzureAD Admin for %s: %+v", id, err)
-			}
-
-			if err = future.WaitForCompletionRef(ctx, client.Client); err != nil {
-				return fmt.Errorf("waiting for configuration of AzureAD Admin for %s: %+v", id, err)
+			if _, err := synapseWorkspaceAADAdminCreateOrUpdateIfChanged(ctx, aadAdminClient, id.ResourceGroup, id.Name, *aadAdmin); err != nil {
+				return err
 			}
 		}
 
@@ -670,13 +665,8 @@ func resourceSynapseWorkspaceUpdate(d *pluginsdk.ResourceData, meta interface{})
 				if err := waitSynapseWorkspaceProvisioningState(ctx, client, id); err != nil {
 					return fmt.Errorf("failed waiting for updating %s: %+v", id, err)
 				}
-				workspaceAadAdminsCreateOrUpdateFuture, err := aadAdminClient.CreateOrUpdate(ctx, id.ResourceGroup, id.Name, *aadAdmin)
-				if err != nil {
-					return fmt.Errorf("updating Synapse Workspace %q Sql Admin (Resource Group %q): %+v", id.Name, id.ResourceGroup, err)
-				}
-
-				if err = workspaceAadAdminsCreateOrUpdateFuture.WaitForCompletionRef(ctx, client.Client); err != nil {
-					return fmt.Errorf("waiting on updating for Synapse Workspace %q Sql Admin (Resource Group %q): %+v", id.Name, id.ResourceGroup, err)
+				if _, err := synapseWorkspaceAADAdminCreateOrUpdateIfChanged(ctx, aadAdminClient, id.ResourceGroup, id.Name, *aadAdmin); err != nil {
+					return err
 				}
 			} else {
 				if err := waitSynapseWorkspaceProvisioningState(ctx, client, id); err != nil {